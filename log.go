@@ -22,6 +22,7 @@ import (
 	"github.com/btcsuite/btcd/peer"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/v2transport"
+	"github.com/btcsuite/btcd/wire"
 
 	"github.com/btcsuite/btclog"
 	"github.com/jrick/logrotate/rotator"
@@ -70,6 +71,7 @@ var (
 	srvrLog = backendLog.Logger("SRVR")
 	syncLog = backendLog.Logger("SYNC")
 	txmpLog = backendLog.Logger("TXMP")
+	wireLog = backendLog.Logger("WIRE")
 	v2trLog = backendLog.Logger(v2transport.Subsystem)
 )
 
@@ -87,6 +89,7 @@ func init() {
 	netsync.UseLogger(syncLog)
 	mempool.UseLogger(txmpLog)
 	v2transport.UseLogger(v2trLog)
+	wire.UseLogger(wireLog)
 }
 
 // subsystemLoggers maps each subsystem identifier to its associated logger.
@@ -106,6 +109,7 @@ var subsystemLoggers = map[string]btclog.Logger{
 	"SRVR":                srvrLog,
 	"SYNC":                syncLog,
 	"TXMP":                txmpLog,
+	"WIRE":                wireLog,
 	v2transport.Subsystem: v2trLog,
 }
 
@@ -0,0 +1,43 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"github.com/btcsuite/btclog"
+)
+
+// Logger is the subset of btclog.Logger used by the package, so any
+// btclog.Logger, or an adapter for another logging library, may be provided to
+// UseLogger.
+type Logger interface {
+	// Debugf formats a message at the debug level.
+	Debugf(format string, params ...interface{})
+
+	// Warnf formats a message at the warn level.
+	Warnf(format string, params ...interface{})
+}
+
+// log is a logger that is initialized with no output filters.  This
+// means the package will not perform any logging by default until the caller
+// requests it.
+var log Logger
+
+// The default amount of logging is none.
+func init() {
+	DisableLog()
+}
+
+// DisableLog disables all library log output.  Logging output is disabled
+// by default until UseLogger is called.
+func DisableLog() {
+	log = btclog.Disabled
+}
+
+// UseLogger uses a specified Logger to output package logging info.  Only the
+// conversion and hashing of transactions is logged, such as when data is
+// silently discarded, and nothing is logged on the txid hot paths.
+func UseLogger(logger Logger) {
+	log = logger
+}
@@ -106,6 +106,10 @@ const (
 	// cannot be more than 4000000.
 	maxWitnessItemsPerInput = 4 * 1024 * 1024 * 1024
 
+	// maxWitnessItemsPrealloc is the maximum number of witness items to
+	// allocate space for before any of them have been read.
+	maxWitnessItemsPrealloc = 1024
+
 	// maxWitnessItemSize is the maximum allowed size for an item within
 	// an input's witness data. This value is bounded by the largest
 	// possible block size, post segwit v1 (taproot).
@@ -378,15 +382,25 @@ func (msg *MsgTx) AddTxOut(to *TxOut) {
 	msg.TxOut = append(msg.TxOut, to)
 }
 
-// TxHash generates the Hash for the transaction.
+// TxHash generates the Hash for the transaction.  The hook set by
+// SetTxIDMetrics, if any, is called once for each hash generated.
 func (msg *MsgTx) TxHash() chainhash.Hash {
-	// 对于版本10的交易, 使用自定义的三层哈希逻辑
-	if msg.Version == 10 {
+	onHash, start := startTxIDMetrics()
+	hash := msg.txHash()
+	reportTxIDMetrics(onHash, uint32(msg.Version), start)
+	return hash
+}
+
+// txHash generates the Hash for the transaction as described by TxHash
+// without invoking the hook set by SetTxIDMetrics.
+func (msg *MsgTx) txHash() chainhash.Hash {
+	// 对于注册了哈希策略的交易版本 (默认为版本10的三层哈希逻辑), 使用该策略
+	if _, ok := lookupTxIDStrategy(uint32(msg.Version)); ok {
 		// 1. 将wire.MsgTx转换为通用Transaction结构
 		commonTx := ConvertWireMsgTxToCommonTransaction(msg)
 
 		// 2. 计算txid
-		txidBytes := CalculateTxID(nil, commonTx)
+		txidBytes := calculateTxID(nil, commonTx)
 
 		// 3. 转换为chainhash.Hash
 		var hash chainhash.Hash
@@ -394,7 +408,7 @@ func (msg *MsgTx) TxHash() chainhash.Hash {
 		return hash
 	}
 
-	// 对于非版本10的交易, 使用标准的txid计算方法
+	// 对于其他版本的交易, 使用标准的txid计算方法
 	return chainhash.DoubleHashRaw(msg.SerializeNoWitness)
 }
 
@@ -626,16 +640,23 @@ func (msg *MsgTx) btcDecode(r io.Reader, pver uint32, enc MessageEncoding,
 			// Then for witCount number of stack items, each item
 			// has a varint length prefix, followed by the witness
 			// item itself.
-			txin.Witness = make([][]byte, witCount)
+			//
+			// Since the count is only bounded by the generous limit
+			// above, the stack is grown as the items are actually
+			// read rather than allocated up front.
+			txin.Witness = make(
+				[][]byte, 0, min(witCount, maxWitnessItemsPrealloc),
+			)
 			for j := uint64(0); j < witCount; j++ {
-				txin.Witness[j], err = readScriptBuf(
+				item, err := readScriptBuf(
 					r, pver, buf, sbuf, "script witness item",
 				)
 				if err != nil {
 					return err
 				}
-				totalScriptSize += uint64(len(txin.Witness[j]))
-				sbuf = sbuf[len(txin.Witness[j]):]
+				txin.Witness = append(txin.Witness, item)
+				totalScriptSize += uint64(len(item))
+				sbuf = sbuf[len(item):]
 			}
 		}
 
@@ -1053,6 +1074,16 @@ func readScriptBuf(r io.Reader, pver uint32, buf, s []byte,
 		return nil, messageError("readScript", str)
 	}
 
+	// The script is read into what remains of the caller's script buffer,
+	// so it must also fit into that.  Otherwise a crafted length below the
+	// limit above would panic.
+	if count > uint64(len(s)) {
+		str := fmt.Sprintf("%s is larger than the remaining script "+
+			"buffer [count %d, remaining %d]", fieldName, count,
+			len(s))
+		return nil, messageError("readScript", str)
+	}
+
 	_, err = io.ReadFull(r, s[:count])
 	if err != nil {
 		return nil, err
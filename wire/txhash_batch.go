@@ -0,0 +1,248 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// CalculateTxIDs computes the txids of a batch of transactions, such as all of
+// the transactions in a block, by spreading the work across a bounded pool of
+// runtime.NumCPU() goroutines.  The returned txids are in the same order as
+// the provided transactions.
+//
+// Each transaction is validated as described by CalculateTxIDErr.  When raws
+// is not nil, it must contain the raw serialized bytes of every transaction at
+// the same index.  It may be nil when every transaction is version 10 since
+// their txids do not depend on the raw bytes.
+//
+// In the case any of the transactions are invalid, the error for the one with
+// the lowest index is returned, identifying that index, and no txids are
+// returned.
+func CalculateTxIDs(raws [][]byte, txs []*Transaction) ([][]byte, error) {
+	return CalculateTxIDsCtx(context.Background(), raws, txs)
+}
+
+// CalculateTxIDsCtx is a variant of CalculateTxIDs which stops early when the
+// provided context is canceled, such as when a reorg invalidates the block
+// being processed.  Each worker checks the context before starting on every
+// transaction, and ctx.Err() is returned, with any partial results discarded,
+// if it is done before all of the txids have been computed.
+func CalculateTxIDsCtx(ctx context.Context, raws [][]byte,
+	txs []*Transaction) ([][]byte, error) {
+
+	return calcTxIDs(ctx, raws, txs, 0)
+}
+
+// CalculateTxIDsWithConcurrency is a variant of CalculateTxIDs which uses at
+// most the given number of worker goroutines rather than runtime.NumCPU().
+// This allows background work, such as indexing, to limit its CPU usage so
+// it doesn't starve other goroutines.  A workers value of zero or less means
+// runtime.NumCPU(), as with CalculateTxIDs, while a value of one computes the
+// txids sequentially on a single goroutine.
+func CalculateTxIDsWithConcurrency(raws [][]byte, txs []*Transaction,
+	workers int) ([][]byte, error) {
+
+	return calcTxIDs(context.Background(), raws, txs, workers)
+}
+
+// calcTxIDs implements CalculateTxIDsCtx using at most the given number of
+// worker goroutines, or runtime.NumCPU() when it is zero or less.
+func calcTxIDs(ctx context.Context, raws [][]byte, txs []*Transaction,
+	workers int) ([][]byte, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if raws != nil && len(raws) != len(txs) {
+		return nil, fmt.Errorf("%d raw transactions provided for %d "+
+			"transactions", len(raws), len(txs))
+	}
+
+	numWorkers := workers
+	if numWorkers <= 0 {
+		numWorkers = runtime.NumCPU()
+	}
+	if numWorkers > len(txs) {
+		numWorkers = len(txs)
+	}
+
+	// Rather than dispatching each transaction over a channel, workers
+	// claim the next unprocessed index from a shared counter.  Every
+	// worker writes only to the slots for the indices it claimed, so no
+	// further synchronization is needed for the results.
+	var (
+		ids  = make([][]byte, len(txs))
+		errs = make([]error, len(txs))
+		next atomic.Int64
+		wg   sync.WaitGroup
+	)
+	wg.Add(numWorkers)
+	for w := 0; w < numWorkers; w++ {
+		go func() {
+			defer wg.Done()
+
+			// Each worker reuses its own sha256 states for the
+			// layered txids of all of the transactions it handles.
+			h := NewTxIDHasher()
+			for ctx.Err() == nil {
+				i := int(next.Add(1) - 1)
+				if i >= len(txs) {
+					return
+				}
+
+				var raw []byte
+				if raws != nil {
+					raw = raws[i]
+				}
+				ids[i], errs[i] = calcTxIDErr(raw, txs[i], h)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The context is checked once more since the workers may have stopped
+	// early, leaving some of the txids uncomputed.
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i, err)
+		}
+	}
+
+	return ids, nil
+}
+
+// calcTxIDErr validates and computes the txid of a single transaction as
+// described by CalculateTxIDErr, using the provided hasher for the built-in
+// layered txid strategy.
+func calcTxIDErr(raw []byte, tx *Transaction, h *TxIDHasher) ([]byte, error) {
+	if err := validateTxForHashing(raw, tx, nil); err != nil {
+		return nil, err
+	}
+
+	entry, ok := lookupTxIDStrategy(tx.Version)
+	switch {
+	case !ok:
+		return calcStandardTxID(raw, tx)
+
+	case entry.layered:
+		return calcV10TxID(tx, h), nil
+
+	default:
+		return entry.strategy(tx.InternalOrder()), nil
+	}
+}
+
+// BuildTxIDIndex returns a map of the provided transactions, such as all of
+// the transactions in a block, keyed by their txid as a display order hex
+// string as returned by TxIDString.  The txids are computed with
+// CalculateTxIDs, so they respect any registered TxIDStrategy and each
+// transaction is validated as described by CalculateTxIDErr.
+//
+// An error is returned if any transaction is invalid or can't be serialized,
+// if a registered strategy produces a txid which is not 32 bytes, or if two
+// transactions have the same txid, since that indicates a malformed block.
+func BuildTxIDIndex(txs []*Transaction) (map[string]*Transaction, error) {
+	ids, err := calcBatchTxIDs("BuildTxIDIndex", txs)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]*Transaction, len(txs))
+	positions := make(map[TxID]int, len(txs))
+	for i, id := range ids {
+		if j, ok := positions[id]; ok {
+			return nil, fmt.Errorf("transactions %d and %d have "+
+				"the same txid %s", j, i, id)
+		}
+		index[id.String()] = txs[i]
+		positions[id] = i
+	}
+
+	return index, nil
+}
+
+// DedupTransactions returns the provided transactions, such as those of
+// overlapping blocks seen during a reorg, with every transaction whose txid
+// matches that of an earlier one removed, so only the first occurrence of
+// each txid is kept and the order is otherwise preserved.  The txids are
+// computed as described by BuildTxIDIndex, and the same errors are returned
+// for transactions which fail to hash.  The returned slice does not share its
+// backing array with txs, while the transactions themselves are shared.
+func DedupTransactions(txs []*Transaction) ([]*Transaction, error) {
+	ids, err := calcBatchTxIDs("DedupTransactions", txs)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[TxID]struct{}, len(txs))
+	deduped := make([]*Transaction, 0, len(txs))
+	for i, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		deduped = append(deduped, txs[i])
+	}
+
+	return deduped, nil
+}
+
+// calcBatchTxIDs computes the txids of the transactions with CalculateTxIDs
+// on behalf of the function op, serializing only those which are hashed over
+// their raw bytes.  An error is returned if any transaction is invalid or
+// can't be serialized, or if a registered strategy produces a txid which is
+// not 32 bytes.
+func calcBatchTxIDs(op string, txs []*Transaction) ([]TxID, error) {
+	// Only transactions which are hashed over their raw bytes need to be
+	// serialized.
+	raws := make([][]byte, len(txs))
+	for i, tx := range txs {
+		if tx == nil {
+			return nil, fmt.Errorf("transaction %d: %w", i,
+				invalidTxError(op, -1, ErrNilTx, "nil "+
+					"transaction"))
+		}
+		if _, ok := lookupTxIDStrategy(tx.Version); ok {
+			continue
+		}
+
+		raw, err := tx.Bytes()
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i, err)
+		}
+		raws[i] = raw
+	}
+
+	ids, err := CalculateTxIDs(raws, txs)
+	if err != nil {
+		return nil, err
+	}
+
+	txids := make([]TxID, len(ids))
+	for i, id := range ids {
+		// Registered strategies aren't guaranteed to produce a valid
+		// txid.
+		if len(id) != chainhash.HashSize {
+			return nil, fmt.Errorf("transaction %d: %w", i,
+				invalidTxError(op, -1, ErrBadTxIDLen, "%d "+
+					"byte txid from strategy for "+
+					"version %d", len(id),
+					txs[i].Version))
+		}
+		txids[i] = TxID(id)
+	}
+
+	return txids, nil
+}
@@ -0,0 +1,313 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// v10TestBlock returns numTxs distinct version 10 transactions with the given
+// number of inputs and outputs each.
+func v10TestBlock(numTxs, numIn, numOut int) []*Transaction {
+	txs := make([]*Transaction, numTxs)
+	for i := range txs {
+		txs[i] = largeV10TestTx(numIn, numOut)
+		txs[i].LockTime = uint32(i)
+	}
+	return txs
+}
+
+// TestCalculateTxIDs ensures the batch API returns the same txids, in the
+// same order, as computing each one individually, and that it reports the
+// lowest index of any invalid transaction.
+func TestCalculateTxIDs(t *testing.T) {
+	t.Parallel()
+
+	txs := v10TestBlock(100, 3, 2)
+
+	// Mix in some standard transactions which are hashed over their raw
+	// bytes.
+	raws := make([][]byte, len(txs))
+	for i := 0; i < len(txs); i += 7 {
+		txs[i].Version = 1
+		raws[i] = bytes.Repeat([]byte{byte(i)}, 100)
+	}
+
+	ids, err := CalculateTxIDs(raws, txs)
+	require.NoError(t, err)
+	require.Len(t, ids, len(txs))
+	for i, tx := range txs {
+		require.Equal(t, CalculateTxID(raws[i], tx), ids[i])
+	}
+
+	// An empty batch produces no txids.
+	ids, err = CalculateTxIDs(nil, nil)
+	require.NoError(t, err)
+	require.Empty(t, ids)
+
+	// Raw bytes are required for standard transactions.
+	_, err = CalculateTxIDs(nil, txs)
+	require.ErrorIs(t, err, ErrInvalidTxForHashing)
+	require.Contains(t, err.Error(), "transaction 0:")
+
+	// The raw bytes must line up with the transactions.
+	_, err = CalculateTxIDs(raws[1:], txs)
+	require.Error(t, err)
+
+	// The first invalid transaction is the one reported.
+	txs[90].TxInCount++
+	txs[43].TxOutCount++
+	_, err = CalculateTxIDs(raws, txs)
+	require.ErrorIs(t, err, ErrInvalidTxForHashing)
+	require.Contains(t, err.Error(), "transaction 43:")
+}
+
+// TestCalculateTxIDsCtx ensures the batch API stops early and discards all
+// results when its context is canceled.
+func TestCalculateTxIDsCtx(t *testing.T) {
+	restoreTxIDStrategies(t)
+
+	// A context which is canceled up front computes nothing.
+	txs := v10TestBlock(10, 1, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ids, err := CalculateTxIDsCtx(ctx, nil, txs)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Nil(t, ids)
+
+	// Cancel the context from within the strategy of the first transaction
+	// it is called for.  No worker starts another transaction afterwards,
+	// so at most one transaction per worker is hashed.
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	var calls atomic.Int32
+	RegisterTxIDStrategy(7, func(tx *Transaction) []byte {
+		calls.Add(1)
+		cancel()
+		return make([]byte, 32)
+	})
+	txs = v10TestBlock(1000, 1, 1)
+	for _, tx := range txs {
+		tx.Version = 7
+	}
+	ids, err = CalculateTxIDsCtx(ctx, nil, txs)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Nil(t, ids)
+	require.LessOrEqual(t, int(calls.Load()), runtime.NumCPU())
+
+	// A context which is never canceled behaves like CalculateTxIDs.
+	txs = v10TestBlock(10, 1, 1)
+	ids, err = CalculateTxIDsCtx(context.Background(), nil, txs)
+	require.NoError(t, err)
+	want, err := CalculateTxIDs(nil, txs)
+	require.NoError(t, err)
+	require.Equal(t, want, ids)
+}
+
+// TestCalculateTxIDsWithConcurrency ensures the batch API uses no more than
+// the requested number of workers, and that with a single worker it behaves
+// exactly like computing each txid in turn.
+func TestCalculateTxIDsWithConcurrency(t *testing.T) {
+	restoreTxIDStrategies(t)
+
+	// sequential computes the txids one at a time, returning the first
+	// error in the same form as the batch API.
+	sequential := func(raws [][]byte, txs []*Transaction) ([][]byte,
+		error) {
+
+		ids := make([][]byte, len(txs))
+		for i, tx := range txs {
+			var err error
+			ids[i], err = CalculateTxIDErr(raws[i], tx)
+			if err != nil {
+				return nil, fmt.Errorf("transaction %d: %w",
+					i, err)
+			}
+		}
+		return ids, nil
+	}
+
+	txs := v10TestBlock(50, 2, 2)
+	raws := make([][]byte, len(txs))
+	for i := 0; i < len(txs); i += 5 {
+		txs[i].Version = 1
+		raws[i] = bytes.Repeat([]byte{byte(i)}, 100)
+	}
+
+	want, err := sequential(raws, txs)
+	require.NoError(t, err)
+	for _, workers := range []int{-1, 0, 1, 2, 1000} {
+		ids, err := CalculateTxIDsWithConcurrency(raws, txs, workers)
+		require.NoError(t, err, workers)
+		require.Equal(t, want, ids, workers)
+	}
+
+	txs[31].TxOutCount++
+	txs[12].TxInCount++
+	_, wantErr := sequential(raws, txs)
+	require.Error(t, wantErr)
+	ids, err := CalculateTxIDsWithConcurrency(raws, txs, 1)
+	require.Nil(t, ids)
+	require.ErrorIs(t, err, ErrInvalidTxForHashing)
+	require.Equal(t, wantErr.Error(), err.Error())
+
+	// Track the order the transactions are hashed in and the greatest
+	// number being hashed at once.
+	var (
+		mtx             sync.Mutex
+		order           []uint32
+		active, maxSeen int
+	)
+	RegisterTxIDStrategy(7, func(tx *Transaction) []byte {
+		mtx.Lock()
+		order = append(order, tx.LockTime)
+		active++
+		maxSeen = max(maxSeen, active)
+		mtx.Unlock()
+
+		time.Sleep(100 * time.Microsecond)
+
+		mtx.Lock()
+		active--
+		mtx.Unlock()
+		return make([]byte, 32)
+	})
+	txs = v10TestBlock(50, 1, 1)
+	for _, tx := range txs {
+		tx.Version = 7
+	}
+
+	for _, workers := range []int{1, 2, 3} {
+		order, maxSeen = nil, 0
+		_, err := CalculateTxIDsWithConcurrency(nil, txs, workers)
+		require.NoError(t, err)
+		require.LessOrEqual(t, maxSeen, workers)
+		require.Len(t, order, len(txs))
+
+		if workers == 1 {
+			for i, lockTime := range order {
+				require.Equal(t, uint32(i), lockTime)
+			}
+		}
+	}
+}
+
+// BenchmarkCalculateTxIDsSequential benchmarks computing the txids of a full
+// block of version 10 transactions one at a time for comparison with
+// BenchmarkCalculateTxIDs.
+func BenchmarkCalculateTxIDsSequential(b *testing.B) {
+	txs := v10TestBlock(3000, 2, 2)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, tx := range txs {
+			CalculateTxID(nil, tx)
+		}
+	}
+}
+
+// BenchmarkCalculateTxIDs benchmarks computing the txids of a full block of
+// version 10 transactions with the parallel batch API.
+func BenchmarkCalculateTxIDs(b *testing.B) {
+	txs := v10TestBlock(3000, 2, 2)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := CalculateTxIDs(nil, txs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// TestBuildTxIDIndex ensures transactions are indexed by their display txid
+// and that duplicate txids are rejected.
+func TestBuildTxIDIndex(t *testing.T) {
+	t.Parallel()
+
+	txs := v10TestBlock(20, 2, 2)
+	txs = append(txs, ConvertWireMsgTxToCommonTransaction(multiTx))
+
+	index, err := BuildTxIDIndex(txs)
+	require.NoError(t, err)
+	require.Len(t, index, len(txs))
+	for _, tx := range txs {
+		require.Same(t, tx, index[TxIDString(tx.TxID())])
+	}
+	hash := multiTx.TxHash()
+	require.Same(t, txs[len(txs)-1], index[hash.String()])
+
+	index, err = BuildTxIDIndex(nil)
+	require.NoError(t, err)
+	require.Empty(t, index)
+
+	// The same transaction twice has a duplicate txid.
+	dup := append(txs[:3:3], txs[1].Clone())
+	_, err = BuildTxIDIndex(dup)
+	require.ErrorContains(t, err, "transactions 1 and 3")
+
+	_, err = BuildTxIDIndex([]*Transaction{txs[0], nil})
+	require.ErrorIs(t, err, ErrInvalidTxForHashing)
+
+	standard := ConvertWireMsgTxToCommonTransaction(multiTx)
+	standard.TxInCount++
+	_, err = BuildTxIDIndex([]*Transaction{standard})
+	require.Error(t, err)
+}
+
+// TestDedupTransactions ensures only the first occurrence of each txid is kept,
+// including for equal transactions which are distinct values, and that
+// transactions which fail to hash are reported.
+func TestDedupTransactions(t *testing.T) {
+	t.Parallel()
+
+	txs := v10TestBlock(5, 2, 2)
+	txs = append(txs, ConvertWireMsgTxToCommonTransaction(multiTx))
+
+	deduped, err := DedupTransactions(txs)
+	require.NoError(t, err)
+	require.Equal(t, txs, deduped)
+
+	// Overlapping sets of the same transactions, some of which are copies
+	// rather than the same values.
+	overlapping := []*Transaction{
+		txs[0], txs[1], txs[5], txs[1].Clone(), txs[2], txs[0],
+		ConvertWireMsgTxToCommonTransaction(multiTx), txs[3],
+	}
+	deduped, err = DedupTransactions(overlapping)
+	require.NoError(t, err)
+	want := []*Transaction{txs[0], txs[1], txs[5], txs[2], txs[3]}
+	require.Len(t, deduped, len(want))
+	for i := range want {
+		require.Same(t, want[i], deduped[i], "transaction %d", i)
+	}
+
+	// The returned slice can be modified without affecting the input.
+	deduped[0] = nil
+	require.NotNil(t, overlapping[0])
+
+	deduped, err = DedupTransactions(nil)
+	require.NoError(t, err)
+	require.Empty(t, deduped)
+
+	_, err = DedupTransactions([]*Transaction{txs[0], nil})
+	require.ErrorIs(t, err, ErrInvalidTxForHashing)
+	require.ErrorContains(t, err, "transaction 1")
+
+	bad := txs[2].Clone()
+	bad.TxIn[0].Hash = bad.TxIn[0].Hash[:31]
+	_, err = DedupTransactions([]*Transaction{txs[0], bad})
+	require.ErrorIs(t, err, ErrBadHashLen)
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+)
+
+// HashPrevouts returns the hashPrevouts field of the BIP0143 signature hash
+// preimage, which is the double sha256 of the previous outpoint of every
+// input, each serialized as its hash in internal byte order followed by its
+// index as a 4 byte little-endian value.  This is the inputs layer of the
+// version 10 txid without the sequence numbers, hashed twice.
+//
+// Along with HashSequence and HashOutputs, it allows the signature hashes of
+// segwit inputs to be computed from a Transaction.  Each is the same as the
+// corresponding midstate computed by txscript.NewTxSigHashes for the
+// equivalent MsgTx.  The previous outpoint hashes of a transaction with a
+// HashOrder of HashOrderDisplay are reversed first.  The zero hash is returned
+// if the transaction or any of its inputs is nil or if any previous outpoint
+// hash is not exactly 32 bytes.
+func HashPrevouts(tx *Transaction) [sha256.Size]byte {
+	const op = "HashPrevouts"
+	if err := checkPrevoutHashes(op, tx); err != nil {
+		log.Warnf("Unable to compute %s: %v", op, err)
+		return [sha256.Size]byte{}
+	}
+
+	var scratch [4]byte
+	h := newSha256()
+	for _, input := range tx.InternalOrder().TxIn {
+		h.Write(input.Hash)
+		binary.LittleEndian.PutUint32(scratch[:], input.Index)
+		h.Write(scratch[:])
+	}
+
+	return sumDoubleSha256(h)
+}
+
+// HashSequence returns the hashSequence field of the BIP0143 signature hash
+// preimage, which is the double sha256 of the sequence number of every input,
+// each serialized as a 4 byte little-endian value.  The zero hash is returned
+// if the transaction or any of its inputs is nil.  See HashPrevouts.
+func HashSequence(tx *Transaction) [sha256.Size]byte {
+	const op = "HashSequence"
+	if err := checkBIP143Inputs(op, tx); err != nil {
+		log.Warnf("Unable to compute %s: %v", op, err)
+		return [sha256.Size]byte{}
+	}
+
+	var scratch [4]byte
+	h := newSha256()
+	for _, input := range tx.TxIn {
+		binary.LittleEndian.PutUint32(scratch[:], input.Sequence)
+		h.Write(scratch[:])
+	}
+
+	return sumDoubleSha256(h)
+}
+
+// HashOutputs returns the hashOutputs field of the BIP0143 signature hash
+// preimage, which is the double sha256 of every output in its wire
+// serialization, being its value as an 8 byte little-endian value followed by
+// its public key script prefixed by its length as a variable length integer.
+// Unlike the outputs layer of the version 10 txid, the scripts are serialized
+// as is rather than hashed.  The zero hash is returned if the transaction or
+// any of its outputs is nil.  See HashPrevouts.
+func HashOutputs(tx *Transaction) [sha256.Size]byte {
+	const op = "HashOutputs"
+	if tx == nil {
+		log.Warnf("Unable to compute %s: %v", op,
+			txHashError(op, -1, ErrNilTx, "nil transaction"))
+		return [sha256.Size]byte{}
+	}
+	for i, output := range tx.TxOut {
+		if output == nil {
+			log.Warnf("Unable to compute %s: %v", op,
+				txHashError(op, i, ErrNilTx, "output %d is "+
+					"nil", i))
+			return [sha256.Size]byte{}
+		}
+	}
+
+	var scratch [8]byte
+	h := newSha256()
+	for _, output := range tx.TxOut {
+		binary.LittleEndian.PutUint64(scratch[:], output.Value)
+		h.Write(scratch[:])
+
+		// Writes to a hash.Hash never fail.
+		script := output.PkScript.Pkscript
+		_ = WriteVarInt(h, 0, uint64(len(script)))
+		h.Write(script)
+	}
+
+	return sumDoubleSha256(h)
+}
+
+// checkBIP143Inputs returns a TxHashError for the given function if the
+// transaction or any of its inputs is nil.
+func checkBIP143Inputs(op string, tx *Transaction) error {
+	if tx == nil {
+		return txHashError(op, -1, ErrNilTx, "nil transaction")
+	}
+	for i, input := range tx.TxIn {
+		if input == nil {
+			return txHashError(op, i, ErrNilTx, "input %d is nil",
+				i)
+		}
+	}
+	return nil
+}
+
+// sumDoubleSha256 returns the sha256 of the digest of the data written to h,
+// which is the double sha256 of that data.
+func sumDoubleSha256(h hash.Hash) [sha256.Size]byte {
+	var first [sha256.Size]byte
+	return sum256(h.Sum(first[:0]))
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBIP143Hashes ensures the signature hash midstates match the native
+// P2WPKH example of BIP0143 and an independent serialization of the
+// equivalent MsgTx, for either HashOrder, and that malformed transactions
+// produce the zero hash.
+func TestBIP143Hashes(t *testing.T) {
+	t.Parallel()
+
+	// The unsigned transaction of the native P2WPKH example in BIP0143.
+	rawTx, err := hex.DecodeString("" +
+		"0100000002fff7f7881a8099afa6940d42d1e7f6362bec38171ea3edf433" +
+		"541db4e4ad969f0000000000eeffffffef51e1b804cc89d182d279655c3a" +
+		"a89e815b1b309fe287d9b2b55d57b90ec68a0100000000ffffffff02202c" +
+		"b206000000001976a9148280b37df378db99f66f85c95a783a76ac7a6d59" +
+		"88ac9093510d000000001976a9143bde42dbee7e4dbe6a21b2d50ce2f016" +
+		"7faa815988ac11000000")
+	require.NoError(t, err)
+
+	var msgTx MsgTx
+	require.NoError(t, msgTx.Deserialize(bytes.NewReader(rawTx)))
+	tx := ConvertWireMsgTxToCommonTransaction(&msgTx)
+
+	hashHex := func(h [32]byte) string {
+		return hex.EncodeToString(h[:])
+	}
+	const (
+		wantPrevouts = "96b827c8483d4e9b96712b6713a7b68d" +
+			"6e8003a781feba36c31143470b4efd37"
+		wantSequence = "52b0a642eea2fb7ae638c36f6252b675" +
+			"0293dbe574a806984b8e4d8548339a3b"
+		wantOutputs = "863ef3e1a92afbfdb97f31ad0fc7683e" +
+			"e943e9abcf2501590ff8f6551f47e5e5"
+	)
+	require.Equal(t, wantPrevouts, hashHex(HashPrevouts(tx)))
+	require.Equal(t, wantSequence, hashHex(HashSequence(tx)))
+	require.Equal(t, wantOutputs, hashHex(HashOutputs(tx)))
+
+	be := ConvertWireMsgTxToCommonTransactionBE(&msgTx)
+	require.Equal(t, wantPrevouts, hashHex(HashPrevouts(be)))
+
+	// An independent serialization of a larger transaction.
+	large := largeV10TestTx(20, 30)
+	largeMsgTx, err := ConvertCommonTransactionToWireMsgTx(large)
+	require.NoError(t, err)
+	var prevouts, sequences []byte
+	var outputs bytes.Buffer
+	for _, txIn := range largeMsgTx.TxIn {
+		prevOut := txIn.PreviousOutPoint
+		prevouts = append(prevouts, prevOut.Hash[:]...)
+		prevouts = binary.LittleEndian.AppendUint32(prevouts,
+			prevOut.Index)
+		sequences = binary.LittleEndian.AppendUint32(sequences,
+			txIn.Sequence)
+	}
+	for _, txOut := range largeMsgTx.TxOut {
+		require.NoError(t, WriteTxOut(&outputs, 0, 0, txOut))
+	}
+	require.Equal(t, chainhash.DoubleHashH(prevouts),
+		chainhash.Hash(HashPrevouts(large)))
+	require.Equal(t, chainhash.DoubleHashH(sequences),
+		chainhash.Hash(HashSequence(large)))
+	require.Equal(t, chainhash.DoubleHashH(outputs.Bytes()),
+		chainhash.Hash(HashOutputs(large)))
+
+	// Malformed transactions produce the zero hash.
+	var zero [32]byte
+	require.Equal(t, zero, HashPrevouts(nil))
+	require.Equal(t, zero, HashSequence(nil))
+	require.Equal(t, zero, HashOutputs(nil))
+
+	shortHash := largeV10TestTx(2, 2)
+	shortHash.TxIn[1].Hash = shortHash.TxIn[1].Hash[:31]
+	require.Equal(t, zero, HashPrevouts(shortHash))
+	require.NotEqual(t, zero, HashSequence(shortHash))
+
+	nilEntries := largeV10TestTx(2, 2)
+	nilEntries.TxIn[0] = nil
+	nilEntries.TxOut[1] = nil
+	require.Equal(t, zero, HashPrevouts(nilEntries))
+	require.Equal(t, zero, HashSequence(nilEntries))
+	require.Equal(t, zero, HashOutputs(nilEntries))
+}
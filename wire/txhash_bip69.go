@@ -0,0 +1,80 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"sort"
+)
+
+// SortBIP69 sorts the inputs and outputs of the transaction in place into the
+// canonical order defined by BIP0069, which allows wallets to construct
+// transactions deterministically without revealing anything through the
+// order of their entries.  Inputs are sorted by their previous outpoint hash
+// in display byte order, honoring HashOrder, and then by index, while outputs
+// are sorted by value and then by the bytes of their public key script.  Nil
+// inputs and outputs are moved to the end.  It is the same order produced by
+// the btcutil/txsort package for the equivalent MsgTx.
+//
+// Sorting changes the txid whenever any entry is moved, so it must be called
+// before the transaction is hashed or signed.  Any cached txid is
+// invalidated.
+func (tx *Transaction) SortBIP69() {
+	display := tx.HashOrder == HashOrderDisplay
+	sort.SliceStable(tx.TxIn, func(i, j int) bool {
+		a, b := tx.TxIn[i], tx.TxIn[j]
+		if a == nil || b == nil {
+			return b == nil && a != nil
+		}
+
+		cmp := compareDisplayHashes(a.Hash, b.Hash, display)
+		if cmp != 0 {
+			return cmp < 0
+		}
+		return a.Index < b.Index
+	})
+	sort.SliceStable(tx.TxOut, func(i, j int) bool {
+		a, b := tx.TxOut[i], tx.TxOut[j]
+		if a == nil || b == nil {
+			return b == nil && a != nil
+		}
+
+		if a.Value != b.Value {
+			return a.Value < b.Value
+		}
+		return bytes.Compare(a.PkScript.Pkscript,
+			b.PkScript.Pkscript) < 0
+	})
+
+	tx.InvalidateTxID()
+}
+
+// compareDisplayHashes compares the two previous outpoint hashes in display
+// byte order, returning -1, 0, or 1 in the same way as bytes.Compare.  When
+// display is false the hashes are in internal byte order, so they are
+// compared from their last byte without reversing them, giving the same
+// result as bytes.Compare of their reversals for any length.
+func compareDisplayHashes(a, b []byte, display bool) int {
+	if display {
+		return bytes.Compare(a, b)
+	}
+
+	for i := 0; i < len(a) && i < len(b); i++ {
+		x, y := a[len(a)-1-i], b[len(b)-1-i]
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
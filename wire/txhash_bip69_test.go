@@ -0,0 +1,122 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestTransactionSortBIP69 ensures transactions are sorted according to the
+// BIP0069 test vectors, regardless of the byte order their hashes are held in,
+// and that nil entries are moved to the end.
+func TestTransactionSortBIP69(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		hexFile      string
+		unsortedHash string
+		sortedHash   string
+	}{
+		{
+			"inputs by hash", "bip69-1.hex",
+			"0a6a357e2f7796444e02638749d9611c008b253fb55f5dc88b739b230ed0c4c3",
+			"839503cb611a3e3734bd521c608f881be2293ff77b7384057ab994c794fce623",
+		},
+		{
+			"already sorted", "bip69-2.hex",
+			"28204cad1d7fc1d199e8ef4fa22f182de6258a3eaafe1bbe56ebdcacd3069a5f",
+			"28204cad1d7fc1d199e8ef4fa22f182de6258a3eaafe1bbe56ebdcacd3069a5f",
+		},
+		{
+			"outputs by value", "bip69-3.hex",
+			"fbde5d03b027d2b9ba4cf5d4fecab9a99864df2637b25ea4cbcb1796ff6550ca",
+			"0a8c246c55f6b82f094d211f4f57167bf2ea4898741d218b09bdb2536fd8d13f",
+		},
+		{
+			"inputs and outputs", "bip69-4.hex",
+			"8131ffb0a2c945ecaf9b9063e59558784f9c3a74741ce6ae2a18d0571dac15bb",
+			"a3196553b928b0b6154b002fa9a1ce875adabc486fedaaaf4c17430fd4486329",
+		},
+		{
+			"outputs by script", "bip69-5.hex",
+			"ff85e8fc92e71bbc217e3ea9a3bacb86b435e52b6df0b089d67302c293a2b81d",
+			"9a6c24746de024f77cac9b2138694f11101d1c66289261224ca52a25155a7c94",
+		},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join("testdata", test.hexFile)
+		txHex, err := os.ReadFile(path)
+		require.NoError(t, err, test.name)
+		tx, _, err := ParseTransactionHex(
+			strings.TrimSpace(string(txHex)),
+		)
+		require.NoError(t, err, test.name)
+		require.Equal(t, test.unsortedHash, TxIDString(tx.TxID()),
+			test.name)
+
+		// The display order conversion must sort identically.
+		msgTx, err := ConvertCommonTransactionToWireMsgTx(tx)
+		require.NoError(t, err, test.name)
+		displayTx := ConvertWireMsgTxToCommonTransactionBE(msgTx)
+
+		tx.SortBIP69()
+		require.Equal(t, test.sortedHash, TxIDString(tx.TxID()),
+			test.name)
+
+		displayTx.SortBIP69()
+		require.Equal(t, test.sortedHash,
+			TxIDString(displayTx.TxID()), test.name)
+		require.True(t, displayTx.Equal(tx), test.name)
+	}
+
+	// Nil entries are moved to the end without disturbing the others.
+	tx := largeV10TestTx(3, 3)
+	// The last byte is the most significant in display byte order, so
+	// the last input sorts first even though its first byte is larger.
+	tx.TxIn[0].Hash = make([]byte, 32)
+	tx.TxIn[0].Hash[31] = 0x01
+	tx.TxIn[2].Hash = make([]byte, 32)
+	tx.TxIn[2].Hash[0] = 0x02
+	first, last := tx.TxIn[2], tx.TxIn[0]
+	tx.TxIn[1] = nil
+	tx.TxOut[0].Value = 2
+	tx.TxOut[2].Value = 1
+	tx.TxOut[1] = nil
+	wantOut := []*TxOutput{tx.TxOut[2], tx.TxOut[0], nil}
+
+	tx.SortBIP69()
+	require.Equal(t, []*TxInput{first, last, nil}, tx.TxIn)
+	require.Equal(t, wantOut, tx.TxOut)
+}
+
+// TestCompareDisplayHashes ensures hashes in internal byte order are compared
+// as if they were reversed into display byte order.
+func TestCompareDisplayHashes(t *testing.T) {
+	t.Parallel()
+
+	hashes := [][]byte{
+		nil, {0x01}, {0x02, 0x01}, {0x01, 0x02}, {0x00, 0x00, 0x02},
+		{0x01, 0x01}, {0xff},
+	}
+	for _, a := range hashes {
+		for _, b := range hashes {
+			want := bytes.Compare(ReverseBytes(a), ReverseBytes(b))
+			got := compareDisplayHashes(a, b, false)
+			require.Equal(t, want, got, "%x %x", a, b)
+
+			want = bytes.Compare(a, b)
+			got = compareDisplayHashes(a, b, true)
+			require.Equal(t, want, got, "%x %x", a, b)
+		}
+	}
+}
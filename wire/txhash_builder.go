@@ -0,0 +1,90 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// NewTransaction returns a new transaction with the given version and locktime
+// and no inputs or outputs, which can be added with AddInput and AddOutput.
+// The previous outpoint hashes are in internal byte order, as for a
+// transaction converted by ConvertWireMsgTxToCommonTransaction.
+func NewTransaction(version, lockTime uint32) *Transaction {
+	return &Transaction{
+		Version:  version,
+		LockTime: lockTime,
+	}
+}
+
+// AddInput appends an input spending the previous outpoint with the given hash,
+// in the byte order given by the HashOrder of the transaction, and index,
+// along with the given sequence number and signature script.  TxInCount is
+// kept in sync with the number of inputs, and the cached txid is invalidated.
+// The hash and script are copied, so the caller may reuse them.
+//
+// An error wrapping ErrBadHashLen is returned if the hash is not exactly 32
+// bytes, and one wrapping ErrScriptTooLarge if the script exceeds
+// DefaultMaxSignatureScriptSize, in which case the input is not added.  The
+// errors are a *TxHashError whose Index is the index the input would have had.
+func (tx *Transaction) AddInput(hash []byte, index, sequence uint32,
+	sigScript []byte) error {
+
+	const op = "AddInput"
+	i := len(tx.TxIn)
+	if len(hash) != chainhash.HashSize {
+		return txHashError(op, i, ErrBadHashLen, "input %d is %d "+
+			"bytes, want %d", i, len(hash), chainhash.HashSize)
+	}
+	if len(sigScript) > DefaultMaxSignatureScriptSize {
+		return txHashError(op, i, ErrScriptTooLarge, "input %d "+
+			"signature script is %d bytes, max %d", i,
+			len(sigScript), DefaultMaxSignatureScriptSize)
+	}
+
+	tx.TxIn = append(tx.TxIn, &TxInput{
+		Hash:            bytes.Clone(hash),
+		Index:           index,
+		SignatureScript: bytes.Clone(sigScript),
+		Sequence:        sequence,
+	})
+	tx.TxInCount = uint(len(tx.TxIn))
+	tx.InvalidateTxID()
+
+	return nil
+}
+
+// AddOutput appends an output with the given value and public key script.
+// TxOutCount is kept in sync with the number of outputs, and the cached txid
+// is invalidated.  The script is copied, so the caller may reuse it.
+//
+// An error wrapping ErrValueTooLarge is returned if the value exceeds
+// MaxTxOutputValue, and one wrapping ErrScriptTooLarge if the script exceeds
+// DefaultMaxPkScriptSize, in which case the output is not added.  See
+// AddInput.
+func (tx *Transaction) AddOutput(value uint64, pkScript []byte) error {
+	const op = "AddOutput"
+	i := len(tx.TxOut)
+	if value > MaxTxOutputValue {
+		return txHashError(op, i, ErrValueTooLarge, "output %d "+
+			"value %d exceeds max %d", i, value, MaxTxOutputValue)
+	}
+	if len(pkScript) > DefaultMaxPkScriptSize {
+		return txHashError(op, i, ErrScriptTooLarge, "output %d "+
+			"public key script is %d bytes, max %d", i,
+			len(pkScript), DefaultMaxPkScriptSize)
+	}
+
+	tx.TxOut = append(tx.TxOut, &TxOutput{
+		Value:    value,
+		PkScript: PkScript{Pkscript: bytes.Clone(pkScript)},
+	})
+	tx.TxOutCount = uint(len(tx.TxOut))
+	tx.InvalidateTxID()
+
+	return nil
+}
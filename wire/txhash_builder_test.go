@@ -0,0 +1,123 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// buildTestTx builds a copy of the transaction with NewTransaction, AddInput,
+// and AddOutput.
+func buildTestTx(t *testing.T, tx *Transaction) *Transaction {
+	t.Helper()
+
+	built := NewTransaction(tx.Version, tx.LockTime)
+	for _, in := range tx.TxIn {
+		err := built.AddInput(in.Hash, in.Index, in.Sequence,
+			in.SignatureScript)
+		require.NoError(t, err)
+	}
+	for _, out := range tx.TxOut {
+		err := built.AddOutput(out.Value, out.PkScript.Pkscript)
+		require.NoError(t, err)
+	}
+
+	return built
+}
+
+// TestTransactionBuilder ensures a transaction built one input and output at
+// a time is equal to and hashes identically to one constructed directly, with
+// counts kept in sync.
+func TestTransactionBuilder(t *testing.T) {
+	t.Parallel()
+
+	standard := ConvertWireMsgTxToCommonTransaction(multiTx)
+	for _, tx := range []*Transaction{
+		v10TestTx(), largeV10TestTx(40, 25), standard,
+	} {
+		built := buildTestTx(t, tx)
+		require.True(t, tx.Equal(built))
+		require.Equal(t, uint(len(tx.TxIn)), built.TxInCount)
+		require.Equal(t, uint(len(tx.TxOut)), built.TxOutCount)
+
+		want := CalculateTxID(mustBytes(t, tx), tx)
+		raw := mustBytes(t, built)
+		require.Equal(t, want, CalculateTxID(raw, built))
+		require.Equal(t, want, built.TxID())
+
+		id, err := CalculateTxIDErr(raw, built)
+		require.NoError(t, err)
+		require.Equal(t, want, id)
+	}
+
+	// The hash and script are copied, and the cached txid is invalidated
+	// by each addition.
+	tx := NewTransaction(LayeredTxIDVersion, 0)
+	hash := bytes.Repeat([]byte{0x11}, 32)
+	script := []byte{0x51}
+	require.NoError(t, tx.AddInput(hash, 0, MaxTxInSequenceNum, script))
+	hash[0], script[0] = 0xff, 0xff
+	require.Equal(t, byte(0x11), tx.TxIn[0].Hash[0])
+	require.Equal(t, byte(0x51), tx.TxIn[0].SignatureScript[0])
+
+	before := tx.TxID()
+	require.NoError(t, tx.AddOutput(1000, script))
+	require.NotEqual(t, before, tx.TxID())
+	require.Equal(t, CalculateTxID(nil, tx), tx.TxID())
+}
+
+// TestTransactionBuilderErrors ensures invalid inputs and outputs are rejected
+// with the index they would have had and without being added.
+func TestTransactionBuilderErrors(t *testing.T) {
+	t.Parallel()
+
+	tx := NewTransaction(LayeredTxIDVersion, 0)
+	require.NoError(t, tx.AddInput(make([]byte, 32), 0, 0, nil))
+	require.NoError(t, tx.AddOutput(0, nil))
+
+	tests := []struct {
+		name     string
+		err      error
+		sentinel error
+	}{{
+		name:     "short hash",
+		err:      tx.AddInput(make([]byte, 31), 0, 0, nil),
+		sentinel: ErrBadHashLen,
+	}, {
+		name:     "nil hash",
+		err:      tx.AddInput(nil, 0, 0, nil),
+		sentinel: ErrBadHashLen,
+	}, {
+		name: "signature script too large",
+		err: tx.AddInput(make([]byte, 32), 0, 0,
+			make([]byte, DefaultMaxSignatureScriptSize+1)),
+		sentinel: ErrScriptTooLarge,
+	}, {
+		name: "public key script too large",
+		err: tx.AddOutput(0, make([]byte,
+			DefaultMaxPkScriptSize+1)),
+		sentinel: ErrScriptTooLarge,
+	}, {
+		name:     "value too large",
+		err:      tx.AddOutput(MaxTxOutputValue+1, nil),
+		sentinel: ErrValueTooLarge,
+	}}
+
+	for _, test := range tests {
+		var txErr *TxHashError
+		require.ErrorAs(t, test.err, &txErr, test.name)
+		require.Equal(t, 1, txErr.Index, test.name)
+		require.ErrorIs(t, test.err, test.sentinel, test.name)
+	}
+
+	require.Len(t, tx.TxIn, 1)
+	require.Len(t, tx.TxOut, 1)
+	require.Equal(t, uint(1), tx.TxInCount)
+	require.Equal(t, uint(1), tx.TxOutCount)
+	require.NoError(t, tx.AddOutput(MaxTxOutputValue, nil))
+}
@@ -0,0 +1,227 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// InputDiff describes an input which differs between two transactions.
+// Inputs are matched by the previous outpoint they spend, so Old is nil for
+// an added input, New is nil for a removed input, and both are set for an
+// input whose signature script, sequence, or witness was modified.
+type InputDiff struct {
+	OutPoint OutPointRef
+	Old      *TxInput
+	New      *TxInput
+}
+
+// OutputDiff describes an output which differs between two transactions.
+// Outputs are matched by their index, so Old is nil for an added output, New
+// is nil for a removed output, and both are set for an output whose value or
+// public key script was modified.
+type OutputDiff struct {
+	Index int
+	Old   *TxOutput
+	New   *TxOutput
+}
+
+// TxDiff describes the differences between two transactions as returned by
+// DiffTransactions, such as between a transaction and its replacement, listing
+// only the inputs and outputs which differ.
+type TxDiff struct {
+	OldVersion  uint32
+	NewVersion  uint32
+	OldLockTime uint32
+	NewLockTime uint32
+
+	// Inputs holds the removed and modified inputs in the order they
+	// appear in the old transaction, followed by the added inputs in the
+	// order they appear in the new transaction.
+	Inputs []InputDiff
+
+	// Outputs holds the modified, added, and removed outputs in order of
+	// their index.
+	Outputs []OutputDiff
+}
+
+// DiffTransactions returns the differences between the transactions a and b,
+// with a treated as the old transaction and b as the new one.  Inputs are
+// matched by the previous outpoint they spend, regardless of their position,
+// while outputs are matched by their index.  Nil inputs are ignored.
+//
+// A nil transaction is treated as one without any inputs or outputs and a
+// version and locktime of zero, so two nil transactions have no differences,
+// while a nil transaction compared to another reports every input and output
+// of the other as added or removed.
+func DiffTransactions(a, b *Transaction) TxDiff {
+	if a == nil {
+		a = &Transaction{}
+	}
+	if b == nil {
+		b = &Transaction{}
+	}
+	a, b = a.InternalOrder(), b.InternalOrder()
+	diff := TxDiff{
+		OldVersion:  a.Version,
+		NewVersion:  b.Version,
+		OldLockTime: a.LockTime,
+		NewLockTime: b.LockTime,
+	}
+
+	newInputs := make(map[string]*TxInput, len(b.TxIn))
+	for _, in := range b.TxIn {
+		if in == nil {
+			continue
+		}
+		if key := in.OutPoint().String(); newInputs[key] == nil {
+			newInputs[key] = in
+		}
+	}
+
+	oldInputs := make(map[string]struct{}, len(a.TxIn))
+	for _, in := range a.TxIn {
+		if in == nil {
+			continue
+		}
+		key := in.OutPoint().String()
+		oldInputs[key] = struct{}{}
+
+		newIn := newInputs[key]
+		if newIn == nil || !txInputsEqual(in, newIn) {
+			diff.Inputs = append(diff.Inputs, InputDiff{
+				OutPoint: in.OutPoint(),
+				Old:      in,
+				New:      newIn,
+			})
+		}
+	}
+	for _, in := range b.TxIn {
+		if in == nil {
+			continue
+		}
+		if _, ok := oldInputs[in.OutPoint().String()]; !ok {
+			diff.Inputs = append(diff.Inputs, InputDiff{
+				OutPoint: in.OutPoint(),
+				New:      in,
+			})
+		}
+	}
+
+	for i := 0; i < max(len(a.TxOut), len(b.TxOut)); i++ {
+		var oldOut, newOut *TxOutput
+		if i < len(a.TxOut) {
+			oldOut = a.TxOut[i]
+		}
+		if i < len(b.TxOut) {
+			newOut = b.TxOut[i]
+		}
+		if !txOutputsEqual(oldOut, newOut) {
+			diff.Outputs = append(diff.Outputs, OutputDiff{
+				Index: i,
+				Old:   oldOut,
+				New:   newOut,
+			})
+		}
+	}
+
+	return diff
+}
+
+// IsEmpty returns whether DiffTransactions found no differences.  Note that
+// the TxInCount and TxOutCount fields are not compared.
+func (d *TxDiff) IsEmpty() bool {
+	return d.OldVersion == d.NewVersion &&
+		d.OldLockTime == d.NewLockTime &&
+		len(d.Inputs) == 0 && len(d.Outputs) == 0
+}
+
+// String returns a single line summary of the differences which is suitable
+// for logging, such as:
+//
+//	locktime 0 -> 500; output 1 modified: value 5000 -> 4000
+func (d *TxDiff) String() string {
+	if d.IsEmpty() {
+		return "no changes"
+	}
+
+	var changes []string
+	if d.OldVersion != d.NewVersion {
+		changes = append(changes, fmt.Sprintf("version %d -> %d",
+			d.OldVersion, d.NewVersion))
+	}
+	if d.OldLockTime != d.NewLockTime {
+		changes = append(changes, fmt.Sprintf("locktime %d -> %d",
+			d.OldLockTime, d.NewLockTime))
+	}
+	for i := range d.Inputs {
+		changes = append(changes, d.Inputs[i].String())
+	}
+	for i := range d.Outputs {
+		changes = append(changes, d.Outputs[i].String())
+	}
+
+	return strings.Join(changes, "; ")
+}
+
+// String returns a summary of the input difference.
+func (d *InputDiff) String() string {
+	prefix := "input " + d.OutPoint.String()
+	switch {
+	case d.Old == nil:
+		return prefix + " added"
+	case d.New == nil:
+		return prefix + " removed"
+	}
+
+	var fields []string
+	if d.Old.Sequence != d.New.Sequence {
+		fields = append(fields, fmt.Sprintf("sequence %d -> %d",
+			d.Old.Sequence, d.New.Sequence))
+	}
+	if !bytes.Equal(d.Old.SignatureScript, d.New.SignatureScript) {
+		fields = append(fields, "signature script")
+	}
+	if !witnessesEqual(d.Old.Witness, d.New.Witness) {
+		fields = append(fields, "witness")
+	}
+
+	return prefix + " modified: " + strings.Join(fields, ", ")
+}
+
+// String returns a summary of the output difference.
+func (d *OutputDiff) String() string {
+	prefix := fmt.Sprintf("output %d", d.Index)
+	switch {
+	case d.Old == nil && d.New == nil:
+		return prefix + " unchanged"
+	case d.Old == nil:
+		return prefix + " added: " + describeOutput(d.New)
+	case d.New == nil:
+		return prefix + " removed: " + describeOutput(d.Old)
+	}
+
+	var fields []string
+	if d.Old.Value != d.New.Value {
+		fields = append(fields, fmt.Sprintf("value %d -> %d",
+			d.Old.Value, d.New.Value))
+	}
+	oldScript, newScript := d.Old.PkScript.Pkscript, d.New.PkScript.Pkscript
+	if !bytes.Equal(oldScript, newScript) {
+		fields = append(fields, fmt.Sprintf("script %x -> %x",
+			oldScript, newScript))
+	}
+
+	return prefix + " modified: " + strings.Join(fields, ", ")
+}
+
+// describeOutput returns the value and script of the output for String.
+func describeOutput(out *TxOutput) string {
+	return fmt.Sprintf("value %d script %s", out.Value,
+		hex.EncodeToString(out.PkScript.Pkscript))
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestDiffTransactions ensures the differences between a transaction and a
+// replacement for it are reported by outpoint for inputs and by index for
+// outputs.
+func TestDiffTransactions(t *testing.T) {
+	t.Parallel()
+
+	orig := largeV10TestTx(3, 3)
+	diff := DiffTransactions(orig, orig.Clone())
+	require.True(t, diff.IsEmpty())
+	require.Empty(t, diff.Inputs)
+	require.Empty(t, diff.Outputs)
+	require.Equal(t, "no changes", diff.String())
+
+	// Replace the transaction with one which reorders its inputs, signals
+	// replacement on one, swaps another for a new one, lowers the value
+	// of an output, and adds an output.
+	replacement := orig.Clone()
+	replacement.LockTime++
+	replacement.TxIn[0], replacement.TxIn[1] = replacement.TxIn[1],
+		replacement.TxIn[0]
+	replacement.TxIn[0].Sequence = 0xfffffffd
+	replacement.TxIn[2] = &TxInput{
+		Hash:     bytes.Repeat([]byte{0xee}, 32),
+		Index:    5,
+		Sequence: 0xffffffff,
+	}
+	replacement.TxOut[1].Value -= 500
+	replacement.TxOut = append(replacement.TxOut, &TxOutput{
+		Value:    700,
+		PkScript: PkScript{Pkscript: []byte{0x51}},
+	})
+	replacement.TxOutCount++
+
+	diff = DiffTransactions(orig, replacement)
+	require.False(t, diff.IsEmpty())
+	require.Equal(t, orig.LockTime, diff.OldLockTime)
+	require.Equal(t, replacement.LockTime, diff.NewLockTime)
+	require.Equal(t, diff.OldVersion, diff.NewVersion)
+
+	require.Len(t, diff.Inputs, 3)
+	modified, removed, added := diff.Inputs[0], diff.Inputs[1],
+		diff.Inputs[2]
+	require.Equal(t, orig.TxIn[1].OutPoint(), modified.OutPoint)
+	require.Same(t, orig.TxIn[1], modified.Old)
+	require.Same(t, replacement.TxIn[0], modified.New)
+	require.Equal(t, orig.TxIn[2].OutPoint(), removed.OutPoint)
+	require.Nil(t, removed.New)
+	require.Equal(t, replacement.TxIn[2].OutPoint(), added.OutPoint)
+	require.Nil(t, added.Old)
+
+	require.Len(t, diff.Outputs, 2)
+	require.Equal(t, 1, diff.Outputs[0].Index)
+	require.Equal(t, 3, diff.Outputs[1].Index)
+	require.Nil(t, diff.Outputs[1].Old)
+
+	want := "locktime 500000 -> 500001; " +
+		"input " + modified.OutPoint.String() + " modified: " +
+		"sequence 7 -> 4294967293; " +
+		"input " + removed.OutPoint.String() + " removed; " +
+		"input " + added.OutPoint.String() + " added; " +
+		"output 1 modified: value 1000 -> 500; " +
+		"output 3 added: value 700 script 51"
+	require.Equal(t, want, diff.String())
+
+	// The reverse diff reports the output as removed, and modified
+	// scripts and witnesses are described.
+	replacement.TxOut[0].PkScript.Pkscript = []byte{0x00, 0x14}
+	replacement.TxIn[0].Witness = [][]byte{{0x01}}
+	replacement.TxIn[0].SignatureScript = nil
+	diff = DiffTransactions(replacement, orig)
+	require.Contains(t, diff.String(), "output 3 removed: value 700 "+
+		"script 51")
+	require.Contains(t, diff.String(), "output 0 modified: script "+
+		"0014 -> ")
+	require.Contains(t, diff.String(), "modified: sequence 4294967293 "+
+		"-> 7, signature script, witness")
+}
+
+// TestDiffTransactionsNil ensures nil transactions are diffed as transactions
+// without any inputs or outputs rather than causing a panic.
+func TestDiffTransactionsNil(t *testing.T) {
+	t.Parallel()
+
+	diff := DiffTransactions(nil, nil)
+	require.True(t, diff.IsEmpty())
+
+	tx := largeV10TestTx(2, 3)
+	diff = DiffTransactions(nil, tx)
+	require.Equal(t, uint32(0), diff.OldVersion)
+	require.Equal(t, tx.Version, diff.NewVersion)
+	require.Equal(t, tx.LockTime, diff.NewLockTime)
+	require.Len(t, diff.Inputs, 2)
+	require.Len(t, diff.Outputs, 3)
+	for i, in := range diff.Inputs {
+		require.Nil(t, in.Old)
+		require.Same(t, tx.TxIn[i], in.New)
+	}
+	for i, out := range diff.Outputs {
+		require.Nil(t, out.Old)
+		require.Same(t, tx.TxOut[i], out.New)
+	}
+
+	diff = DiffTransactions(tx, nil)
+	require.Len(t, diff.Inputs, 2)
+	require.Len(t, diff.Outputs, 3)
+	for i, in := range diff.Inputs {
+		require.Same(t, tx.TxIn[i], in.Old)
+		require.Nil(t, in.New)
+	}
+	for i, out := range diff.Outputs {
+		require.Same(t, tx.TxOut[i], out.Old)
+		require.Nil(t, out.New)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The sentinel errors describing the conditions reported by a TxHashError.
+// They are wrapped by the Err field, so callers should test for them with
+// errors.Is.
+var (
+	// ErrNilTx indicates a nil transaction, or a nil input or output of
+	// one, in which case TxHashError.Index identifies the entry.
+	ErrNilTx = errors.New("nil transaction or entry")
+
+	// ErrBadHashLen indicates a previous outpoint hash which is not 32
+	// bytes.  It is the same value as ErrBadPrevoutHashLength.
+	ErrBadHashLen = ErrBadPrevoutHashLength
+
+	// ErrCountMismatch indicates a TxInCount or TxOutCount which does not
+	// match the number of inputs or outputs of the transaction.
+	ErrCountMismatch = errors.New("count mismatch")
+
+	// ErrNegativeValue indicates a wire output with a negative value,
+	// which can't be represented by TxOutput.
+	ErrNegativeValue = errors.New("negative output value")
+
+	// ErrValueTooLarge indicates an output, or the sum of the outputs,
+	// with a value exceeding MaxTxOutputValue, or an output value which
+	// does not fit in the signed 64-bit wire representation.
+	ErrValueTooLarge = errors.New("output value too large")
+
+	// ErrDuplicateInputs indicates more than one input spending the same
+	// previous outpoint, as reported by HasDuplicateInputs.
+	ErrDuplicateInputs = errors.New("duplicate inputs")
+
+	// ErrMissingRawTx indicates a transaction hashed over its raw bytes
+	// for which no raw bytes were supplied.
+	ErrMissingRawTx = errors.New("missing raw transaction data")
+
+	// ErrCountTooLarge indicates an input or output count exceeding
+	// math.MaxUint32, which can't be committed to by the layered txid.
+	ErrCountTooLarge = errors.New("count too large")
+
+	// ErrEmptyTx indicates a transaction with neither inputs nor
+	// outputs.
+	ErrEmptyTx = errors.New("empty transaction")
+
+	// ErrBadTxIDLen indicates a txid which is not 32 bytes, such as one
+	// produced by a misbehaving TxIDStrategy or the nil returned by
+	// CalculateTxID when no txid could be computed.
+	ErrBadTxIDLen = errors.New("txid is not 32 bytes")
+)
+
+// TxHashError describes a transaction which was rejected by one of the
+// checked functions of this package, such as CalculateTxIDErr and
+// ConvertWireMsgTxToCommonTransactionWithOptions.  Err identifies the
+// condition, while Index identifies the offending input or output, or is -1
+// when the condition does not concern a single entry.  Whether Index refers
+// to an input or an output follows from Err and is also given in Reason.
+//
+// Errors returned for transactions which can't be hashed, such as by
+// CalculateTxIDErr, CalculateTxIDInto, and the batch functions, additionally
+// wrap ErrInvalidTxForHashing, so existing tests for it with errors.Is
+// continue to work.  Use errors.As to obtain the TxHashError itself.  The
+// batch functions prefix the error with the index of the offending
+// transaction.
+type TxHashError struct {
+	Op     string // Function which rejected the transaction
+	Reason string // Human readable description of the issue
+	Index  int    // Index of the offending input or output, or -1
+	Err    error  // Sentinel error describing the condition
+}
+
+// Error satisfies the error interface and prints human-readable errors.
+func (e *TxHashError) Error() string {
+	msg := e.Reason
+	if e.Err != nil {
+		msg = e.Err.Error() + ": " + msg
+	}
+	if e.Op != "" {
+		msg = e.Op + ": " + msg
+	}
+	return msg
+}
+
+// Unwrap returns the sentinel error describing the condition so it can be
+// tested for with errors.Is.
+func (e *TxHashError) Unwrap() error {
+	return e.Err
+}
+
+// txHashError creates a TxHashError for the given function, entry index, and
+// sentinel error, with a reason formatted according to the format specifier.
+// The sentinel must not be nil so every condition can be tested for with
+// errors.Is.
+func txHashError(op string, index int, err error, format string,
+	a ...interface{}) *TxHashError {
+
+	return &TxHashError{
+		Op:     op,
+		Reason: fmt.Sprintf(format, a...),
+		Index:  index,
+		Err:    err,
+	}
+}
+
+// invalidTxError returns a TxHashError for the given function whose Err wraps
+// both ErrInvalidTxForHashing and the sentinel error.
+func invalidTxError(op string, index int, err error, format string,
+	a ...interface{}) *TxHashError {
+
+	wrapped := fmt.Errorf("%w: %w", ErrInvalidTxForHashing, err)
+	return txHashError(op, index, wrapped, format, a...)
+}
+
+// invalidForHashing returns the invalidTxError for CalculateTxIDErr.
+func invalidForHashing(index int, err error, format string,
+	a ...interface{}) *TxHashError {
+
+	return invalidTxError("CalculateTxIDErr", index, err, format, a...)
+}
@@ -0,0 +1,219 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestTxHashError ensures the checked hashing and conversion functions return
+// a TxHashError identifying the function, sentinel, and offending entry, and
+// that the errors previously returned can still be tested for.
+func TestTxHashError(t *testing.T) {
+	t.Parallel()
+
+	calcTxIDErr := func(tx *Transaction) error {
+		_, err := CalculateTxIDErr(nil, tx)
+		return err
+	}
+	convertMsgTx := func(msgTx *MsgTx) error {
+		_, err := ConvertWireMsgTxToCommonTransactionWithOptions(
+			msgTx, &ConvertOptions{RejectV10Witness: true},
+		)
+		return err
+	}
+	convertTx := func(tx *Transaction) error {
+		_, err := ConvertCommonTransactionToWireMsgTx(tx)
+		return err
+	}
+
+	tests := []struct {
+		name      string
+		err       error
+		op        string
+		sentinel  error
+		index     int
+		forHashes bool
+	}{{
+		name:      "nil transaction",
+		err:       calcTxIDErr(nil),
+		op:        "CalculateTxIDErr",
+		sentinel:  ErrNilTx,
+		index:     -1,
+		forHashes: true,
+	}, {
+		name: "nil output",
+		err: calcTxIDErr(func() *Transaction {
+			tx := largeV10TestTx(2, 2)
+			tx.TxOut[1] = nil
+			return tx
+		}()),
+		op:        "CalculateTxIDErr",
+		sentinel:  ErrNilTx,
+		index:     1,
+		forHashes: true,
+	}, {
+		name: "short hash",
+		err: calcTxIDErr(func() *Transaction {
+			tx := largeV10TestTx(2, 2)
+			tx.TxIn[1].Hash = tx.TxIn[1].Hash[:31]
+			return tx
+		}()),
+		op:        "CalculateTxIDErr",
+		sentinel:  ErrBadHashLen,
+		index:     1,
+		forHashes: true,
+	}, {
+		name: "count mismatch",
+		err: calcTxIDErr(func() *Transaction {
+			tx := largeV10TestTx(2, 2)
+			tx.TxOutCount++
+			return tx
+		}()),
+		op:        "CalculateTxIDErr",
+		sentinel:  ErrCountMismatch,
+		index:     -1,
+		forHashes: true,
+	}, {
+		name: "negative value",
+		err: convertMsgTx(func() *MsgTx {
+			msgTx := multiTx.Copy()
+			msgTx.TxOut[1].Value = -1
+			return msgTx
+		}()),
+		op:       "ConvertWireMsgTxToCommonTransactionWithOptions",
+		sentinel: ErrNegativeValue,
+		index:    1,
+	}, {
+		name: "value too large",
+		err: convertMsgTx(func() *MsgTx {
+			msgTx := multiTx.Copy()
+			msgTx.TxOut[0].Value = int64(MaxTxOutputValue) + 1
+			return msgTx
+		}()),
+		op:       "ConvertWireMsgTxToCommonTransactionWithOptions",
+		sentinel: ErrValueTooLarge,
+		index:    0,
+	}, {
+		name:     "witness on v10",
+		err:      convertMsgTx(v10TestMsgTx(t, true)),
+		op:       "ConvertWireMsgTxToCommonTransactionWithOptions",
+		sentinel: ErrWitnessOnV10,
+		index:    0,
+	}, {
+		name: "script too large",
+		err: convertMsgTx(func() *MsgTx {
+			msgTx := multiTx.Copy()
+			msgTx.TxOut[0].PkScript = make(
+				[]byte, DefaultMaxPkScriptSize+1,
+			)
+			return msgTx
+		}()),
+		op:       "ConvertWireMsgTxToCommonTransactionWithOptions",
+		sentinel: ErrScriptTooLarge,
+		index:    0,
+	}, {
+		name: "wire conversion short hash",
+		err: convertTx(func() *Transaction {
+			tx := largeV10TestTx(2, 2)
+			tx.TxIn[0].Hash = nil
+			return tx
+		}()),
+		op:       "ConvertCommonTransactionToWireMsgTx",
+		sentinel: ErrBadHashLen,
+		index:    0,
+	}, {
+		name:      "into nil transaction",
+		err:       CalculateTxIDInto(make([]byte, 32), nil, nil),
+		op:        "CalculateTxIDInto",
+		sentinel:  ErrNilTx,
+		index:     -1,
+		forHashes: true,
+	}, {
+		name: "into short destination",
+		err: CalculateTxIDInto(make([]byte, 31), nil,
+			largeV10TestTx(1, 1)),
+		op:       "CalculateTxIDInto",
+		sentinel: io.ErrShortBuffer,
+		index:    -1,
+	}, {
+		name: "typed nil transaction",
+		err: func() error {
+			_, err := CalculateTxIDTyped(nil, nil)
+			return err
+		}(),
+		op:        "CalculateTxIDTyped",
+		sentinel:  ErrNilTx,
+		index:     -1,
+		forHashes: true,
+	}, {
+		name: "typed without txid",
+		err: func() error {
+			tx := largeV10TestTx(1, 1)
+			tx.TxIn[0].Hash = nil
+			_, err := CalculateTxIDTyped(nil, tx)
+			return err
+		}(),
+		op:        "CalculateTxIDTyped",
+		sentinel:  ErrBadTxIDLen,
+		index:     -1,
+		forHashes: true,
+	}, {
+		name: "index nil transaction",
+		err: func() error {
+			txs := []*Transaction{largeV10TestTx(1, 1), nil}
+			_, err := BuildTxIDIndex(txs)
+			return err
+		}(),
+		op:        "BuildTxIDIndex",
+		sentinel:  ErrNilTx,
+		index:     -1,
+		forHashes: true,
+	}, {
+		name: "dedup nil transaction",
+		err: func() error {
+			_, err := DedupTransactions([]*Transaction{nil})
+			return err
+		}(),
+		op:        "DedupTransactions",
+		sentinel:  ErrNilTx,
+		index:     -1,
+		forHashes: true,
+	}, {
+		name:      "framed nil transaction",
+		err:       WriteFramed(io.Discard, nil),
+		op:        "WriteFramed",
+		sentinel:  ErrNilTx,
+		index:     -1,
+		forHashes: true,
+	}}
+
+	for _, test := range tests {
+		var txErr *TxHashError
+		require.ErrorAs(t, test.err, &txErr, test.name)
+		require.Equal(t, test.op, txErr.Op, test.name)
+		require.Equal(t, test.index, txErr.Index, test.name)
+		require.ErrorIs(t, test.err, test.sentinel, test.name)
+		require.Equal(t, test.forHashes,
+			errors.Is(test.err, ErrInvalidTxForHashing), test.name)
+		require.Contains(t, test.err.Error(), txErr.Reason, test.name)
+	}
+
+	err := &TxHashError{
+		Op:     "Op",
+		Reason: "input 2 is nil",
+		Index:  2,
+		Err:    ErrNilTx,
+	}
+	require.Equal(t, "Op: nil transaction or entry: input 2 is nil",
+		err.Error())
+	require.Equal(t, "input 2 is nil", (&TxHashError{
+		Reason: "input 2 is nil",
+	}).Error())
+}
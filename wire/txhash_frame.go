@@ -0,0 +1,163 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// DefaultMaxFrameSize is the default maximum size of the serialized
+// transaction carried by a frame.  No valid transaction can be larger than a
+// block.
+const DefaultMaxFrameSize = MaxBlockPayload
+
+// FrameOptions configures the framing used by WriteFramedWithOptions and
+// ReadFramedWithOptions.  The zero value uses the defaults.  Both sides of a
+// connection must use the same options.
+type FrameOptions struct {
+	// Magic, when not zero, is written as a single byte before each frame
+	// and required to be present when reading one.  It allows the reader
+	// to detect a peer using a different protocol or protocol version.
+	Magic byte
+
+	// MaxFrameSize is the maximum size of the serialized transaction
+	// carried by a frame.  Zero means DefaultMaxFrameSize.
+	MaxFrameSize uint32
+}
+
+// maxFrameSize returns the maximum frame size to use for the options, which
+// may be nil.
+func (opts *FrameOptions) maxFrameSize() uint32 {
+	if opts == nil || opts.MaxFrameSize == 0 {
+		return DefaultMaxFrameSize
+	}
+	return opts.MaxFrameSize
+}
+
+// magic returns the magic byte to use for the options, which may be nil.
+func (opts *FrameOptions) magic() byte {
+	if opts == nil {
+		return 0
+	}
+	return opts.Magic
+}
+
+// WriteFramed writes the transaction to w as a single frame using the default
+// FrameOptions.  See WriteFramedWithOptions.
+func WriteFramed(w io.Writer, tx *Transaction) error {
+	return WriteFramedWithOptions(w, tx, nil)
+}
+
+// WriteFramedWithOptions writes the transaction to w as a single frame which
+// can be read back with ReadFramedWithOptions.  A frame consists of the
+// optional magic byte, the length of the serialized transaction as a 4-byte
+// big-endian value, and the serialized transaction itself.  The witness
+// serialization is used when any input has witness data so it isn't lost.
+//
+// An error is returned if the transaction can't be serialized or if it is
+// larger than the maximum frame size, in which case nothing is written.  A
+// nil transaction is reported with an error wrapping both
+// ErrInvalidTxForHashing and ErrNilTx.
+func WriteFramedWithOptions(w io.Writer, tx *Transaction,
+	opts *FrameOptions) error {
+
+	const op = "WriteFramed"
+	if tx == nil {
+		return invalidTxError(op, -1, ErrNilTx, "nil transaction")
+	}
+
+	var payload bytes.Buffer
+	if err := tx.serialize(&payload, op, true); err != nil {
+		return err
+	}
+	if maxSize := opts.maxFrameSize(); payload.Len() > int(maxSize) {
+		str := fmt.Sprintf("serialized transaction is %d bytes which "+
+			"is larger than the max frame size of %d",
+			payload.Len(), maxSize)
+		return messageError(op, str)
+	}
+
+	var header [5]byte
+	prefix := header[1:]
+	if magic := opts.magic(); magic != 0 {
+		header[0] = magic
+		prefix = header[:]
+	}
+	binary.BigEndian.PutUint32(header[1:], uint32(payload.Len()))
+
+	if _, err := w.Write(prefix); err != nil {
+		return err
+	}
+	_, err := w.Write(payload.Bytes())
+	return err
+}
+
+// ReadFramed reads a single frame written by WriteFramed from r using the
+// default FrameOptions.  See ReadFramedWithOptions.
+func ReadFramed(r io.Reader) (*Transaction, error) {
+	return ReadFramedWithOptions(r, nil)
+}
+
+// ReadFramedWithOptions reads a single frame written by
+// WriteFramedWithOptions from r and returns the transaction it carries
+// converted with ConvertWireMsgTxToCommonTransactionErr.
+//
+// The length is checked against the maximum frame size before the
+// transaction is read, so an oversized frame can't cause a large allocation.
+// An error is returned if the magic byte doesn't match, if the frame is too
+// large, or if the frame does not contain exactly one valid transaction.
+// io.EOF is returned if r is at EOF before the frame starts, while
+// io.ErrUnexpectedEOF is returned if it ends part way through.
+func ReadFramedWithOptions(r io.Reader, opts *FrameOptions) (*Transaction,
+	error) {
+
+	const op = "ReadFramed"
+
+	var header [5]byte
+	prefix := header[1:]
+	magic := opts.magic()
+	if magic != 0 {
+		prefix = header[:]
+	}
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, err
+	}
+	if magic != 0 && header[0] != magic {
+		str := fmt.Sprintf("frame has magic byte %#02x, want %#02x",
+			header[0], magic)
+		return nil, messageError(op, str)
+	}
+
+	size := binary.BigEndian.Uint32(header[1:])
+	if maxSize := opts.maxFrameSize(); size > maxSize {
+		str := fmt.Sprintf("frame is %d bytes which is larger than "+
+			"the max frame size of %d", size, maxSize)
+		return nil, messageError(op, str)
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	var msgTx MsgTx
+	pr := bytes.NewReader(payload)
+	if err := msgTx.Deserialize(pr); err != nil {
+		return nil, err
+	}
+	if pr.Len() != 0 {
+		str := fmt.Sprintf("%d bytes of trailing data after "+
+			"transaction in frame", pr.Len())
+		return nil, messageError(op, str)
+	}
+
+	return ConvertWireMsgTxToCommonTransactionErr(&msgTx)
+}
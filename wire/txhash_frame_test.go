@@ -0,0 +1,113 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestFramedRoundTrip ensures transactions survive being written to and read
+// from a stream of frames, with and without a magic byte.
+func TestFramedRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	txs := []*Transaction{
+		ConvertWireMsgTxToCommonTransaction(multiTx),
+		ConvertWireMsgTxToCommonTransaction(multiWitnessTx),
+		v10TestTx(),
+	}
+
+	for _, opts := range []*FrameOptions{nil, {Magic: 0xb7}} {
+		var buf bytes.Buffer
+		for _, tx := range txs {
+			err := WriteFramedWithOptions(&buf, tx, opts)
+			require.NoError(t, err)
+		}
+
+		for _, want := range txs {
+			got, err := ReadFramedWithOptions(&buf, opts)
+			require.NoError(t, err)
+			require.True(t, want.Equal(got))
+		}
+
+		_, err := ReadFramedWithOptions(&buf, opts)
+		require.ErrorIs(t, err, io.EOF)
+	}
+
+	// The default framing is a 4-byte big-endian length followed by the
+	// serialized transaction.
+	var buf bytes.Buffer
+	require.NoError(t, WriteFramed(&buf, txs[0]))
+	raw := mustBytes(t, txs[0])
+	require.Equal(t, uint32(len(raw)), binary.BigEndian.Uint32(buf.Bytes()))
+	require.Equal(t, raw, buf.Bytes()[4:])
+
+	got, err := ReadFramed(&buf)
+	require.NoError(t, err)
+	require.True(t, txs[0].Equal(got))
+}
+
+// TestFramedErrors ensures malformed and oversized frames and nil
+// transactions are rejected.
+func TestFramedErrors(t *testing.T) {
+	t.Parallel()
+
+	tx := v10TestTx()
+	raw := mustBytes(t, tx)
+	small := &FrameOptions{MaxFrameSize: uint32(len(raw) - 1)}
+
+	// Oversized transactions are not written at all.
+	var buf bytes.Buffer
+	require.Error(t, WriteFramedWithOptions(&buf, tx, small))
+	require.Zero(t, buf.Len())
+
+	frame := func(payload []byte) *bytes.Reader {
+		b := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+		return bytes.NewReader(append(b, payload...))
+	}
+
+	_, err := ReadFramedWithOptions(frame(raw), small)
+	require.Error(t, err)
+
+	// A huge length must be rejected before allocating for it.
+	_, err = ReadFramed(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff}))
+	require.Error(t, err)
+
+	_, err = ReadFramed(frame(append(bytes.Clone(raw), 0x00)))
+	require.ErrorContains(t, err, "trailing data")
+
+	_, err = ReadFramed(frame(raw[:len(raw)-1]))
+	require.Error(t, err)
+
+	truncated := frame(raw)
+	_, err = ReadFramed(io.LimitReader(truncated, int64(len(raw))))
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	_, err = ReadFramed(bytes.NewReader([]byte{0x00, 0x00}))
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	magic := &FrameOptions{Magic: 0xb7}
+	buf.Reset()
+	require.NoError(t, WriteFramedWithOptions(&buf, tx, magic))
+	_, err = ReadFramedWithOptions(&buf, &FrameOptions{Magic: 0xb8})
+	require.ErrorContains(t, err, "magic")
+
+	tx.TxInCount++
+	require.Error(t, WriteFramed(&buf, tx))
+
+	// A nil transaction is rejected rather than causing a panic.
+	buf.Reset()
+	err = WriteFramed(&buf, nil)
+	require.ErrorIs(t, err, ErrInvalidTxForHashing)
+	require.ErrorIs(t, err, ErrNilTx)
+	require.ErrorIs(t, WriteFramedWithOptions(&buf, nil, magic),
+		ErrInvalidTxForHashing)
+	require.Zero(t, buf.Len())
+}
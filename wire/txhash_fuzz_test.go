@@ -0,0 +1,63 @@
+//go:build gofuzz || go1.18
+
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// FuzzConvertAndHash ensures any transaction which deserializes can be
+// converted and hashed without panicking, always producing a 32 byte txid
+// which agrees with the other txid entry points.
+func FuzzConvertAndHash(f *testing.F) {
+	for _, msgTx := range []*MsgTx{multiTx, multiWitnessTx} {
+		for _, version := range []int32{1, 2, 10} {
+			seed := msgTx.Copy()
+			seed.Version = version
+
+			var buf bytes.Buffer
+			if err := seed.Serialize(&buf); err != nil {
+				f.Fatal(err)
+			}
+			f.Add(buf.Bytes())
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, input []byte) {
+		var msgTx MsgTx
+		r := bytes.NewReader(input)
+		if err := msgTx.Deserialize(r); err != nil {
+			return
+		}
+		raw := input[:len(input)-r.Len()]
+
+		tx := ConvertWireMsgTxToCommonTransaction(&msgTx)
+		txid := CalculateTxID(raw, tx)
+		if len(txid) != chainhash.HashSize {
+			t.Fatalf("txid has %d bytes", len(txid))
+		}
+
+		// The txid excludes any witness data, so every other way of
+		// computing it must agree with the hash of the wire
+		// transaction.
+		want := msgTx.TxHash()
+		if got := tx.TxID(); !bytes.Equal(got, want[:]) {
+			t.Fatalf("TxID: got %x, want %x", got, want)
+		}
+		got, err := CalculateTxIDFromReader(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatalf("CalculateTxIDFromReader: %v", err)
+		}
+		if !bytes.Equal(got, want[:]) {
+			t.Fatalf("CalculateTxIDFromReader: got %x, want %x",
+				got, want)
+		}
+	})
+}
@@ -0,0 +1,267 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"sync"
+)
+
+// TxIDHasher incrementally computes the layered version 10 txid described by
+// CalculateV10TxID.  Each of the three serializations is streamed directly
+// into its own sha256 state as inputs and outputs are added, so the memory
+// required is constant regardless of the size of the transaction.
+//
+// Inputs and outputs must be added in transaction order.  A TxIDHasher is not
+// safe for concurrent use.
+type TxIDHasher struct {
+	inputs  hash.Hash
+	scripts hash.Hash
+	outputs hash.Hash
+
+	numIn  uint32
+	numOut uint32
+
+	// sigScriptMemo avoids rehashing runs of identical signature scripts.
+	sigScriptMemo scriptHashMemo
+
+	// scratch is used to encode the fixed width fields and script hashes
+	// without allocating for every input and output.
+	scratch [sha256.Size + 8]byte
+
+	// preimage is used to build the final preimage in Sum, since the
+	// data hashed by the sha256 backend escapes to the heap.
+	preimage [16 + 3*sha256.Size]byte
+
+	// backend is the sha256 backend set by SetSha256Backend when the
+	// hasher was created, which determines whether a pooled hasher can
+	// be reused.
+	backend *Sha256Backend
+}
+
+// txIDHasherPool holds hashers for reuse by the functions which compute
+// layered txids without being given a hasher, such as CalculateV10TxID, so
+// computing many txids, including on many goroutines at once, doesn't
+// allocate new sha256 states for every transaction.
+var txIDHasherPool = sync.Pool{
+	New: func() any {
+		return NewTxIDHasher()
+	},
+}
+
+// getTxIDHasher returns a reset hasher from the pool.  A pooled hasher created
+// before the sha256 backend was last changed is discarded in favor of a new
+// one, so the current backend is always used.  The hasher should be returned
+// with putTxIDHasher once it is no longer used.
+func getTxIDHasher() *TxIDHasher {
+	h := txIDHasherPool.Get().(*TxIDHasher)
+	if h.backend != sha256Backend.Load() {
+		return NewTxIDHasher()
+	}
+	h.Reset()
+
+	return h
+}
+
+// putTxIDHasher returns a hasher obtained from getTxIDHasher to the pool.
+func putTxIDHasher(h *TxIDHasher) {
+	txIDHasherPool.Put(h)
+}
+
+// TxIDAccumulator is an alternative name for TxIDHasher for callers building
+// a version 10 transaction one input and output at a time, such as a large
+// coinbase, which can append each one with AppendInput and AppendOutput as it
+// is constructed and Finalize the txid without ever holding the whole
+// transaction in memory.
+type TxIDAccumulator = TxIDHasher
+
+// NewTxIDHasher returns a new TxIDHasher ready to have the inputs and outputs
+// of a transaction added.
+func NewTxIDHasher() *TxIDHasher {
+	return &TxIDHasher{
+		inputs:  newSha256(),
+		scripts: newSha256(),
+		outputs: newSha256(),
+		backend: sha256Backend.Load(),
+	}
+}
+
+// Reset clears all inputs and outputs added so far so the hasher can be
+// reused for another transaction.
+func (h *TxIDHasher) Reset() {
+	h.inputs.Reset()
+	h.scripts.Reset()
+	h.outputs.Reset()
+	h.numIn = 0
+	h.numOut = 0
+	h.sigScriptMemo.valid = false
+}
+
+// AddInput adds the next transaction input to the inputs and scripts layers.
+func (h *TxIDHasher) AddInput(in *TxInput) {
+	h.AppendInput(in.Hash, in.Index, in.Sequence, in.SignatureScript)
+}
+
+// AppendInput adds the next transaction input given its previous outpoint
+// hash, in internal byte order, index, sequence, and signature script.  It is
+// equivalent to AddInput without requiring a TxInput.  The hash and script are
+// not retained.  The hash is written as is, so the caller must ensure it is 32
+// bytes, as CalculateV10TxID does.
+func (h *TxIDHasher) AppendInput(hash []byte, index, sequence uint32,
+	sigScript []byte) {
+
+	scriptHash := h.sigScriptMemo.sum(sigScript)
+	h.addInput(hash, index, sequence, scriptHash)
+}
+
+// addInput adds the next transaction input given its individual fields and the
+// already computed sha256 of its signature script.
+func (h *TxIDHasher) addInput(prevHash []byte, index, sequence uint32,
+	scriptHash []byte) {
+
+	h.inputs.Write(prevHash)
+	binary.LittleEndian.PutUint32(h.scratch[0:4], index)
+	binary.LittleEndian.PutUint32(h.scratch[4:8], sequence)
+	h.inputs.Write(h.scratch[:8])
+
+	// The script hash is copied into the scratch buffer first so the
+	// caller's array does not escape to the heap via the interface call.
+	copy(h.scratch[:], scriptHash)
+	h.scripts.Write(h.scratch[:sha256.Size])
+
+	h.numIn++
+}
+
+// AddOutput adds the next transaction output to the outputs layer.
+func (h *TxIDHasher) AddOutput(out *TxOutput) {
+	h.AppendOutput(out.Value, out.PkScript.Pkscript)
+}
+
+// AppendOutput adds the next transaction output given its value and public
+// key script.  It is equivalent to AddOutput without requiring a TxOutput.
+// The script is not retained.
+func (h *TxIDHasher) AppendOutput(value uint64, pkScript []byte) {
+	// The script hash is computed directly into its position in the
+	// scratch buffer, so copying it there again is a no-op.
+	h.addOutput(value, sha256Into(h.scratch[8:], pkScript))
+}
+
+// addOutput adds the next transaction output given its value and the already
+// computed sha256 of its public key script.
+func (h *TxIDHasher) addOutput(value uint64, scriptHash []byte) {
+	binary.LittleEndian.PutUint64(h.scratch[0:8], value)
+	copy(h.scratch[8:], scriptHash)
+	h.outputs.Write(h.scratch[:])
+
+	h.numOut++
+}
+
+// Sum finalizes the layered txid for the inputs and outputs added so far
+// using the provided version and locktime.  The result is in internal byte
+// order.  Sum does not change the underlying state, so more inputs and outputs
+// may be added and Sum called again.
+func (h *TxIDHasher) Sum(version, locktime uint32) []byte {
+	inputsHash, scriptsHash, outputsHash := h.layers()
+	putV10Preimage(h.preimage[:], version, locktime, h.numIn, h.numOut,
+		&inputsHash, &scriptsHash, &outputsHash)
+
+	return doubleSha256(h.preimage[:])
+}
+
+// v10OutputEntrySize is the size of the entry of a single output in the
+// outputs layer of the layered txid, which is its value followed by the
+// sha256 of its public key script.
+const v10OutputEntrySize = 8 + sha256.Size
+
+// putV10OutputEntry writes the entry of the output with the given value and
+// public key script in the outputs layer of the layered txid into the first
+// v10OutputEntrySize bytes of entry.
+func putV10OutputEntry(entry []byte, value uint64, pkScript []byte) {
+	binary.LittleEndian.PutUint64(entry[:8], value)
+	sha256Into(entry[8:], pkScript)
+}
+
+// putV10Preimage writes the 112 byte preimage of the layered txid described
+// by CalculateV10TxID into the provided buffer, which must be at least that
+// long.
+func putV10Preimage(preimage []byte, version, locktime, numIn, numOut uint32,
+	inputsHash, scriptsHash, outputsHash *[sha256.Size]byte) {
+
+	binary.LittleEndian.PutUint32(preimage[0:4], version)
+	binary.LittleEndian.PutUint32(preimage[4:8], locktime)
+	binary.LittleEndian.PutUint32(preimage[8:12], numIn)
+	binary.LittleEndian.PutUint32(preimage[12:16], numOut)
+	copy(preimage[16:], inputsHash[:])
+	copy(preimage[16+sha256.Size:], scriptsHash[:])
+	copy(preimage[16+2*sha256.Size:], outputsHash[:])
+}
+
+// Finalize returns the layered txid for the inputs and outputs appended so
+// far using the provided version and locktime.  It is identical to Sum and,
+// likewise, does not prevent further inputs and outputs from being appended.
+func (h *TxIDHasher) Finalize(version, locktime uint32) []byte {
+	return h.Sum(version, locktime)
+}
+
+// layers returns the sha256 of each of the three layers for the inputs and
+// outputs added so far without changing the underlying state.
+func (h *TxIDHasher) layers() (inputsHash, scriptsHash,
+	outputsHash [sha256.Size]byte) {
+
+	h.inputs.Sum(inputsHash[:0])
+	h.scripts.Sum(scriptsHash[:0])
+	h.outputs.Sum(outputsHash[:0])
+
+	return inputsHash, scriptsHash, outputsHash
+}
+
+// scriptHashMemo remembers the sha256 of the most recently hashed script so
+// that runs of identical adjacent scripts, such as the inputs of a
+// consolidation transaction spending many outputs to the same address, are
+// only hashed once.
+type scriptHashMemo struct {
+	// script is a copy of the last script hashed, rather than a reference
+	// to it, so the memo can't be invalidated by the caller modifying the
+	// script after it was added.
+	script []byte
+	hash   [sha256.Size]byte
+	valid  bool
+}
+
+// sum returns the sha256 of the script, reusing the previous hash when the
+// script is the same as the last one.  The returned slice is only valid until
+// the next call.
+func (m *scriptHashMemo) sum(script []byte) []byte {
+	if m.valid && m.matches(script) {
+		return m.hash[:]
+	}
+
+	sha256Into(m.hash[:], script)
+	m.script = append(m.script[:0], script...)
+	m.valid = true
+
+	return m.hash[:]
+}
+
+// matches returns whether the script is the same as the last one hashed.  The
+// length and the first and last bytes are checked before comparing the full
+// script so that differing scripts are usually rejected cheaply.
+func (m *scriptHashMemo) matches(script []byte) bool {
+	last := m.script
+	if len(script) != len(last) {
+		return false
+	}
+	if len(script) == 0 {
+		return true
+	}
+	if script[0] != last[0] || script[len(script)-1] != last[len(last)-1] {
+		return false
+	}
+
+	return bytes.Equal(script, last)
+}
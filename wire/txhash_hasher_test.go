@@ -0,0 +1,246 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// largeV10TestTx returns a version 10 transaction with the given number of
+// inputs and outputs, each with distinct contents.
+func largeV10TestTx(numIn, numOut int) *Transaction {
+	tx := &Transaction{
+		Version:    10,
+		LockTime:   500000,
+		TxIn:       make([]*TxInput, numIn),
+		TxOut:      make([]*TxOutput, numOut),
+		TxInCount:  uint(numIn),
+		TxOutCount: uint(numOut),
+	}
+	for i := range tx.TxIn {
+		tx.TxIn[i] = &TxInput{
+			Hash:            bytes.Repeat([]byte{byte(i)}, 32),
+			Index:           uint32(i),
+			SignatureScript: bytes.Repeat([]byte{byte(i)}, i%100),
+			Sequence:        uint32(i) * 7,
+		}
+	}
+	for i := range tx.TxOut {
+		tx.TxOut[i] = &TxOutput{
+			Value: uint64(i) * 1000,
+			PkScript: PkScript{
+				Pkscript: bytes.Repeat([]byte{0xac}, i%40),
+			},
+		}
+	}
+
+	return tx
+}
+
+// TestTxIDHasher ensures the streaming hasher is bit-identical to the
+// materialized layered construction, including when Sum is called part way
+// through adding inputs and outputs.
+func TestTxIDHasher(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		numIn  int
+		numOut int
+	}{
+		{name: "empty", numIn: 0, numOut: 0},
+		{name: "inputs only", numIn: 3, numOut: 0},
+		{name: "outputs only", numIn: 0, numOut: 3},
+		{name: "1-in-2-out", numIn: 1, numOut: 2},
+		{name: "large", numIn: 2000, numOut: 500},
+	}
+
+	for _, test := range tests {
+		tx := largeV10TestTx(test.numIn, test.numOut)
+
+		h := NewTxIDHasher()
+		for _, in := range tx.TxIn {
+			h.AddInput(in)
+		}
+
+		// Summing must not disturb the running state.
+		partial := &Transaction{
+			Version:  tx.Version,
+			LockTime: tx.LockTime,
+			TxIn:     tx.TxIn,
+		}
+		require.Equal(t, refV10TxID(partial),
+			h.Sum(tx.Version, tx.LockTime), test.name)
+
+		for _, out := range tx.TxOut {
+			h.AddOutput(out)
+		}
+		require.Equal(t, refV10TxID(tx), h.Sum(tx.Version, tx.LockTime),
+			test.name)
+		require.Equal(t, refV10TxID(tx), CalculateV10TxID(tx),
+			test.name)
+	}
+}
+
+// TestTxIDAccumulator ensures appending the individual fields of each input
+// and output produces the same txid as hashing the finished transaction.
+func TestTxIDAccumulator(t *testing.T) {
+	t.Parallel()
+
+	tx := largeV10TestTx(300, 20)
+
+	var acc *TxIDAccumulator = NewTxIDHasher()
+	for _, in := range tx.TxIn {
+		acc.AppendInput(
+			in.Hash, in.Index, in.Sequence, in.SignatureScript,
+		)
+	}
+	for _, out := range tx.TxOut {
+		acc.AppendOutput(out.Value, out.PkScript.Pkscript)
+	}
+
+	want := CalculateV10TxID(tx)
+	require.Equal(t, want, acc.Finalize(tx.Version, tx.LockTime))
+	require.Equal(t, want, acc.Sum(tx.Version, tx.LockTime))
+}
+
+// consolidationV10TestTx returns a version 10 transaction with the given
+// number of inputs which all share an identical signature script, as is
+// typical when consolidating many outputs paid to the same address.
+func consolidationV10TestTx(numIn int) *Transaction {
+	tx := largeV10TestTx(numIn, 1)
+
+	// A typical signature script consisting of a push of a DER signature
+	// followed by a push of a compressed public key.
+	sigScript := make([]byte, 107)
+	for i := range sigScript {
+		sigScript[i] = byte(i * 31)
+	}
+	for _, in := range tx.TxIn {
+		in.SignatureScript = bytes.Clone(sigScript)
+	}
+
+	return tx
+}
+
+// TestTxIDHasherScriptMemo ensures reusing the hash of identical adjacent
+// signature scripts does not change the txid, including when scripts only
+// differ in their interior bytes and when a memoized script is modified
+// between transactions.
+func TestTxIDHasherScriptMemo(t *testing.T) {
+	t.Parallel()
+
+	tx := consolidationV10TestTx(10)
+	require.Equal(t, refV10TxID(tx), CalculateV10TxID(tx))
+
+	// Alternate between scripts which share the same length and first and
+	// last bytes and only differ in the middle.
+	for i := 1; i < len(tx.TxIn); i += 2 {
+		tx.TxIn[i].SignatureScript[50] ^= 0xff
+	}
+	require.Equal(t, refV10TxID(tx), CalculateV10TxID(tx))
+
+	// Interleave empty scripts and scripts of different lengths.
+	tx.TxIn[2].SignatureScript = nil
+	tx.TxIn[3].SignatureScript = []byte{}
+	tx.TxIn[4].SignatureScript = tx.TxIn[4].SignatureScript[:10]
+	require.Equal(t, refV10TxID(tx), CalculateV10TxID(tx))
+
+	// Modifying the last script in place after it was hashed must not
+	// cause a reused hasher to return the stale hash.
+	h := NewTxIDHasher()
+	single := consolidationV10TestTx(1)
+	calcV10TxID(single, h)
+	single.TxIn[0].SignatureScript[50] ^= 0xff
+	require.Equal(t, refV10TxID(single), calcV10TxID(single, h))
+}
+
+// TestTxIDHasherPool ensures pooled hashers are reset between uses, produce
+// the same txids on many goroutines at once, and are not reused once the
+// sha256 backend changes.
+func TestTxIDHasherPool(t *testing.T) {
+	t.Cleanup(func() { SetSha256Backend(nil) })
+
+	// A hasher returned with inputs and outputs still added is reset.
+	h := getTxIDHasher()
+	h.AddInput(v10TestTx().TxIn[0])
+	putTxIDHasher(h)
+	tx := v10TestTx()
+	require.Equal(t, refV10TxID(tx), CalculateV10TxID(tx))
+
+	txs := v10TestBlock(50, 3, 2)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, tx := range txs {
+				if !bytes.Equal(refV10TxID(tx),
+					CalculateV10TxID(tx)) {
+
+					t.Error("wrong pooled txid")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Hashers pooled before the backend was set are not used.
+	putTxIDHasher(NewTxIDHasher())
+	backend := new(countingSha256Backend)
+	SetSha256Backend(backend)
+	h = getTxIDHasher()
+	require.Equal(t, sha256Backend.Load(), h.backend)
+	putTxIDHasher(h)
+
+	calls := backend.calls.Load()
+	require.Equal(t, refV10TxID(tx), CalculateV10TxID(tx))
+	require.Greater(t, backend.calls.Load(), calls)
+}
+
+// BenchmarkCalculateV10TxIDConsolidation benchmarks the layered txid of a
+// consolidation transaction with 1000 inputs sharing an identical signature
+// script, each of which only needs to be hashed once.
+func BenchmarkCalculateV10TxIDConsolidation(b *testing.B) {
+	tx := consolidationV10TestTx(1000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		CalculateV10TxID(tx)
+	}
+}
+
+// BenchmarkCalculateV10TxIDOutputs benchmarks the layered txid of a
+// transaction with 2000 outputs, each of whose script hashes is computed
+// directly into the hasher's scratch buffer, so the allocations are the same
+// as for a transaction with a single output.
+func BenchmarkCalculateV10TxIDOutputs(b *testing.B) {
+	tx := largeV10TestTx(1, 2000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		CalculateV10TxID(tx)
+	}
+}
+
+// BenchmarkCalculateV10TxIDLarge benchmarks the layered txid of a transaction
+// with 50,000 inputs, which previously required materializing each of the
+// three serializations in memory.
+func BenchmarkCalculateV10TxIDLarge(b *testing.B) {
+	tx := largeV10TestTx(50000, 2)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		CalculateV10TxID(tx)
+	}
+}
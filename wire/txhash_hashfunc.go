@@ -0,0 +1,105 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// HashFunc is a single round of a digest used in place of sha256 when
+// computing txids with CalculateTxIDWith.  Every place the standard scheme
+// hashes with sha256 it is used instead, and every place it hashes with
+// double sha256 it is applied twice.  It must not modify or retain the
+// provided data.
+type HashFunc func(data []byte) []byte
+
+// Sha256HashFunc is the default HashFunc.  Computing a txid with it produces
+// the same double sha256 based result as CalculateTxID.
+func Sha256HashFunc(data []byte) []byte {
+	hash := sum256(data)
+	return hash[:]
+}
+
+// CalculateTxIDWith computes the txid of the transaction in the same way as
+// CalculateTxID, but uses the provided hash function in place of sha256.  A
+// nil hash function uses Sha256HashFunc.
+//
+// Transactions hashed over their raw bytes have a txid of h(h(rawTxData)),
+// with any witness data stripped from the raw bytes first, and those using
+// the built-in layered strategy, such as version 10, keep the structure
+// described by CalculateV10TxID with each sha256 replaced by h.
+// Since the individual layer digests are concatenated into the final
+// preimage, h may produce digests of any length, although nil is returned
+// when a previous outpoint hash is not 32 bytes as with CalculateV10TxID.  Any
+// other registered TxIDStrategy defines its own hashing and is applied
+// unchanged.
+//
+// This is primarily intended for deterministic tests using a simpler hash and
+// for experimenting with chains which use a different digest.  As with
+// CalculateTxID, nil is returned for a nil transaction.
+func CalculateTxIDWith(rawTxData []byte, tx *Transaction, h HashFunc) []byte {
+	if isNilTx("CalculateTxIDWith", tx) {
+		return nil
+	}
+	if h == nil {
+		h = Sha256HashFunc
+	}
+
+	entry, ok := lookupTxIDStrategy(tx.Version)
+	switch {
+	case !ok:
+		raw, err := strippedTxData(rawTxData, tx)
+		if err != nil {
+			log.Warnf("Unable to compute txid: %v", err)
+			return nil
+		}
+		return h(h(raw))
+
+	case entry.layered:
+		return calcLayeredTxIDWith(tx, h)
+
+	default:
+		return entry.strategy(tx.InternalOrder())
+	}
+}
+
+// calcLayeredTxIDWith computes the layered txid described by CalculateV10TxID
+// using h in place of sha256.  Unlike TxIDHasher, the layers are buffered in
+// full since a HashFunc can't be fed incrementally.
+func calcLayeredTxIDWith(tx *Transaction, h HashFunc) []byte {
+	if err := checkV10Entries("CalculateTxIDWith", tx); err != nil {
+		log.Warnf("Unable to compute txid: %v", err)
+		return nil
+	}
+
+	tx = tx.InternalOrder()
+	var inputs, scripts, outputs []byte
+	for _, in := range tx.TxIn {
+		inputs = append(inputs, in.Hash...)
+		inputs = binary.LittleEndian.AppendUint32(inputs, in.Index)
+		inputs = binary.LittleEndian.AppendUint32(inputs, in.Sequence)
+		scripts = append(scripts, h(in.SignatureScript)...)
+	}
+	for _, out := range tx.TxOut {
+		outputs = binary.LittleEndian.AppendUint64(outputs, out.Value)
+		outputs = append(outputs, h(out.PkScript.Pkscript)...)
+	}
+
+	preimage := make([]byte, 0, 16+3*sha256.Size)
+	preimage = binary.LittleEndian.AppendUint32(preimage, tx.Version)
+	preimage = binary.LittleEndian.AppendUint32(preimage, tx.LockTime)
+	preimage = binary.LittleEndian.AppendUint32(
+		preimage, uint32(len(tx.TxIn)),
+	)
+	preimage = binary.LittleEndian.AppendUint32(
+		preimage, uint32(len(tx.TxOut)),
+	)
+	preimage = append(preimage, h(inputs)...)
+	preimage = append(preimage, h(scripts)...)
+	preimage = append(preimage, h(outputs)...)
+
+	return h(h(preimage))
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestCalculateTxIDWith ensures the default hash function reproduces
+// CalculateTxID, including for raw bytes in the witness serialization, and
+// that a custom one is used for every layer.
+func TestCalculateTxIDWith(t *testing.T) {
+	t.Parallel()
+
+	tx := v10TestTx()
+	raw := []byte{0x01, 0x00, 0x00, 0x00, 0xaa}
+	stdTx := v10TestTx()
+	stdTx.Version = 1
+
+	// The default hash function matches CalculateTxID.
+	for _, h := range []HashFunc{nil, Sha256HashFunc} {
+		require.Equal(t, CalculateTxID(nil, tx),
+			CalculateTxIDWith(nil, tx, h))
+		require.Equal(t, CalculateTxID(raw, stdTx),
+			CalculateTxIDWith(raw, stdTx, h))
+	}
+
+	// With an identity hash the txid is the layered preimage itself, with
+	// the scripts taking the place of their hashes, which exposes the
+	// structure described by CalculateV10TxID.
+	identity := func(data []byte) []byte {
+		return bytes.Clone(data)
+	}
+	want := bytes.Join([][]byte{
+		// Version, locktime, input count, and output count.
+		{0x0a, 0, 0, 0}, {0, 0, 0, 0}, {2, 0, 0, 0}, {1, 0, 0, 0},
+
+		// Inputs.
+		bytes.Repeat([]byte{0x11}, 32), {0, 0, 0, 0},
+		{0xff, 0xff, 0xff, 0xff},
+		bytes.Repeat([]byte{0x22}, 32), {1, 0, 0, 0},
+		{0xfe, 0xff, 0xff, 0xff},
+
+		// Scripts.
+		{0x51}, {0x52, 0x53},
+
+		// Outputs.
+		{0x00, 0xf2, 0x05, 0x2a, 0x01, 0, 0, 0}, {0x76, 0xa9},
+	}, nil)
+	require.Equal(t, want, CalculateTxIDWith(nil, tx, identity))
+	require.Equal(t, raw, CalculateTxIDWith(raw, stdTx, identity))
+
+	// The inner digest is applied twice at the top level.
+	var calls int
+	counting := func(data []byte) []byte {
+		calls++
+		return Sha256HashFunc(data)
+	}
+	CalculateTxIDWith(raw, stdTx, counting)
+	require.Equal(t, 2, calls)
+
+	// Witness data is stripped from the raw bytes as with CalculateTxID.
+	witnessRaw, err := hex.DecodeString(segnetWitnessTxHex)
+	require.NoError(t, err)
+	var msgTx MsgTx
+	require.NoError(t, msgTx.Deserialize(bytes.NewReader(witnessRaw)))
+	witnessTx := ConvertWireMsgTxToCommonTransaction(&msgTx)
+	wantTxID := msgTx.TxHash()
+	for _, h := range []HashFunc{nil, Sha256HashFunc} {
+		require.Equal(t, wantTxID[:],
+			CalculateTxIDWith(witnessRaw, witnessTx, h))
+	}
+	stripped, err := witnessTx.StandardSerialize()
+	require.NoError(t, err)
+	require.Equal(t, stripped,
+		CalculateTxIDWith(witnessRaw, witnessTx, identity))
+}
@@ -7,7 +7,52 @@ package wire
 import (
 	"bytes"
 	"crypto/sha256"
-	"encoding/binary"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// ErrInvalidTxForHashing is returned by CalculateTxIDErr when the supplied
+// transaction is malformed in a way that would make any txid computed over it
+// meaningless.  The returned errors are a *TxHashError which wraps this value
+// along with any more specific sentinel error and describes the offending
+// field, so callers should test for it with errors.Is.
+var ErrInvalidTxForHashing = errors.New("invalid transaction for hashing")
+
+// ErrBadPrevoutHashLength is returned when the previous outpoint hash of an
+// input is not exactly 32 bytes, which would otherwise shift every following
+// field of the data the txid is computed over.  It is wrapped together with
+// ErrInvalidTxForHashing by CalculateTxIDErr, so callers may test for either
+// with errors.Is.
+var ErrBadPrevoutHashLength = errors.New("bad previous outpoint hash length")
+
+// MaxTxOutputValue is the maximum value, in satoshi, that a single output or
+// the sum of all outputs of a transaction may have.  It is the same limit as
+// btcutil.MaxSatoshi, which can't be referenced here since btcutil depends on
+// this package.
+const MaxTxOutputValue uint64 = 21e6 * 1e8
+
+// HashOrder is the byte order in which the previous outpoint hashes of a
+// Transaction are held.
+type HashOrder uint8
+
+const (
+	// HashOrderInternal is the internal (little-endian) byte order used
+	// by the wire serialization and chainhash.Hash.  It is the zero value.
+	HashOrderInternal HashOrder = iota
+
+	// HashOrderDisplay is the display (big-endian) byte order shown by
+	// block explorers and RPC interfaces, which is the reverse of the
+	// internal byte order.
+	HashOrderDisplay
 )
 
 // Transaction represents a bitcoin transaction.
@@ -18,6 +63,27 @@ type Transaction struct {
 	TxOut      []*TxOutput
 	TxInCount  uint
 	TxOutCount uint
+
+	// HashOrder is the byte order of the Hash of every input.  The zero
+	// value is HashOrderInternal, as produced by
+	// ConvertWireMsgTxToCommonTransaction, while
+	// ConvertWireMsgTxToCommonTransactionBE produces HashOrderDisplay.
+	//
+	// The functions of this package which hash or serialize a
+	// Transaction, including CalculateTxID and the version 10 layered
+	// txid, consult it and always commit to the hashes in internal byte
+	// order, so both conversions of the same MsgTx have the same txid.
+	// Methods of TxInput, such as DisplayHash and OutPoint, can't see it
+	// and assume internal byte order, so they should be used on the
+	// inputs returned by InternalOrder.  A registered TxIDStrategy is
+	// always given a transaction in internal byte order.
+	HashOrder HashOrder
+
+	// txidCache holds the []byte txid computed by TxID until it is
+	// invalidated.  It is an atomic.Value rather than a slice guarded by
+	// a mutex so that a Transaction can be copied by value, in which case
+	// the copy starts out with the txid cached by the original.
+	txidCache atomic.Value
 }
 
 // TxInput represents a bitcoin transaction input.
@@ -26,6 +92,98 @@ type TxInput struct {
 	Index           uint32
 	SignatureScript []byte
 	Sequence        uint32
+
+	// Witness is the segregated witness stack of the input, if any.  It
+	// is not committed to by the txid.
+	Witness [][]byte
+}
+
+// DisplayHash returns the previous outpoint hash of the input as a hex string
+// in display (big-endian) byte order, which is the form shown by block
+// explorers and RPC interfaces.  The Hash field itself must be held in
+// internal (little-endian) byte order, which is the case unless the
+// transaction has a HashOrder of HashOrderDisplay.
+func (in *TxInput) DisplayHash() string {
+	return hex.EncodeToString(ReverseBytes(in.Hash))
+}
+
+// OutPointRef identifies the previous transaction output spent by an input by
+// the txid of the transaction which created it, in internal byte order, and
+// the index of the output within it.
+type OutPointRef struct {
+	Hash  []byte
+	Index uint32
+}
+
+// OutPoint returns the previous outpoint spent by the input.  The returned
+// Hash aliases that of the input.
+func (in *TxInput) OutPoint() OutPointRef {
+	return OutPointRef{Hash: in.Hash, Index: in.Index}
+}
+
+// String returns the outpoint in the form displayhash:index, where the hash is
+// in display (big-endian) byte order.  It is the same string returned by
+// OutPoint.String for the equivalent wire outpoint, so it may be used as a
+// map key.
+func (o OutPointRef) String() string {
+	return hex.EncodeToString(ReverseBytes(o.Hash)) + ":" +
+		strconv.FormatUint(uint64(o.Index), 10)
+}
+
+// Equal returns whether the two outpoints have the same hash and index.
+func (o OutPointRef) Equal(other OutPointRef) bool {
+	return o.Index == other.Index && bytes.Equal(o.Hash, other.Hash)
+}
+
+// ScriptHash returns the sha256 of the signature script of the input, which is
+// its contribution to the scripts layer of the version 10 txid described by
+// CalculateV10TxID.  The scripts layer is the sha256 of the concatenation of
+// these hashes for every input in order, so they may be precomputed and
+// compared input by input when debugging a mismatched txid.
+func (in *TxInput) ScriptHash() [32]byte {
+	return sum256(in.SignatureScript)
+}
+
+// ParseTxInputHash decodes a previous outpoint hash given as a hex string in
+// display (big-endian) byte order, such as one shown by a block explorer, into
+// the internal byte order expected by TxInput.Hash.  An error is returned
+// unless the string encodes exactly 32 bytes.
+func ParseTxInputHash(hexStr string) ([]byte, error) {
+	if len(hexStr) != chainhash.MaxHashStringSize {
+		return nil, fmt.Errorf("hash string has %d characters, want %d",
+			len(hexStr), chainhash.MaxHashStringSize)
+	}
+
+	hash, err := hex.DecodeString(hexStr)
+	if err != nil {
+		return nil, err
+	}
+	ReverseBytesInPlace(hash)
+
+	return hash, nil
+}
+
+// TxIDString returns the txid, which must be in the internal byte order
+// returned by CalculateTxID, as a hex string in display (big-endian) byte
+// order, which is the form shown by block explorers and RPC interfaces.  It
+// is the same string returned by chainhash.Hash.String for the txid.
+//
+// It panics if the txid is not exactly 32 bytes since that indicates a
+// programming error rather than a transaction which can't be formatted.
+func TxIDString(id []byte) string {
+	if len(id) != chainhash.HashSize {
+		panic(fmt.Sprintf("txid has %d bytes, want %d", len(id),
+			chainhash.HashSize))
+	}
+	return hex.EncodeToString(ReverseBytes(id))
+}
+
+// TxIDFromString is the inverse of TxIDString.  It decodes a txid given as a
+// hex string in display (big-endian) byte order into the internal byte order
+// returned by CalculateTxID.  An error is returned unless the string encodes
+// exactly 32 bytes.
+func TxIDFromString(s string) ([]byte, error) {
+	return ParseTxInputHash(s)
 }
 
 // PkScript represents a bitcoin transaction output script.
@@ -39,15 +197,483 @@ type TxOutput struct {
 	PkScript PkScript
 }
 
+// Hash returns the sha256 of the entry of the output in the outputs layer of
+// the version 10 txid described by CalculateV10TxID, which is the 40 byte
+// serialization of its value as an 8 byte little-endian value followed by the
+// sha256 of its public key script:
+//
+//	sha256(value (8) || sha256(public key script) (32))
+//
+// The outputs layer is the sha256 of the concatenation of the entries rather
+// than of these hashes, so it can't be computed from them, but they commit to
+// the same data as the outputs layer does for each output and are suitable as
+// the leaves of commitment schemes such as output accumulators and merkle
+// trees.
+func (out *TxOutput) Hash() [32]byte {
+	var entry [v10OutputEntrySize]byte
+	putV10OutputEntry(entry[:], out.Value, out.PkScript.Pkscript)
+	return sum256(entry[:])
+}
+
+// Input returns the input at index i and true, or nil and false when i is out
+// of range or the input is nil.  Unlike indexing TxIn directly, it does not
+// panic, so it is suitable for indexes given by untrusted sources such as RPC
+// parameters.
+func (tx *Transaction) Input(i int) (*TxInput, bool) {
+	if i < 0 || i >= len(tx.TxIn) || tx.TxIn[i] == nil {
+		return nil, false
+	}
+	return tx.TxIn[i], true
+}
+
+// Output returns the output at index i and true, or nil and false when i is out
+// of range or the output is nil.  See Input.
+func (tx *Transaction) Output(i int) (*TxOutput, bool) {
+	if i < 0 || i >= len(tx.TxOut) || tx.TxOut[i] == nil {
+		return nil, false
+	}
+	return tx.TxOut[i], true
+}
+
+// Inputs returns a copy of the TxIn slice, so inputs can be added, removed, or
+// reordered in it without affecting the transaction.  The inputs themselves
+// are shared, so use Clone for a copy which can be modified freely.
+func (tx *Transaction) Inputs() []*TxInput {
+	return append([]*TxInput(nil), tx.TxIn...)
+}
+
+// Outputs returns a copy of the TxOut slice.  See Inputs.
+func (tx *Transaction) Outputs() []*TxOutput {
+	return append([]*TxOutput(nil), tx.TxOut...)
+}
+
+// HasWitness returns false if none of the inputs within the transaction
+// contain witness data, true otherwise.
+func (tx *Transaction) HasWitness() bool {
+	for _, txIn := range tx.TxIn {
+		if txIn != nil && len(txIn.Witness) != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsCoinbase determines whether or not the transaction is a coinbase.  As with
+// blockchain.IsCoinBaseTx, a coinbase is represented by a transaction with a
+// single input whose previous outpoint has an all zero hash and the maximum
+// index.  Its signature script is arbitrary.
+//
+// Coinbases need no special handling when computing txids.  In particular,
+// the layered version 10 txid hashes the all zero previous outpoint like any
+// other.
+func (tx *Transaction) IsCoinbase() bool {
+	// A coinbase must only have one transaction input.
+	if len(tx.TxIn) != 1 || tx.TxIn[0] == nil {
+		return false
+	}
+
+	// The previous output of a coinbase must have a max value index and a
+	// zero hash.
+	in := tx.TxIn[0]
+	if in.Index != MaxPrevOutIndex || len(in.Hash) != chainhash.HashSize {
+		return false
+	}
+	for _, b := range in.Hash {
+		if b != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// HasDuplicateInputs returns whether more than one input of the transaction
+// spends the same previous outpoint, meaning the same hash and index.  Such a
+// transaction is invalid since an output can only be spent once, and its
+// layered version 10 txid would nonetheless commit to every copy.  Nil inputs
+// are ignored.
+func (tx *Transaction) HasDuplicateInputs() bool {
+	type outPoint struct {
+		hash  string
+		index uint32
+	}
+
+	if len(tx.TxIn) < 2 {
+		return false
+	}
+
+	seen := make(map[outPoint]struct{}, len(tx.TxIn))
+	for _, in := range tx.TxIn {
+		if in == nil {
+			continue
+		}
+
+		op := outPoint{hash: string(in.Hash), index: in.Index}
+		if _, ok := seen[op]; ok {
+			return true
+		}
+		seen[op] = struct{}{}
+	}
+
+	return false
+}
+
+// witnessCommitmentPrefix is the prefix of the public key script of the
+// coinbase output which holds the witness commitment of a block as defined by
+// BIP0141: OP_RETURN, a 36 byte push, and the commitment header 0xaa21a9ed.
+var witnessCommitmentPrefix = []byte{0x6a, 0x24, 0xaa, 0x21, 0xa9, 0xed}
+
+// WitnessCommitment returns the 32 byte witness commitment held by an output
+// of the coinbase transaction of a block, which commits to the merkle root of
+// the wtxids of all of the transactions in the block.  Following BIP0141, the
+// commitment is in the output with the highest index whose public key script
+// is at least 38 bytes and begins with OP_RETURN, a 36 byte push, and the
+// commitment header 0xaa21a9ed.  Any bytes after the commitment have no
+// consensus meaning and are ignored.
+//
+// False is returned if the transaction is not a coinbase or none of its
+// outputs holds a commitment.  The returned slice aliases the public key
+// script.
+func (tx *Transaction) WitnessCommitment() ([]byte, bool) {
+	if !tx.IsCoinbase() {
+		return nil, false
+	}
+
+	const scriptLen = 38
+	for i := len(tx.TxOut) - 1; i >= 0; i-- {
+		if tx.TxOut[i] == nil {
+			continue
+		}
+
+		script := tx.TxOut[i].PkScript.Pkscript
+		if len(script) >= scriptLen &&
+			bytes.HasPrefix(script, witnessCommitmentPrefix) {
+
+			return script[len(witnessCommitmentPrefix):scriptLen],
+				true
+		}
+	}
+
+	return nil, false
+}
+
+// String returns a human-readable dump of the transaction intended for log
+// messages and debugging.  The first line holds the txid, version, and
+// locktime, followed by one indented line per input and output, so two dumps
+// can be compared line by line.  Hashes are shown in display byte order, and
+// the txid is shown as "unknown" when the transaction can't be serialized.
+func (tx *Transaction) String() string {
+	tx = tx.InternalOrder()
+	txid := tx.displayTxID()
+	if txid == "" {
+		txid = "unknown"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "tx %s version %d locktime %d", txid, tx.Version,
+		tx.LockTime)
+	for i, txIn := range tx.TxIn {
+		if txIn == nil {
+			fmt.Fprintf(&b, "\n  in %d: nil", i)
+			continue
+		}
+		fmt.Fprintf(&b, "\n  in %d: %s:%d script %d bytes sequence "+
+			"0x%08x", i, txIn.DisplayHash(), txIn.Index,
+			len(txIn.SignatureScript), txIn.Sequence)
+		if len(txIn.Witness) != 0 {
+			fmt.Fprintf(&b, " witness %d items", len(txIn.Witness))
+		}
+	}
+	for i, txOut := range tx.TxOut {
+		if txOut == nil {
+			fmt.Fprintf(&b, "\n  out %d: nil", i)
+			continue
+		}
+		fmt.Fprintf(&b, "\n  out %d: value %d script %d bytes %s", i,
+			txOut.Value, len(txOut.PkScript.Pkscript),
+			txOut.PkScript.ScriptType())
+	}
+
+	return b.String()
+}
+
+// displayTxID returns the txid of the transaction as a hex string in display
+// byte order without consulting the cache, or an empty string if it can't be
+// computed.
+func (tx *Transaction) displayTxID() string {
+	if tx.hasNilEntry() {
+		return ""
+	}
+	txid := tx.calcTxID()
+	if txid == nil {
+		return ""
+	}
+	return hex.EncodeToString(ReverseBytes(txid))
+}
+
+// hasNilEntry returns whether any input or output of the transaction is nil.
+func (tx *Transaction) hasNilEntry() bool {
+	for _, txIn := range tx.TxIn {
+		if txIn == nil {
+			return true
+		}
+	}
+	for _, txOut := range tx.TxOut {
+		if txOut == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TxID returns the txid of the transaction in internal byte order, computing
+// it with the version appropriate scheme on first use and caching it for
+// subsequent calls.  Standard transactions are hashed over the serialization
+// produced by Bytes.  Nil is returned, and nothing is cached, if the
+// transaction can't be serialized.
+//
+// The cache is not updated automatically, so InvalidateTxID must be called
+// after mutating the transaction, including a copy of one whose txid was
+// already cached.  It is safe to call TxID concurrently, although concurrent
+// calls made before the txid is cached may each compute it.
+func (tx *Transaction) TxID() []byte {
+	txid, _ := tx.txidCache.Load().([]byte)
+	if txid == nil {
+		txid = tx.calcTxID()
+		if txid == nil {
+			return nil
+		}
+		tx.txidCache.Store(txid)
+	}
+
+	// Return a copy so callers can't modify the cached value.
+	return append([]byte(nil), txid...)
+}
+
+// InvalidateTxID clears the txid cached by TxID so that it is recomputed on
+// the next call.  It must be called after mutating the transaction.
+func (tx *Transaction) InvalidateTxID() {
+	tx.txidCache.Store([]byte(nil))
+}
+
+// InternalOrder returns the transaction with the previous outpoint hashes of
+// its inputs in internal byte order.  The transaction itself is returned when
+// its HashOrder is already HashOrderInternal.  Otherwise a copy is returned
+// with a HashOrder of HashOrderInternal and every hash reversed into its own
+// backing array, while the scripts, witnesses, and outputs are shared with
+// the original.  Nil inputs remain nil.
+func (tx *Transaction) InternalOrder() *Transaction {
+	if tx.HashOrder == HashOrderInternal {
+		return tx
+	}
+
+	internal := &Transaction{
+		Version:    tx.Version,
+		LockTime:   tx.LockTime,
+		TxIn:       make([]*TxInput, len(tx.TxIn)),
+		TxOut:      tx.TxOut,
+		TxInCount:  tx.TxInCount,
+		TxOutCount: tx.TxOutCount,
+	}
+	for i, txIn := range tx.TxIn {
+		if txIn == nil {
+			continue
+		}
+		in := *txIn
+		in.Hash = ReverseBytes(txIn.Hash)
+		internal.TxIn[i] = &in
+	}
+
+	return internal
+}
+
+// shallowCopy returns a copy of the transaction which shares its inputs and
+// outputs, so its header fields can be changed without affecting the original.
+// The cached txid, if any, is not copied.
+func (tx *Transaction) shallowCopy() *Transaction {
+	return &Transaction{
+		Version:    tx.Version,
+		LockTime:   tx.LockTime,
+		TxIn:       tx.TxIn,
+		TxOut:      tx.TxOut,
+		TxInCount:  tx.TxInCount,
+		TxOutCount: tx.TxOutCount,
+		HashOrder:  tx.HashOrder,
+	}
+}
+
+// Clone returns a deep copy of the transaction so the original is not
+// affected when the copy is manipulated.  Every hash, script, and witness item
+// is copied into its own backing array, while nil inputs, outputs, and slices
+// remain nil.  The cached txid, if any, is not copied.
+func (tx *Transaction) Clone() *Transaction {
+	newTx := &Transaction{
+		Version:    tx.Version,
+		LockTime:   tx.LockTime,
+		TxInCount:  tx.TxInCount,
+		TxOutCount: tx.TxOutCount,
+		HashOrder:  tx.HashOrder,
+	}
+
+	if tx.TxIn != nil {
+		newTx.TxIn = make([]*TxInput, len(tx.TxIn))
+	}
+	for i, txIn := range tx.TxIn {
+		if txIn == nil {
+			continue
+		}
+
+		var witness [][]byte
+		if txIn.Witness != nil {
+			witness = make([][]byte, len(txIn.Witness))
+			for j, item := range txIn.Witness {
+				witness[j] = bytes.Clone(item)
+			}
+		}
+		newTx.TxIn[i] = &TxInput{
+			Hash:            bytes.Clone(txIn.Hash),
+			Index:           txIn.Index,
+			SignatureScript: bytes.Clone(txIn.SignatureScript),
+			Sequence:        txIn.Sequence,
+			Witness:         witness,
+		}
+	}
+
+	if tx.TxOut != nil {
+		newTx.TxOut = make([]*TxOutput, len(tx.TxOut))
+	}
+	for i, txOut := range tx.TxOut {
+		if txOut == nil {
+			continue
+		}
+		newTx.TxOut[i] = &TxOutput{
+			Value: txOut.Value,
+			PkScript: PkScript{
+				Pkscript: bytes.Clone(txOut.PkScript.Pkscript),
+			},
+		}
+	}
+
+	return newTx
+}
+
+// Equal returns whether the transaction has the same contents as other.  The
+// version, locktime, and every input and output, including witness stacks,
+// are compared by value, while TxInCount and TxOutCount are ignored in favor
+// of the number of entries actually present.  Nil and empty byte slices are
+// considered equal, as are two nil inputs or outputs at the same position.
+// Previous outpoint hashes are compared in internal byte order, so
+// transactions with a different HashOrder may be equal.  The cached txid is
+// not compared.
+func (tx *Transaction) Equal(other *Transaction) bool {
+	if tx == nil || other == nil {
+		return tx == other
+	}
+	if tx.HashOrder != other.HashOrder {
+		tx, other = tx.InternalOrder(), other.InternalOrder()
+	}
+	if tx.Version != other.Version || tx.LockTime != other.LockTime ||
+		len(tx.TxIn) != len(other.TxIn) ||
+		len(tx.TxOut) != len(other.TxOut) {
+
+		return false
+	}
+
+	for i, txIn := range tx.TxIn {
+		if !txInputsEqual(txIn, other.TxIn[i]) {
+			return false
+		}
+	}
+	for i, txOut := range tx.TxOut {
+		if !txOutputsEqual(txOut, other.TxOut[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// txInputsEqual returns whether the two inputs have the same contents.
+func txInputsEqual(a, b *TxInput) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Index != b.Index || a.Sequence != b.Sequence ||
+		!bytes.Equal(a.Hash, b.Hash) ||
+		!bytes.Equal(a.SignatureScript, b.SignatureScript) {
+
+		return false
+	}
+
+	return witnessesEqual(a.Witness, b.Witness)
+}
+
+// witnessesEqual returns whether the two witness stacks have the same items.
+func witnessesEqual(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i, item := range a {
+		if !bytes.Equal(item, b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// txOutputsEqual returns whether the two outputs have the same contents.
+func txOutputsEqual(a, b *TxOutput) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Value == b.Value &&
+		bytes.Equal(a.PkScript.Pkscript, b.PkScript.Pkscript)
+}
+
+// calcTxID computes the txid of the transaction without consulting the cache.
+func (tx *Transaction) calcTxID() []byte {
+	if entry, ok := lookupTxIDStrategy(tx.Version); ok {
+		return entry.strategy(tx.InternalOrder())
+	}
+
+	raw, err := tx.Bytes()
+	if err != nil {
+		log.Warnf("Unable to compute txid: %v", err)
+		return nil
+	}
+	return doubleSha256(raw)
+}
+
+// DoubleSha256 returns sha256(sha256(b)), which is the digest used for the
+// txid of standard transactions and for block hashes.  The result is in
+// internal byte order, so it must be reversed with ReverseBytes, or formatted
+// with TxIDString, to obtain the familiar display form.
+func DoubleSha256(b []byte) []byte {
+	return doubleSha256Into(make([]byte, sha256.Size), b)
+}
+
 // doubleSha256 计算 sha256(sha256(b)).
 func doubleSha256(b []byte) []byte {
-	first := sha256.Sum256(b)
-	second := sha256.Sum256(first[:])
-	return second[:]
+	return DoubleSha256(b)
+}
+
+// doubleSha256Into computes sha256(sha256(b)) and writes the result into the
+// first 32 bytes of dst, which must be at least that long, returning them.  It
+// allows callers computing many hashes to reuse a single scratch buffer.
+func doubleSha256Into(dst, b []byte) []byte {
+	// The first digest is staged in dst rather than a local array, since
+	// the data passed to the sha256 backend escapes to the heap.
+	return sha256Into(dst, sha256Into(dst, b))
 }
 
-// reverseBytes 反转字节序列
-func reverseBytes(b []byte) []byte {
+// ReverseBytes returns a copy of b with the order of its bytes reversed.  It
+// is the canonical way to convert a hash between the internal byte order held
+// in TxInput.Hash and returned by CalculateTxID, and the display byte order
+// shown by block explorers and RPC interfaces.
+func ReverseBytes(b []byte) []byte {
 	reversed := make([]byte, len(b))
 	for i := 0; i < len(b); i++ {
 		reversed[i] = b[len(b)-1-i]
@@ -55,6 +681,14 @@ func reverseBytes(b []byte) []byte {
 	return reversed
 }
 
+// ReverseBytesInPlace reverses the order of the bytes in b without
+// allocating.  See ReverseBytes for details.
+func ReverseBytesInPlace(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
+
 // ConvertWireMsgTxToCommonTransaction 将 wire.MsgTx 转换为 Transaction
 func ConvertWireMsgTxToCommonTransaction(msgTx *MsgTx) *Transaction {
 	commonTx := &Transaction{
@@ -76,10 +710,16 @@ func ConvertWireMsgTxToCommonTransaction(msgTx *MsgTx) *Transaction {
 			Index:           txIn.PreviousOutPoint.Index,
 			SignatureScript: txIn.SignatureScript,
 			Sequence:        txIn.Sequence,
+			Witness:         txIn.Witness,
 		}
 	}
 
 	for i, txOut := range msgTx.TxOut {
+		if txOut.Value < 0 {
+			log.Warnf("Output %d has negative value %d which is "+
+				"reinterpreted as %d", i, txOut.Value,
+				uint64(txOut.Value))
+		}
 		commonTx.TxOut[i] = &TxOutput{
 			Value: uint64(txOut.Value),
 			PkScript: PkScript{
@@ -91,83 +731,1234 @@ func ConvertWireMsgTxToCommonTransaction(msgTx *MsgTx) *Transaction {
 	return commonTx
 }
 
+// ConvertWireMsgTxToCommonTransactionBE is a variant of
+// ConvertWireMsgTxToCommonTransaction which stores the previous outpoint
+// hashes of the inputs in display (big-endian) byte order rather than the
+// internal byte order of the wire serialization, and sets the HashOrder of
+// the returned transaction to HashOrderDisplay to record it.  The hashes can
+// then be compared against those shown by block explorers and RPC interfaces
+// without reversing them.
+//
+// Since the functions which hash the transaction consult HashOrder, it has
+// the same txid as the result of ConvertWireMsgTxToCommonTransaction.
+func ConvertWireMsgTxToCommonTransactionBE(msgTx *MsgTx) *Transaction {
+	tx := ConvertWireMsgTxToCommonTransaction(msgTx)
+	for _, txIn := range tx.TxIn {
+		// The hashes were copied by the conversion, so they can be
+		// reversed without affecting the MsgTx.
+		ReverseBytesInPlace(txIn.Hash)
+	}
+	tx.HashOrder = HashOrderDisplay
+
+	return tx
+}
+
+// The default script size limits enforced by
+// ConvertWireMsgTxToCommonTransactionErr.  They are the same as the maximum
+// script size allowed by the script engine, txscript.MaxScriptSize.
+const (
+	// DefaultMaxSignatureScriptSize is the default maximum size of the
+	// signature script of an input.
+	DefaultMaxSignatureScriptSize = 10000
+
+	// DefaultMaxPkScriptSize is the default maximum size of the public key
+	// script of an output.
+	DefaultMaxPkScriptSize = 10000
+)
+
+// ErrScriptTooLarge is returned by the checked conversions from MsgTx when a
+// script exceeds the configured maximum size.  The returned errors wrap this
+// value with the offending input or output, so callers should test for it
+// with errors.Is.
+var ErrScriptTooLarge = errors.New("script too large")
+
+// ErrWitnessOnV10 is returned by the checked conversions from MsgTx when
+// ConvertOptions.RejectV10Witness is set and a version 10 transaction has
+// witness data, which version 10 transactions are not permitted to carry.
+// The returned errors wrap this value with the offending input, so callers
+// should test for it with errors.Is.
+var ErrWitnessOnV10 = errors.New("witness data on version 10 transaction")
+
+// ConvertOptions configures the checks performed by
+// ConvertWireMsgTxToCommonTransactionWithOptions.  The zero value uses the
+// defaults.
+type ConvertOptions struct {
+	// MaxSignatureScriptSize is the maximum size of the signature script
+	// of any input.  Zero means DefaultMaxSignatureScriptSize.
+	MaxSignatureScriptSize int
+
+	// MaxPkScriptSize is the maximum size of the public key script of any
+	// output.  Zero means DefaultMaxPkScriptSize.
+	MaxPkScriptSize int
+
+	// RejectV10Witness enables the strict mode in which a version 10
+	// transaction with a non-empty witness stack on any input is
+	// rejected with ErrWitnessOnV10.  The layered txid does not commit to
+	// witness data, so it is otherwise accepted and carried along
+	// unchecked.
+	RejectV10Witness bool
+}
+
+// ConvertWireMsgTxToCommonTransactionErr is a variant of
+// ConvertWireMsgTxToCommonTransaction which validates the transaction rather
+// than blindly reinterpreting it, using the default ConvertOptions.  See
+// ConvertWireMsgTxToCommonTransactionWithOptions for the checks performed.
+// This should be used for transactions which originate from untrusted
+// sources.
+func ConvertWireMsgTxToCommonTransactionErr(msgTx *MsgTx) (*Transaction,
+	error) {
+
+	return ConvertWireMsgTxToCommonTransactionWithOptions(msgTx, nil)
+}
+
+// ConvertWireMsgTxToCommonTransactionWithOptions is a variant of
+// ConvertWireMsgTxToCommonTransaction which validates the transaction
+// according to opts, which may be nil to use the defaults.  An error is
+// returned if:
+//   - any output has a negative value, which would otherwise become a huge
+//     unsigned value, in which case the error wraps ErrNegativeValue
+//   - any output or the sum of all outputs exceeds MaxTxOutputValue, in
+//     which case the error wraps ErrValueTooLarge
+//   - any signature script or public key script exceeds the configured
+//     maximum size, in which case the error wraps ErrScriptTooLarge
+//   - RejectV10Witness is set and the transaction is version 10 with witness
+//     data on any input, in which case the error wraps ErrWitnessOnV10
+//
+// The returned errors are a *TxHashError identifying the offending input or
+// output, and a nil transaction, input, or output is reported with
+// ErrNilTx.
+//
+// The limits are enforced even though deserialization bounds the size of the
+// scripts, since a MsgTx may also be constructed directly, so consumers of
+// the returned Transaction can rely on them.
+func ConvertWireMsgTxToCommonTransactionWithOptions(msgTx *MsgTx,
+	opts *ConvertOptions) (*Transaction, error) {
+
+	maxSigScriptSize := DefaultMaxSignatureScriptSize
+	maxPkScriptSize := DefaultMaxPkScriptSize
+	if opts != nil && opts.MaxSignatureScriptSize != 0 {
+		maxSigScriptSize = opts.MaxSignatureScriptSize
+	}
+	if opts != nil && opts.MaxPkScriptSize != 0 {
+		maxPkScriptSize = opts.MaxPkScriptSize
+	}
+
+	const op = "ConvertWireMsgTxToCommonTransactionWithOptions"
+	if msgTx == nil {
+		return nil, txHashError(op, -1, ErrNilTx, "nil transaction")
+	}
+	rejectWitness := opts != nil && opts.RejectV10Witness &&
+		uint32(msgTx.Version) == LayeredTxIDVersion
+	for i, txIn := range msgTx.TxIn {
+		if txIn == nil {
+			return nil, txHashError(op, i, ErrNilTx, "input %d is "+
+				"nil", i)
+		}
+		if len(txIn.SignatureScript) > maxSigScriptSize {
+			return nil, txHashError(op, i, ErrScriptTooLarge,
+				"input %d signature script is %d bytes, max %d",
+				i, len(txIn.SignatureScript), maxSigScriptSize)
+		}
+		if rejectWitness && len(txIn.Witness) > 0 {
+			return nil, txHashError(op, i, ErrWitnessOnV10,
+				"input %d has %d witness items", i,
+				len(txIn.Witness))
+		}
+	}
+
+	var total uint64
+	for i, txOut := range msgTx.TxOut {
+		if txOut == nil {
+			return nil, txHashError(op, i, ErrNilTx, "output %d "+
+				"is nil", i)
+		}
+		if len(txOut.PkScript) > maxPkScriptSize {
+			return nil, txHashError(op, i, ErrScriptTooLarge,
+				"output %d public key script is %d bytes, max "+
+					"%d", i, len(txOut.PkScript),
+				maxPkScriptSize)
+		}
+		if txOut.Value < 0 {
+			return nil, txHashError(op, i, ErrNegativeValue,
+				"output %d has value %d", i, txOut.Value)
+		}
+
+		// Since each value is limited to MaxTxOutputValue, the sum
+		// can't overflow before it is detected as exceeding it.
+		value := uint64(txOut.Value)
+		if value > MaxTxOutputValue {
+			return nil, txHashError(op, i, ErrValueTooLarge,
+				"output %d value %d exceeds max allowed "+
+					"value %d", i, value, MaxTxOutputValue)
+		}
+		total += value
+		if total > MaxTxOutputValue {
+			return nil, txHashError(op, i, ErrValueTooLarge,
+				"total value of outputs 0 through %d "+
+					"exceeds max allowed value %d",
+				i, MaxTxOutputValue)
+		}
+	}
+
+	return ConvertWireMsgTxToCommonTransaction(msgTx), nil
+}
+
+// ConvertCommonTransactionToWireMsgTx converts a Transaction back into a
+// MsgTx so it can be serialized through the standard wire encoding.  It is the
+// inverse of ConvertWireMsgTxToCommonTransaction, so converting a MsgTx to a
+// Transaction and back produces an identical serialization.  The previous
+// outpoint hashes are reversed when the transaction has a HashOrder of
+// HashOrderDisplay, so the same holds for
+// ConvertWireMsgTxToCommonTransactionBE.
+//
+// An error is returned if any previous outpoint hash is not exactly 32 bytes,
+// which wraps ErrBadHashLen, or any output value does not fit in the signed
+// 64-bit wire representation, which wraps ErrValueTooLarge.
+// The returned MsgTx shares the script and witness byte slices of the
+// transaction.
+func ConvertCommonTransactionToWireMsgTx(tx *Transaction) (*MsgTx, error) {
+	const op = "ConvertCommonTransactionToWireMsgTx"
+	if tx == nil {
+		return nil, txHashError(op, -1, ErrNilTx, "nil transaction")
+	}
+	tx = tx.InternalOrder()
+
+	if tx.TxInCount != uint(len(tx.TxIn)) ||
+		tx.TxOutCount != uint(len(tx.TxOut)) {
+
+		log.Warnf("Ignoring input and output counts %d and %d which "+
+			"do not match the %d inputs and %d outputs",
+			tx.TxInCount, tx.TxOutCount, len(tx.TxIn),
+			len(tx.TxOut))
+	}
+
+	msgTx := &MsgTx{
+		Version:  int32(tx.Version),
+		TxIn:     make([]*TxIn, len(tx.TxIn)),
+		TxOut:    make([]*TxOut, len(tx.TxOut)),
+		LockTime: tx.LockTime,
+	}
+
+	for i, input := range tx.TxIn {
+		if input == nil {
+			return nil, txHashError(op, i, ErrNilTx, "input %d is "+
+				"nil", i)
+		}
+
+		// Both representations now hold the previous outpoint hash
+		// in internal byte order, so it is copied over as is.
+		if err := checkPrevoutHash(op, i, input); err != nil {
+			return nil, err
+		}
+		var hash chainhash.Hash
+		copy(hash[:], input.Hash)
+
+		msgTx.TxIn[i] = &TxIn{
+			PreviousOutPoint: OutPoint{
+				Hash:  hash,
+				Index: input.Index,
+			},
+			SignatureScript: input.SignatureScript,
+			Witness:         input.Witness,
+			Sequence:        input.Sequence,
+		}
+	}
+
+	for i, output := range tx.TxOut {
+		if output == nil {
+			return nil, txHashError(op, i, ErrNilTx, "output %d "+
+				"is nil", i)
+		}
+		if output.Value > math.MaxInt64 {
+			return nil, txHashError(op, i, ErrValueTooLarge,
+				"output %d value %d overflows int64", i,
+				output.Value)
+		}
+
+		msgTx.TxOut[i] = &TxOut{
+			Value:    int64(output.Value),
+			PkScript: output.PkScript.Pkscript,
+		}
+	}
+
+	return msgTx, nil
+}
+
 // CalculateTxID 计算交易ID.
-// 如果交易版本为10, 它将使用一个特殊的三层哈希计算方式.
-// 否则, 它将对原始交易数据进行标准的 double_sha256 计算.
-// rawTxData 是序列化后的原始交易字节.
+// 如果交易版本注册了哈希策略 (版本10默认使用一个特殊的三层哈希计算方式),
+// 它将使用该策略. 否则, 它将对原始交易数据进行标准的 double_sha256 计算.
+// rawTxData 是序列化后的原始交易字节. 对于注册了哈希策略的版本 (包括版本10),
+// rawTxData 会被忽略, 因此可以安全地传入 nil, 无需为此序列化交易.
 // tx 是从 transaction_parser.go 反序列化后的交易结构体.
+// 参见 RegisterTxIDStrategy.
+//
+// 输入的 Hash 按 tx.HashOrder 指定的字节序解释, 并总是以内部字节序 (小端序)
+// 提交, 因此 ConvertWireMsgTxToCommonTransaction 和
+// ConvertWireMsgTxToCommonTransactionBE 得到的交易具有相同的交易ID.
+//
+// CalculateTxID 不会修改 rawTxData 以及交易中的 Hash, SignatureScript 和
+// PkScript.Pkscript, 也不会保留对它们的引用, 返回的交易ID总是新分配的.
+// 因此调用返回后, 调用者可以立即复用这些切片的底层数组.
+//
+// CalculateTxID 返回的是不包含见证数据的 txid, 而不是 wtxid. 对于标准交易,
+// rawTxData 应该是不包含见证数据的序列化 (参见 Transaction.StandardSerialize).
+// 如果 rawTxData 是 BIP0144 见证序列化 (包含 marker, flag 和见证数据) 且交易
+// 包含见证数据, 则改为对交易重新序列化后去除见证数据的字节计算, 因此 txid
+// 与 MsgTx.TxHash 一致. 如果无法序列化交易, 则返回 nil.
+// wtxid 请使用 CalculateWitnessTxID.
+//
+// 如果通过 SetTxIDMetrics 设置了钩子, 每次计算后都会以交易版本和耗时调用它.
+//
+// 如果 tx 为 nil, 则记录警告并返回 nil, 而不会 panic, 这与 CalculateV10TxID
+// 一致. 对于来自不可信来源的交易, 请使用 CalculateTxIDErr 以获得具体的错误.
 func CalculateTxID(rawTxData []byte, tx *Transaction) []byte {
-	if tx.Version != 10 {
-		return doubleSha256(rawTxData)
+	if isNilTx("CalculateTxID", tx) {
+		return nil
 	}
 
-	// 1. 准备各部分数据
-	var (
-		serialization1 []byte // 输入部分
-		serialization2 []byte // 脚本部分
-		serialization3 []byte // 输出部分
-	)
+	onHash, start := startTxIDMetrics()
+	txid := calculateTxID(rawTxData, tx)
+	reportTxIDMetrics(onHash, tx.Version, start)
+	return txid
+}
 
-	// 处理输入部分
-	for _, input := range tx.TxIn {
-		// 序列化: TXID(小端) + VOUT + Sequence
-		serialization1 = append(serialization1, input.Hash...) // 注意: 这里Hash应该已经是小端序
+// calculateTxID computes the txid as described by CalculateTxID without
+// invoking the hook set by SetTxIDMetrics.
+func calculateTxID(rawTxData []byte, tx *Transaction) []byte {
+	entry, ok := lookupTxIDStrategy(tx.Version)
+	if !ok {
+		txid, err := calcStandardTxID(rawTxData, tx)
+		if err != nil {
+			log.Warnf("Unable to compute txid: %v", err)
+			return nil
+		}
+		return txid
+	}
+
+	return entry.strategy(tx.InternalOrder())
+}
+
+// isWitnessSerialization returns whether the raw transaction data is the
+// BIP0144 witness serialization of the transaction rather than the
+// serialization the standard txid is computed over.
+//
+// The marker byte is in the position of the input count, so it is ambiguous
+// with the serialization of a transaction without any inputs.  Since only
+// transactions with inputs can have witness data, the data is only
+// considered to be a witness serialization when the transaction has witness
+// data.
+func isWitnessSerialization(rawTxData []byte, tx *Transaction) bool {
+	return len(rawTxData) > 5 && rawTxData[4] == TxFlagMarker &&
+		rawTxData[5] == byte(WitnessFlag) && tx.HasWitness()
+}
+
+// strippedTxData returns the raw transaction data the standard txid is
+// computed over.  This is the raw data itself unless it is the witness
+// serialization of the transaction, in which case the transaction is
+// serialized again without the marker, flag and witness data.
+func strippedTxData(rawTxData []byte, tx *Transaction) ([]byte, error) {
+	if !isWitnessSerialization(rawTxData, tx) {
+		return rawTxData, nil
+	}
+	return tx.Bytes()
+}
+
+// calcStandardTxID returns the double sha256 of the raw transaction data with
+// any witness data stripped as described by strippedTxData.
+func calcStandardTxID(rawTxData []byte, tx *Transaction) ([]byte, error) {
+	raw, err := strippedTxData(rawTxData, tx)
+	if err != nil {
+		return nil, err
+	}
+	return doubleSha256(raw), nil
+}
+
+// CalculateTxIDInto computes the txid of the transaction in the same way as
+// CalculateTxID and writes it into the first 32 bytes of dst.  For
+// transactions hashed over their raw bytes it does not allocate, which makes
+// it suitable for tight loops over many transactions, such as when reindexing,
+// where the caller can reuse a single destination buffer.  The exception is
+// raw bytes in the witness serialization, which must be serialized again
+// without their witness data.  Transactions using a registered TxIDStrategy
+// are hashed with the strategy and the result is copied into dst.
+//
+// An error is returned if dst is shorter than 32 bytes, which wraps
+// io.ErrShortBuffer, if the transaction is nil, which wraps
+// ErrInvalidTxForHashing and ErrNilTx, if witness data can't be stripped from
+// the raw bytes, or if a registered strategy does not produce a 32 byte txid,
+// which wraps ErrInvalidTxForHashing and ErrBadTxIDLen.  The errors other
+// than those from stripping the witness data are a *TxHashError.
+func CalculateTxIDInto(dst, rawTxData []byte, tx *Transaction) error {
+	onHash, start := startTxIDMetrics()
+	err := calculateTxIDInto(dst, rawTxData, tx)
+	if tx != nil {
+		reportTxIDMetrics(onHash, tx.Version, start)
+	}
+	return err
+}
+
+// calculateTxIDInto computes the txid as described by CalculateTxIDInto
+// without invoking the hook set by SetTxIDMetrics.
+func calculateTxIDInto(dst, rawTxData []byte, tx *Transaction) error {
+	const op = "CalculateTxIDInto"
+	if len(dst) < chainhash.HashSize {
+		return txHashError(op, -1, io.ErrShortBuffer, "destination "+
+			"is %d bytes, want at least %d", len(dst),
+			chainhash.HashSize)
+	}
+	if tx == nil {
+		return invalidTxError(op, -1, ErrNilTx, "nil transaction")
+	}
+
+	entry, ok := lookupTxIDStrategy(tx.Version)
+	if !ok {
+		raw, err := strippedTxData(rawTxData, tx)
+		if err != nil {
+			return err
+		}
+		doubleSha256Into(dst, raw)
+		return nil
+	}
+
+	txid := entry.strategy(tx.InternalOrder())
+	if len(txid) != chainhash.HashSize {
+		return invalidTxError(op, -1, ErrBadTxIDLen, "%d byte txid "+
+			"from strategy for version %d", len(txid), tx.Version)
+	}
+	copy(dst, txid)
+
+	return nil
+}
+
+// CalculateStandardTxID returns the standard txid of the raw transaction,
+// which is the double sha256 of its serialization without any witness data,
+// regardless of its version or any registered TxIDStrategy.  Unlike
+// CalculateTxID, it gives the txid a version 10 transaction would have had
+// under the standard scheme, which is useful for cross-referencing records
+// made before the layered txid was adopted.
+//
+// Raw bytes in the BIP0144 witness serialization are parsed and serialized
+// again without the marker, flag and witness data before being hashed.  As
+// with CalculateTxID, the marker is ambiguous with a transaction without any
+// inputs, so the raw bytes are only treated as a witness serialization when
+// they parse as one in full and carry witness data.  All other raw bytes are
+// hashed as is.
+func CalculateStandardTxID(rawTxData []byte) []byte {
+	if len(rawTxData) > 5 && rawTxData[4] == TxFlagMarker &&
+		rawTxData[5] == byte(WitnessFlag) {
+
+		var msgTx MsgTx
+		r := bytes.NewReader(rawTxData)
+		if msgTx.Deserialize(r) == nil && r.Len() == 0 &&
+			msgTx.HasWitness() {
+
+			serialize := msgTx.SerializeNoWitness
+			hash := chainhash.DoubleHashRaw(serialize)
+			return hash[:]
+		}
+	}
+
+	return DoubleSha256(rawTxData)
+}
+
+// CalculateTxIDAsVersion computes the txid the transaction would have if its
+// version were asVersion, using the TxIDStrategy registered for asVersion, or
+// the standard txid when there is none, rather than the one for the actual
+// version of the transaction.  The version committed to is asVersion as well.
+// It is an analytical tool for cross-referencing records made under different
+// versioning assumptions, such as what txid a version 10 transaction would
+// have had as version 1, and the result is hypothetical rather than the real
+// txid of the transaction, which is only returned by CalculateTxID.
+//
+// For the standard txid, the version is substituted into the first 4 bytes of
+// a copy of the raw bytes, which are hashed as with CalculateTxID.  When raw
+// is empty, such as for a version 10 transaction which has no standard raw
+// bytes, the transaction is serialized instead.  The raw bytes are ignored
+// when asVersion has a registered TxIDStrategy.  The transaction is not
+// modified, and nil is returned if it is nil or can't be serialized.
+func CalculateTxIDAsVersion(tx *Transaction, asVersion uint32,
+	raw []byte) []byte {
 
-		indexBytes := make([]byte, 4)
-		binary.LittleEndian.PutUint32(indexBytes, input.Index)
-		serialization1 = append(serialization1, indexBytes...)
+	if isNilTx("CalculateTxIDAsVersion", tx) {
+		return nil
+	}
+	hypothetical := tx.shallowCopy()
+	hypothetical.Version = asVersion
+
+	return calcTxIDRewritten(hypothetical, raw)
+}
 
-		sequenceBytes := make([]byte, 4)
-		binary.LittleEndian.PutUint32(sequenceBytes, input.Sequence)
-		serialization1 = append(serialization1, sequenceBytes...)
+// CalculateTxIDWithLockTime computes the txid the transaction would have if its
+// locktime were lockTime, without modifying the transaction, so the effect of
+// the locktime on the txid can be examined, such as for lock times either
+// side of the 500,000,000 threshold between block heights and timestamps.
+// The result is hypothetical rather than the real txid of the transaction.
+//
+// The layered txid commits to the locktime in its fixed header, so it is
+// simply substituted there.  For the standard txid, the locktime is
+// substituted into the last 4 bytes of a copy of the raw bytes, which are
+// hashed as with CalculateTxID.  When raw is empty, the transaction is
+// serialized instead.  The raw bytes are ignored for versions with a
+// registered TxIDStrategy, and nil is returned if the transaction is nil or
+// can't be serialized.
+func CalculateTxIDWithLockTime(tx *Transaction, lockTime uint32,
+	raw []byte) []byte {
 
-		// 脚本哈希
-		scriptHash := sha256.Sum256(input.SignatureScript)
-		serialization2 = append(serialization2, scriptHash[:]...)
+	if isNilTx("CalculateTxIDWithLockTime", tx) {
+		return nil
 	}
+	hypothetical := tx.shallowCopy()
+	hypothetical.LockTime = lockTime
+
+	return calcTxIDRewritten(hypothetical, raw)
+}
+
+// calcTxIDRewritten computes the txid of a transaction whose version or
+// locktime has been changed from that of the raw bytes it was parsed from.
+// The version and locktime of the transaction are substituted into a copy of
+// the raw bytes for the standard txid, or the transaction is serialized when
+// raw is empty.
+func calcTxIDRewritten(tx *Transaction, raw []byte) []byte {
+	if _, ok := lookupTxIDStrategy(tx.Version); ok || len(raw) == 0 {
+		if !ok {
+			var err error
+			if raw, err = tx.Bytes(); err != nil {
+				log.Warnf("Unable to compute txid: %v", err)
+				return nil
+			}
+		}
+		return CalculateTxID(raw, tx)
+	}
+
+	// A raw transaction starts with its version and ends with its
+	// locktime, so both are at fixed offsets.
+	if len(raw) < 8 {
+		log.Warnf("Unable to compute txid: raw transaction of %d "+
+			"bytes is too short", len(raw))
+		return nil
+	}
+	rewritten := bytes.Clone(raw)
+	littleEndian.PutUint32(rewritten[:4], tx.Version)
+	littleEndian.PutUint32(rewritten[len(rewritten)-4:], tx.LockTime)
+
+	return CalculateTxID(rewritten, tx)
+}
+
+// CalculateTxIDBoth computes the txid of the transaction in the same way as
+// CalculateTxID, returning it in both byte orders.  The internal txid is the
+// raw hash, which is what is stored in previous outpoints and a
+// chainhash.Hash, while the display txid is its reversal, which is what block
+// explorers and RPC interfaces show when hex encoded.  The two slices do not
+// share memory.
+//
+// CalculateTxID only returns the internal txid and avoids the additional
+// allocation, so it should be preferred when the display form isn't needed.
+func CalculateTxIDBoth(rawTxData []byte, tx *Transaction) (internal,
+	display []byte) {
+
+	internal = CalculateTxID(rawTxData, tx)
+	if internal == nil {
+		return nil, nil
+	}
+
+	return internal, ReverseBytes(internal)
+}
+
+// CalculateTxIDUnsigned computes a pre-signature identifier for the
+// transaction in the same way as CalculateTxID, except that every input is
+// treated as having an empty signature script and no witness data, regardless
+// of its current contents.  Since signing only changes the signature scripts
+// and witnesses, the result is the same before and after the transaction is
+// signed, which allows it to be tracked through a signing pipeline.
+//
+// This is NOT the txid of the transaction.  Once any input has a non-empty
+// signature script, the txid returned by CalculateTxID differs, and only that
+// txid may be used to refer to the transaction on the network, such as in the
+// previous outpoint of a spending input.  The transaction itself is not
+// modified.  Nil is returned if the transaction is nil or can't be
+// serialized.
+func CalculateTxIDUnsigned(tx *Transaction) []byte {
+	if isNilTx("CalculateTxIDUnsigned", tx) {
+		return nil
+	}
+
+	unsigned := &Transaction{
+		Version:    tx.Version,
+		LockTime:   tx.LockTime,
+		TxIn:       make([]*TxInput, len(tx.TxIn)),
+		TxOut:      tx.TxOut,
+		TxInCount:  tx.TxInCount,
+		TxOutCount: tx.TxOutCount,
+		HashOrder:  tx.HashOrder,
+	}
+	for i, txIn := range tx.TxIn {
+		if txIn == nil {
+			continue
+		}
+		unsigned.TxIn[i] = &TxInput{
+			Hash:     txIn.Hash,
+			Index:    txIn.Index,
+			Sequence: txIn.Sequence,
+		}
+	}
+
+	return unsigned.calcTxID()
+}
+
+// CalculateWitnessTxID computes the wtxid of the transaction in internal byte
+// order.  Unlike the txid returned by CalculateTxID, the wtxid commits to the
+// witness stacks of the inputs.
+//
+// Following BIP0141, a transaction with witness data has a wtxid of the
+// double sha256 of its witness serialization for every version, including
+// version 10, while the wtxid of a transaction without any witness data is
+// the same as its txid.  Nil is returned if the transaction is nil or can't
+// be serialized.
+func CalculateWitnessTxID(tx *Transaction) []byte {
+	if isNilTx("CalculateWitnessTxID", tx) {
+		return nil
+	}
+	if !tx.HasWitness() {
+		return tx.calcTxID()
+	}
+
+	raw, err := tx.witnessBytes()
+	if err != nil {
+		log.Warnf("Unable to compute wtxid: %v", err)
+		return nil
+	}
+	return doubleSha256(raw)
+}
+
+// CalculateV10TxID computes the layered txid used by version 10
+// transactions.  Rather than hashing the raw serialized transaction, three
+// independent serializations are built and hashed with a single sha256 each:
+//
+//	inputs:  for each input, prev hash (32) || index (4) || sequence (4)
+//	scripts: for each input, sha256(signature script) (32)
+//	outputs: for each output, value (8) || sha256(public key script) (32)
+//
+// The txid is then the double sha256 of the following 112 byte preimage:
+//
+//	version (4) || locktime (4) || input count (4) || output count (4) ||
+//	sha256(inputs) (32) || sha256(scripts) (32) || sha256(outputs) (32)
+//
+// All integers are encoded as fixed width little-endian values, and the counts
+// are the number of entries in TxIn and TxOut truncated to 32 bits.  This
+// intentionally differs from the wire serialization, which encodes counts as
+// variable length integers (see WriteVarInt), so that the preimage always
+// has the fixed 112 byte layout above.  The TxInCount and TxOutCount fields
+// are never committed to, so CalculateTxIDErr rejects a transaction whose
+// fields disagree with the number of entries with an error wrapping
+// ErrCountMismatch, since other tooling may have used the fields instead.
+// It also rejects counts which would be truncated.  The previous
+// outpoint hashes are committed to in internal (little-endian) byte order,
+// so those of a transaction with a HashOrder of HashOrderDisplay, as produced
+// by ConvertWireMsgTxToCommonTransactionBE, are reversed first.  The version
+// field of the transaction is committed to as is and is not required to be
+// 10.
+//
+// A transaction with neither inputs nor outputs is still hashed, with each
+// layer being the sha256 of the empty string, so its txid is deterministic
+// but does not identify any meaningful transaction.  Such transactions are
+// typically the result of a decoder producing an empty struct, so
+// CalculateTxIDErr rejects them instead.
+//
+// Every previous outpoint hash must be exactly 32 bytes, since the fields of
+// the inputs layer are not length prefixed and a hash of any other length
+// would silently shift the fields after it.  Nil is returned otherwise, and
+// CalculateTxIDErr reports the offending input with an error wrapping
+// ErrBadPrevoutHashLength.  Nil is likewise returned, rather than panicking,
+// for a nil transaction or one with a nil input or output, which
+// CalculateTxIDErr reports with an error wrapping ErrNilTx.
+func CalculateV10TxID(tx *Transaction) []byte {
+	if err := checkV10Entries("CalculateV10TxID", tx); err != nil {
+		log.Warnf("Unable to compute txid: %v", err)
+		return nil
+	}
+
+	h := getTxIDHasher()
+	defer putTxIDHasher(h)
+
+	return calcV10TxID(tx, h)
+}
+
+// CalculateV10Layers returns the sha256 of each of the inputs, scripts, and
+// outputs serializations described by CalculateV10TxID, which are combined
+// with the version, locktime, and counts to produce the txid.  When a
+// computed txid doesn't match an expected one, comparing the layers against
+// those of a reference implementation shows which section of the transaction
+// the discrepancy is in.  Unlike CalculateV10TxID, previous outpoint hashes
+// which are not 32 bytes are hashed as is rather than rejected, so their
+// effect on the inputs layer can be examined.  As with CalculateV10TxID, a
+// warning is logged and zero layers are returned, rather than panicking, for
+// a nil transaction or one with a nil input or output.
+func CalculateV10Layers(tx *Transaction) (inputsHash, scriptsHash,
+	outputsHash [32]byte) {
+
+	if err := checkNilEntries("CalculateV10Layers", tx); err != nil {
+		log.Warnf("Unable to compute txid: %v", err)
+		return inputsHash, scriptsHash, outputsHash
+	}
+
+	h := getTxIDHasher()
+	defer putTxIDHasher(h)
+
+	addV10Entries(tx, h)
+	return h.layers()
+}
+
+// V10FinalPreimage returns the 112 byte preimage described by CalculateV10TxID
+// whose double sha256 is the layered txid of the transaction, consisting of
+// its version, locktime, and counts followed by the three layer hashes
+// returned by CalculateV10Layers.  When two implementations of the layered
+// txid disagree, comparing hex dumps of their preimages shows which of the
+// fields differs.  As with CalculateV10Layers, previous outpoint hashes which
+// are not 32 bytes are hashed as is rather than rejected, while nil is
+// returned for a nil transaction or one with a nil input or output.
+func V10FinalPreimage(tx *Transaction) []byte {
+	if err := checkNilEntries("V10FinalPreimage", tx); err != nil {
+		log.Warnf("Unable to compute txid: %v", err)
+		return nil
+	}
+
+	h := getTxIDHasher()
+	defer putTxIDHasher(h)
+
+	addV10Entries(tx, h)
+	inputsHash, scriptsHash, outputsHash := h.layers()
+
+	preimage := make([]byte, len(h.preimage))
+	putV10Preimage(preimage, tx.Version, tx.LockTime, h.numIn, h.numOut,
+		&inputsHash, &scriptsHash, &outputsHash)
+
+	return preimage
+}
+
+// CalculateOutputsCommitment returns a commitment to the version, locktime,
+// and outputs of the transaction which is independent of its inputs, so
+// signers of a partially signed transaction can verify they are all signing
+// the same outputs.  It is the single sha256 of the following 44 byte
+// preimage:
+//
+//	version (4) || locktime (4) || output count (4) || sha256(outputs) (32)
+//
+// where the integers are fixed width little-endian values, the output count
+// is the number of entries in TxOut truncated to 32 bits, and sha256(outputs)
+// is the outputs layer of the version 10 txid described by CalculateV10TxID,
+// which is also returned by CalculateV10Layers.  The commitment does not
+// depend on the transaction version being 10, but it is not a txid and does
+// not commit to which outputs are spent.
+//
+// Nil is returned, rather than panicking, for a nil transaction or one with a
+// nil output.
+func CalculateOutputsCommitment(tx *Transaction) []byte {
+	const op = "CalculateOutputsCommitment"
+	if err := checkNilOutputs(op, tx); err != nil {
+		log.Warnf("Unable to compute %s: %v", op, err)
+		return nil
+	}
+
+	h := getTxIDHasher()
+	defer putTxIDHasher(h)
 
-	// 处理输出部分
 	for _, output := range tx.TxOut {
-		valueBytes := make([]byte, 8)
-		binary.LittleEndian.PutUint64(valueBytes, output.Value)
-		serialization3 = append(serialization3, valueBytes...)
+		h.AddOutput(output)
+	}
+	_, _, outputsHash := h.layers()
+
+	var preimage [12 + sha256.Size]byte
+	littleEndian.PutUint32(preimage[0:4], tx.Version)
+	littleEndian.PutUint32(preimage[4:8], tx.LockTime)
+	littleEndian.PutUint32(preimage[8:12], h.numOut)
+	copy(preimage[12:], outputsHash[:])
+
+	commitment := sum256(preimage[:])
+	return commitment[:]
+}
+
+// CombineV10Layers performs the final step of the layered txid described by
+// CalculateV10TxID, combining the layer hashes returned by CalculateV10Layers
+// with the remaining fields of the transaction.  This allows the layers to be
+// computed separately, such as on different machines, and combined later.
+// The result is in internal byte order and is the double sha256 of the
+// following 112 byte preimage:
+//
+//	offset  size  field
+//	0       4     version, little-endian
+//	4       4     locktime, little-endian
+//	8       4     numIn, little-endian
+//	12      4     numOut, little-endian
+//	16      32    inputsHash
+//	48      32    scriptsHash
+//	80      32    outputsHash
+//
+// where numIn and numOut are the number of inputs and outputs of the
+// transaction, and each layer hash is the single sha256 of its layer:
+//
+//	inputsHash:  sha256 of prev hash (32) || index (4, little-endian) ||
+//	             sequence (4, little-endian) for each input in order
+//	scriptsHash: sha256 of sha256(signature script) (32) for each input
+//	             in order
+//	outputsHash: sha256 of value (8, little-endian) ||
+//	             sha256(public key script) (32) for each output in order
+//
+// The previous outpoint hashes are in internal byte order.
+func CombineV10Layers(version, locktime uint32, numIn, numOut uint32,
+	inputsHash, scriptsHash, outputsHash [32]byte) []byte {
 
-		scriptHash := sha256.Sum256(output.PkScript.Pkscript)
-		serialization3 = append(serialization3, scriptHash[:]...)
+	preimage := make([]byte, 16+3*sha256.Size)
+	putV10Preimage(preimage, version, locktime, numIn, numOut,
+		&inputsHash, &scriptsHash, &outputsHash)
+
+	return doubleSha256(preimage)
+}
+
+// V10CountEncoding selects how the input and output counts are encoded in the
+// preimage of the layered txid.
+type V10CountEncoding uint8
+
+const (
+	// V10CountFixed32 encodes each count as a fixed width 4-byte
+	// little-endian value.  It is the encoding used by CalculateV10TxID
+	// and the zero value.
+	V10CountFixed32 V10CountEncoding = iota
+
+	// V10CountVarInt encodes each count as a variable length integer in
+	// the same way as the wire serialization (see WriteVarInt), so the
+	// preimage is between 106 and 122 bytes long and the counts are never
+	// truncated.  It is only intended for interoperability experiments
+	// and does not produce valid version 10 txids.
+	V10CountVarInt
+)
+
+// V10Options configures the construction of the layered txid computed by
+// CalculateV10TxIDWithOptions.  The zero value produces the same txid as
+// CalculateV10TxID.
+type V10Options struct {
+	// CountEncoding selects the encoding of the input and output counts
+	// in the preimage.  The layers themselves are unaffected.
+	CountEncoding V10CountEncoding
+}
+
+// CalculateV10TxIDWithOptions computes the layered txid described by
+// CalculateV10TxID with the preimage modified according to opts.  With the
+// zero value, the result is identical to CalculateV10TxID.  With
+// V10CountVarInt, the preimage is instead:
+//
+//	version (4) || locktime (4) || input count (varint) ||
+//	output count (varint) || sha256(inputs) (32) || sha256(scripts) (32) ||
+//	sha256(outputs) (32)
+//
+// Nil is returned for an unknown count encoding.
+func CalculateV10TxIDWithOptions(tx *Transaction, opts V10Options) []byte {
+	switch opts.CountEncoding {
+	case V10CountFixed32:
+		return CalculateV10TxID(tx)
+
+	case V10CountVarInt:
+		err := checkV10Entries("CalculateV10TxIDWithOptions", tx)
+		if err != nil {
+			log.Warnf("Unable to compute txid: %v", err)
+			return nil
+		}
+
+		h := getTxIDHasher()
+		defer putTxIDHasher(h)
+
+		addV10Entries(tx, h)
+		inputsHash, scriptsHash, outputsHash := h.layers()
+
+		var preimage bytes.Buffer
+		preimage.Grow(8 + 2*MaxVarIntPayload + 3*sha256.Size)
+
+		var buf [8]byte
+		littleEndian.PutUint32(buf[:4], tx.Version)
+		littleEndian.PutUint32(buf[4:], tx.LockTime)
+		preimage.Write(buf[:])
+
+		// Writes to a bytes.Buffer can't fail.
+		_ = WriteVarInt(&preimage, 0, uint64(len(tx.TxIn)))
+		_ = WriteVarInt(&preimage, 0, uint64(len(tx.TxOut)))
+
+		preimage.Write(inputsHash[:])
+		preimage.Write(scriptsHash[:])
+		preimage.Write(outputsHash[:])
+
+		return doubleSha256(preimage.Bytes())
+	}
+
+	log.Warnf("Unable to compute txid: unknown version 10 count "+
+		"encoding %d", opts.CountEncoding)
+	return nil
+}
+
+// calcV10TxID computes the layered txid of the transaction using the provided
+// hasher, which is reset first.  This allows callers hashing many
+// transactions to reuse the same sha256 states.
+func calcV10TxID(tx *Transaction, h *TxIDHasher) []byte {
+	h.Reset()
+	addV10Entries(tx, h)
+	return h.Sum(tx.Version, tx.LockTime)
+}
+
+// addV10Entries adds all of the inputs and outputs of the transaction to the
+// hasher, with the previous outpoint hashes in internal byte order.
+func addV10Entries(tx *Transaction, h *TxIDHasher) {
+	tx = tx.InternalOrder()
+	for _, input := range tx.TxIn {
+		h.AddInput(input)
+	}
+	for _, output := range tx.TxOut {
+		h.AddOutput(output)
+	}
+}
+
+// VerifyV10TxID returns whether the layered txid of the transaction as
+// computed by CalculateV10TxID matches the expected txid, which must be in
+// internal byte order.
+func VerifyV10TxID(tx *Transaction, expected []byte) bool {
+	return TxIDEqual(CalculateV10TxID(tx), expected)
+}
+
+// TxIDEqual returns whether the two txids are equal.  Unlike bytes.Equal, it
+// returns false unless both are exactly chainhash.HashSize bytes, so a
+// truncated or empty txid never matches, and the comparison takes constant
+// time with respect to their contents.
+//
+// Both txids must be in the same byte order.  Comparing a computed txid,
+// which is in internal byte order, against a display order one, such as the
+// result of decoding a txid string directly as hex, will always fail, so the
+// latter must first be reversed with ReverseBytes.  Note that a
+// chainhash.Hash is already in internal byte order.
+func TxIDEqual(a, b []byte) bool {
+	if len(a) != chainhash.HashSize || len(b) != chainhash.HashSize {
+		return false
+	}
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
+
+// The orientations reported by MatchesTxID.
+const (
+	// TxIDOrientationDisplay means the txid is in display (big-endian)
+	// byte order, as shown by block explorers and RPC interfaces.
+	TxIDOrientationDisplay = "display"
+
+	// TxIDOrientationInternal means the txid is in the internal byte
+	// order returned by CalculateTxID, which is the reverse of the display
+	// order.
+	TxIDOrientationInternal = "internal"
+)
+
+// MatchesTxID computes the txid of the transaction as CalculateTxID does and
+// returns whether it matches the expected hex encoded txid in either byte
+// order, along with the orientation the expected txid was found to be in,
+// which is TxIDOrientationDisplay or TxIDOrientationInternal.  The
+// orientation is empty when the txid does not match, including when the
+// expected txid is not valid hex encoding exactly 32 bytes.  Surrounding
+// whitespace is ignored.
+//
+// This is a debugging aid for txids of unknown origin which may have had their
+// bytes reversed.  Code which knows the byte order of the expected txid
+// should compare it with TxIDEqual instead.
+func MatchesTxID(tx *Transaction, raw []byte, expectedHex string) (
+	matched bool, orientation string) {
+
+	expected, err := hex.DecodeString(strings.TrimSpace(expectedHex))
+	if err != nil || len(expected) != chainhash.HashSize {
+		return false, ""
+	}
+
+	txid := CalculateTxID(raw, tx)
+	switch {
+	case TxIDEqual(ReverseBytes(expected), txid):
+		return true, TxIDOrientationDisplay
+
+	case TxIDEqual(expected, txid):
+		return true, TxIDOrientationInternal
+	}
+
+	return false, ""
+}
+
+// CalculateTxIDErr is a variant of CalculateTxID which validates the supplied
+// transaction before hashing it and returns an error wrapping
+// ErrInvalidTxForHashing rather than silently producing a hash over malformed
+// data.  This should be used for transactions which originate from untrusted
+// sources such as remote peers.
+//
+// The following conditions are rejected:
+//   - a nil transaction, or a nil input or output entry, which wraps ErrNilTx
+//   - any input whose previous outpoint hash is not exactly 32 bytes, which
+//     wraps ErrBadHashLen
+//   - a transaction hashed over its raw bytes without any raw bytes, which
+//     wraps ErrMissingRawTx
+//   - a transaction whose version has a registered TxIDStrategy, such as the
+//     built-in version 10 strategy, when its TxInCount or TxOutCount do not
+//     match the number of inputs and outputs, which wraps ErrCountMismatch,
+//     when either exceeds math.MaxUint32 since the counts are committed to
+//     as 32-bit values, which wraps ErrCountTooLarge, or when it has neither
+//     inputs nor outputs, which wraps ErrEmptyTx
+//
+// The returned errors are a *TxHashError, which callers can obtain with
+// errors.As to report the offending input or output by its Index.  Stricter
+// checks, such as rejecting duplicate inputs, are available through
+// CalculateTxIDErrWithOptions.
+//
+// As with CalculateTxID, the raw bytes are ignored and may be nil for
+// versions with a registered TxIDStrategy, and any witness data in them is
+// stripped.
+func CalculateTxIDErr(rawTxData []byte, tx *Transaction) ([]byte, error) {
+	return CalculateTxIDErrWithOptions(rawTxData, tx, nil)
+}
+
+// TxIDErrOptions configures the checks performed by
+// CalculateTxIDErrWithOptions.  The zero value uses the defaults, which are
+// the checks performed by CalculateTxIDErr.
+type TxIDErrOptions struct {
+	// RejectDuplicateInputs enables the strict mode in which a
+	// transaction with more than one input spending the same previous
+	// outpoint, as reported by HasDuplicateInputs, is rejected with
+	// ErrDuplicateInputs.  Such a transaction is invalid, but its txid is
+	// still well defined, so it is otherwise hashed like any other.
+	RejectDuplicateInputs bool
+}
+
+// rejectDuplicateInputs returns whether duplicate inputs are rejected for the
+// options, which may be nil.
+func (opts *TxIDErrOptions) rejectDuplicateInputs() bool {
+	return opts != nil && opts.RejectDuplicateInputs
+}
+
+// CalculateTxIDErrWithOptions is a variant of CalculateTxIDErr which
+// validates the transaction according to opts, which may be nil to use the
+// defaults, before hashing it.  In addition to the conditions rejected by
+// CalculateTxIDErr, a transaction with duplicate inputs is rejected with an
+// error wrapping ErrDuplicateInputs when RejectDuplicateInputs is set.  This
+// is useful to avoid indexing obviously invalid transactions fetched from
+// peers.
+func CalculateTxIDErrWithOptions(rawTxData []byte, tx *Transaction,
+	opts *TxIDErrOptions) ([]byte, error) {
+
+	onHash, start := startTxIDMetrics()
+	txid, err := calculateTxIDErr(rawTxData, tx, opts)
+	if tx != nil {
+		reportTxIDMetrics(onHash, tx.Version, start)
+	}
+	return txid, err
+}
+
+// calculateTxIDErr computes the txid as described by
+// CalculateTxIDErrWithOptions without invoking the hook set by
+// SetTxIDMetrics.
+func calculateTxIDErr(rawTxData []byte, tx *Transaction,
+	opts *TxIDErrOptions) ([]byte, error) {
+
+	if err := validateTxForHashing(rawTxData, tx, opts); err != nil {
+		return nil, err
+	}
+
+	if _, ok := lookupTxIDStrategy(tx.Version); !ok {
+		return calcStandardTxID(rawTxData, tx)
+	}
+	return calculateTxID(rawTxData, tx), nil
+}
+
+// CalculateMsgTxID computes the txid of the wire transaction in internal byte
+// order.  It converts the transaction with
+// ConvertWireMsgTxToCommonTransactionErr and then hashes it with
+// CalculateTxIDErr, serializing it only when its version is hashed over its
+// raw bytes, so the raw bytes always match the transaction being hashed.  Any
+// error from either step is returned.
+//
+// Unlike MsgTx.TxHash, which computes the same txid, malformed transactions
+// are reported rather than hashed, so this should be preferred for
+// transactions which originate from untrusted sources.
+func CalculateMsgTxID(msgTx *MsgTx) ([]byte, error) {
+	tx, err := ConvertWireMsgTxToCommonTransactionErr(msgTx)
+	if err != nil {
+		return nil, err
 	}
 
-	// 计算各部分哈希
-	hash1 := sha256.Sum256(serialization1)
-	hash2 := sha256.Sum256(serialization2)
-	hash3 := sha256.Sum256(serialization3)
+	if tx.HasWitness() {
+		log.Debugf("Witness data of version %d transaction is not "+
+			"committed to by its txid", tx.Version)
+	}
+
+	var raw []byte
+	if _, ok := lookupTxIDStrategy(tx.Version); !ok {
+		raw, err = tx.Bytes()
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	// 准备头部数据
-	versionBytes := make([]byte, 4)
-	binary.LittleEndian.PutUint32(versionBytes, tx.Version)
+	return CalculateTxIDErr(raw, tx)
+}
+
+// isNilTx returns whether the transaction is nil, logging a warning that no
+// txid could be computed for op if it is, so the unchecked txid functions can
+// return nil rather than panic.
+func isNilTx(op string, tx *Transaction) bool {
+	if tx != nil {
+		return false
+	}
 
-	locktimeBytes := make([]byte, 4)
-	binary.LittleEndian.PutUint32(locktimeBytes, tx.LockTime)
+	err := txHashError(op, -1, ErrNilTx, "nil transaction")
+	log.Warnf("Unable to compute txid: %v", err)
+	return true
+}
 
-	inputCountBytes := make([]byte, 4)
-	binary.LittleEndian.PutUint32(inputCountBytes, uint32(len(tx.TxIn)))
+// checkPrevoutHash returns a TxHashError for op wrapping ErrBadHashLen if the
+// previous outpoint hash of the input at index i is not 32 bytes.
+func checkPrevoutHash(op string, i int, input *TxInput) error {
+	if len(input.Hash) != chainhash.HashSize {
+		return txHashError(op, i, ErrBadHashLen, "input %d is %d "+
+			"bytes, want %d", i, len(input.Hash),
+			chainhash.HashSize)
+	}
+	return nil
+}
 
-	outputCountBytes := make([]byte, 4)
-	binary.LittleEndian.PutUint32(outputCountBytes, uint32(len(tx.TxOut)))
+// checkPrevoutHashes returns a TxHashError for op wrapping ErrNilTx if the
+// transaction or any of its inputs is nil, or the error from checkPrevoutHash
+// for the first input whose previous outpoint hash is not 32 bytes.
+func checkPrevoutHashes(op string, tx *Transaction) error {
+	if tx == nil {
+		return txHashError(op, -1, ErrNilTx, "nil transaction")
+	}
+	for i, input := range tx.TxIn {
+		if input == nil {
+			return txHashError(op, i, ErrNilTx, "input %d is nil",
+				i)
+		}
+		if err := checkPrevoutHash(op, i, input); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	// 构建最终序列化数据
-	finalSerialization := bytes.Join([][]byte{
-		versionBytes,
-		locktimeBytes,
-		inputCountBytes,
-		outputCountBytes,
-		hash1[:],
-		hash2[:],
-		hash3[:],
-	}, nil)
+// checkV10Entries returns the error from checkPrevoutHashes, or a TxHashError
+// for op wrapping ErrNilTx if any output is nil, so the layered txid can be
+// computed over every entry of the transaction without panicking.
+func checkV10Entries(op string, tx *Transaction) error {
+	if err := checkPrevoutHashes(op, tx); err != nil {
+		return err
+	}
+	for i, output := range tx.TxOut {
+		if output == nil {
+			return txHashError(op, i, ErrNilTx, "output %d is nil",
+				i)
+		}
+	}
+	return nil
+}
 
-	// 计算最终TXID (SHA256d)
-	firstHash := sha256.Sum256(finalSerialization)
-	finalHash := sha256.Sum256(firstHash[:])
+// checkNilEntries returns a TxHashError for op wrapping ErrNilTx if the
+// transaction or any of its inputs or outputs is nil.  Unlike checkV10Entries,
+// previous outpoint hashes of any length are accepted.
+func checkNilEntries(op string, tx *Transaction) error {
+	if err := checkBIP143Inputs(op, tx); err != nil {
+		return err
+	}
+	return checkNilOutputs(op, tx)
+}
+
+// checkNilOutputs returns a TxHashError for op wrapping ErrNilTx if the
+// transaction or any of its outputs is nil.
+func checkNilOutputs(op string, tx *Transaction) error {
+	if tx == nil {
+		return txHashError(op, -1, ErrNilTx, "nil transaction")
+	}
+	for i, output := range tx.TxOut {
+		if output == nil {
+			return txHashError(op, i, ErrNilTx, "output %d is nil",
+				i)
+		}
+	}
+	return nil
+}
+
+// validateTxForHashing ensures the transaction is well formed enough for its
+// txid to be meaningful according to opts, which may be nil to use the
+// defaults.  See CalculateTxIDErrWithOptions for the rules.
+func validateTxForHashing(rawTxData []byte, tx *Transaction,
+	opts *TxIDErrOptions) error {
+
+	if tx == nil {
+		return invalidForHashing(-1, ErrNilTx, "nil transaction")
+	}
+
+	for i, input := range tx.TxIn {
+		if input == nil {
+			return invalidForHashing(i, ErrNilTx, "input %d is nil",
+				i)
+		}
+		if len(input.Hash) != chainhash.HashSize {
+			return invalidForHashing(i, ErrBadHashLen, "input %d "+
+				"is %d bytes, want %d", i, len(input.Hash),
+				chainhash.HashSize)
+		}
+	}
+	for i, output := range tx.TxOut {
+		if output == nil {
+			return invalidForHashing(i, ErrNilTx, "output %d is "+
+				"nil", i)
+		}
+	}
+	if opts.rejectDuplicateInputs() && tx.HasDuplicateInputs() {
+		return invalidForHashing(-1, ErrDuplicateInputs, "multiple "+
+			"inputs spend the same previous outpoint")
+	}
+
+	// The standard path only hashes the raw bytes, so there is nothing
+	// further to check about the parsed structure.
+	if _, ok := lookupTxIDStrategy(tx.Version); !ok {
+		if len(rawTxData) == 0 {
+			return invalidForHashing(-1, ErrMissingRawTx, "no raw "+
+				"transaction data for version %d transaction",
+				tx.Version)
+		}
+		return nil
+	}
+
+	// The layered hash commits to the counts as 32-bit values, so larger
+	// counts would be silently truncated, allowing two different
+	// transactions to collide on the same txid.
+	if uint64(len(tx.TxIn)) > math.MaxUint32 ||
+		uint64(tx.TxInCount) > math.MaxUint32 {
+
+		count := max(uint(len(tx.TxIn)), tx.TxInCount)
+		return invalidForHashing(-1, ErrCountTooLarge, "input count "+
+			"%d exceeds max %d", count, uint32(math.MaxUint32))
+	}
+	if uint64(len(tx.TxOut)) > math.MaxUint32 ||
+		uint64(tx.TxOutCount) > math.MaxUint32 {
+
+		count := max(uint(len(tx.TxOut)), tx.TxOutCount)
+		return invalidForHashing(-1, ErrCountTooLarge, "output count "+
+			"%d exceeds max %d", count, uint32(math.MaxUint32))
+	}
+
+	// Strategies such as the layered hash commit to the input and output
+	// counts, so any disagreement between the stored counts and the
+	// actual entries means the parser and the hasher would not agree on
+	// what was committed to.
+	if tx.TxInCount != uint(len(tx.TxIn)) {
+		return invalidForHashing(-1, ErrCountMismatch, "input count "+
+			"%d does not match %d inputs", tx.TxInCount,
+			len(tx.TxIn))
+	}
+	if tx.TxOutCount != uint(len(tx.TxOut)) {
+		return invalidForHashing(-1, ErrCountMismatch, "output count "+
+			"%d does not match %d outputs", tx.TxOutCount,
+			len(tx.TxOut))
+	}
+	if len(tx.TxIn) == 0 && len(tx.TxOut) == 0 {
+		return invalidForHashing(-1, ErrEmptyTx, "version %d "+
+			"transaction has no inputs or outputs", tx.Version)
+	}
 
-	return finalHash[:]
+	return nil
 }
@@ -0,0 +1,2531 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/stretchr/testify/require"
+)
+
+// hashSink is assigned the results of the hashing benchmarks so the compiler
+// can't optimize away the work or the allocations being measured.
+var hashSink []byte
+
+// v10TestTx returns a small, fully populated version 10 transaction used
+// throughout the txid helper tests.
+func v10TestTx() *Transaction {
+	return &Transaction{
+		Version:  10,
+		LockTime: 0,
+		TxIn: []*TxInput{
+			{
+				Hash:            bytes.Repeat([]byte{0x11}, 32),
+				Index:           0,
+				SignatureScript: []byte{0x51},
+				Sequence:        0xffffffff,
+			},
+			{
+				Hash:            bytes.Repeat([]byte{0x22}, 32),
+				Index:           1,
+				SignatureScript: []byte{0x52, 0x53},
+				Sequence:        0xfffffffe,
+			},
+		},
+		TxOut: []*TxOutput{
+			{
+				Value: 5000000000,
+				PkScript: PkScript{
+					Pkscript: []byte{0x76, 0xa9},
+				},
+			},
+		},
+		TxInCount:  2,
+		TxOutCount: 1,
+	}
+}
+
+// TestCalculateTxIDErr ensures CalculateTxIDErr rejects malformed
+// transactions and agrees with CalculateTxID for well formed ones.
+func TestCalculateTxIDErr(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		raw      []byte
+		mutate   func(tx *Transaction) *Transaction
+		wantErr  bool
+		sentinel error
+	}{{
+		name:   "valid v10",
+		mutate: func(tx *Transaction) *Transaction { return tx },
+	}, {
+		name: "valid standard",
+		raw:  []byte{0x01, 0x00, 0x00, 0x00},
+		mutate: func(tx *Transaction) *Transaction {
+			tx.Version = 1
+			return tx
+		},
+	}, {
+		name:     "nil tx",
+		mutate:   func(*Transaction) *Transaction { return nil },
+		wantErr:  true,
+		sentinel: ErrNilTx,
+	}, {
+		name: "nil input",
+		mutate: func(tx *Transaction) *Transaction {
+			tx.TxIn[1] = nil
+			return tx
+		},
+		wantErr:  true,
+		sentinel: ErrNilTx,
+	}, {
+		name: "nil output",
+		mutate: func(tx *Transaction) *Transaction {
+			tx.TxOut[0] = nil
+			return tx
+		},
+		wantErr:  true,
+		sentinel: ErrNilTx,
+	}, {
+		name: "short prevout hash",
+		mutate: func(tx *Transaction) *Transaction {
+			tx.TxIn[0].Hash = tx.TxIn[0].Hash[:31]
+			return tx
+		},
+		wantErr:  true,
+		sentinel: ErrBadHashLen,
+	}, {
+		name: "duplicate inputs",
+		mutate: func(tx *Transaction) *Transaction {
+			tx.TxIn[1].Hash = tx.TxIn[0].Hash
+			tx.TxIn[1].Index = tx.TxIn[0].Index
+			return tx
+		},
+	}, {
+		name: "standard without raw bytes",
+		mutate: func(tx *Transaction) *Transaction {
+			tx.Version = 2
+			return tx
+		},
+		wantErr:  true,
+		sentinel: ErrMissingRawTx,
+	}, {
+		name: "input count mismatch",
+		mutate: func(tx *Transaction) *Transaction {
+			tx.TxInCount = 3
+			return tx
+		},
+		wantErr:  true,
+		sentinel: ErrCountMismatch,
+	}, {
+		name: "output count mismatch",
+		mutate: func(tx *Transaction) *Transaction {
+			tx.TxOutCount = 0
+			return tx
+		},
+		wantErr:  true,
+		sentinel: ErrCountMismatch,
+	}, {
+		name: "input count exceeds uint32",
+		mutate: func(tx *Transaction) *Transaction {
+			count := uint64(math.MaxUint32) + 2
+			tx.TxInCount = uint(count)
+			return tx
+		},
+		wantErr: true,
+	}, {
+		name: "output count exceeds uint32",
+		mutate: func(tx *Transaction) *Transaction {
+			count := uint64(math.MaxUint32) + 1
+			tx.TxOutCount = uint(count)
+			return tx
+		},
+		wantErr: true,
+	}, {
+		name: "empty v10",
+		mutate: func(tx *Transaction) *Transaction {
+			tx.TxIn, tx.TxOut = nil, nil
+			tx.TxInCount, tx.TxOutCount = 0, 0
+			return tx
+		},
+		wantErr:  true,
+		sentinel: ErrEmptyTx,
+	}}
+
+	for _, test := range tests {
+		tx := test.mutate(v10TestTx())
+		id, err := CalculateTxIDErr(test.raw, tx)
+		if test.wantErr {
+			require.Error(t, err, test.name)
+			require.True(t, errors.Is(err, ErrInvalidTxForHashing),
+				test.name)
+			if test.sentinel != nil {
+				require.ErrorIs(t, err, test.sentinel,
+					test.name)
+			}
+			require.Nil(t, id, test.name)
+			continue
+		}
+
+		require.NoError(t, err, test.name)
+		require.Equal(t, CalculateTxID(test.raw, tx), id, test.name)
+	}
+
+	// Counts which don't fit into the 32-bit fields committed to by the
+	// layered hash are reported as such rather than as a mismatch.
+	if math.MaxUint32 < uint64(^uint(0)) {
+		tx := v10TestTx()
+		count := uint64(math.MaxUint32) + 1
+		tx.TxInCount = uint(count)
+		_, err := CalculateTxIDErr(nil, tx)
+		require.ErrorIs(t, err, ErrCountTooLarge)
+	}
+}
+
+// refV10Preimage independently builds the 112 byte preimage documented on
+// CalculateV10TxID by materializing each of the three serializations.
+func refV10Preimage(tx *Transaction) []byte {
+	var inputs, scripts, outputs []byte
+	for _, in := range tx.TxIn {
+		inputs = append(inputs, in.Hash...)
+		inputs = binary.LittleEndian.AppendUint32(inputs, in.Index)
+		inputs = binary.LittleEndian.AppendUint32(inputs, in.Sequence)
+		scriptHash := sha256.Sum256(in.SignatureScript)
+		scripts = append(scripts, scriptHash[:]...)
+	}
+	for _, out := range tx.TxOut {
+		outputs = binary.LittleEndian.AppendUint64(outputs, out.Value)
+		scriptHash := sha256.Sum256(out.PkScript.Pkscript)
+		outputs = append(outputs, scriptHash[:]...)
+	}
+
+	var preimage []byte
+	preimage = binary.LittleEndian.AppendUint32(preimage, tx.Version)
+	preimage = binary.LittleEndian.AppendUint32(preimage, tx.LockTime)
+	preimage = binary.LittleEndian.AppendUint32(
+		preimage, uint32(len(tx.TxIn)),
+	)
+	preimage = binary.LittleEndian.AppendUint32(
+		preimage, uint32(len(tx.TxOut)),
+	)
+	for _, layer := range [][]byte{inputs, scripts, outputs} {
+		layerHash := sha256.Sum256(layer)
+		preimage = append(preimage, layerHash[:]...)
+	}
+
+	return preimage
+}
+
+// refV10TxID returns the double sha256 of refV10Preimage.
+func refV10TxID(tx *Transaction) []byte {
+	first := sha256.Sum256(refV10Preimage(tx))
+	second := sha256.Sum256(first[:])
+	return second[:]
+}
+
+// TestCalculateV10TxID ensures the layered txid matches an independent
+// construction of the documented preimage and that VerifyV10TxID agrees.
+func TestCalculateV10TxID(t *testing.T) {
+	t.Parallel()
+
+	tx := v10TestTx()
+	require.Len(t, refV10Preimage(tx), 112)
+
+	want := refV10TxID(tx)
+	got := CalculateV10TxID(tx)
+	require.Equal(t, want, got)
+	require.Equal(t, got, CalculateTxID(nil, tx))
+	require.True(t, VerifyV10TxID(tx, want))
+
+	tx.TxIn[0].Sequence--
+	require.False(t, VerifyV10TxID(tx, want))
+}
+
+// TestCalculateTxIDCountMismatch ensures the layered txid commits to the number
+// of entries rather than the TxInCount and TxOutCount fields and that the
+// checked variant rejects fields which disagree in either direction.
+func TestCalculateTxIDCountMismatch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		mutate func(tx *Transaction)
+	}{{
+		name:   "input count too high",
+		mutate: func(tx *Transaction) { tx.TxInCount = 1 << 20 },
+	}, {
+		name:   "input count too low",
+		mutate: func(tx *Transaction) { tx.TxInCount-- },
+	}, {
+		name:   "output count too high",
+		mutate: func(tx *Transaction) { tx.TxOutCount++ },
+	}, {
+		name:   "output count too low",
+		mutate: func(tx *Transaction) { tx.TxOutCount = 0 },
+	}}
+
+	for _, test := range tests {
+		tx := largeV10TestTx(3, 2)
+		want := CalculateTxID(nil, tx)
+		test.mutate(tx)
+		require.Equal(t, want, CalculateTxID(nil, tx), test.name)
+
+		id, err := CalculateTxIDErr(nil, tx)
+		require.Nil(t, id, test.name)
+		require.ErrorIs(t, err, ErrCountMismatch, test.name)
+		require.ErrorIs(t, err, ErrInvalidTxForHashing, test.name)
+
+		var txErr *TxHashError
+		require.ErrorAs(t, err, &txErr, test.name)
+		require.Equal(t, -1, txErr.Index, test.name)
+	}
+}
+
+// TestCalculateTxIDNilRawV10 ensures the raw bytes are ignored for version 10
+// transactions so callers need not serialize them just to compute the txid.
+func TestCalculateTxIDNilRawV10(t *testing.T) {
+	t.Parallel()
+
+	tx := v10TestTx()
+	want := CalculateTxID(mustBytes(t, tx), tx)
+	require.Equal(t, want, CalculateTxID(nil, tx))
+	require.Equal(t, want, CalculateTxID([]byte{0xff}, tx))
+
+	got, err := CalculateTxIDErr(nil, tx)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+}
+
+// TestCalculateTxIDBadPrevoutHashLength ensures version 10 transactions with a
+// previous outpoint hash which is not 32 bytes are rejected rather than hashed
+// and that the checked variants report the offending input.
+func TestCalculateTxIDBadPrevoutHashLength(t *testing.T) {
+	t.Parallel()
+
+	for _, length := range []int{0, 31, 33} {
+		tx := v10TestTx()
+		tx.TxIn[1].Hash = make([]byte, length)
+
+		require.Nil(t, CalculateV10TxID(tx), "length %d", length)
+		require.Nil(t, CalculateTxID(nil, tx), "length %d", length)
+		require.Nil(t, CalculateTxIDWith(nil, tx, Sha256HashFunc),
+			"length %d", length)
+		require.Nil(t, CalculateV10TxIDWithOptions(tx, V10Options{
+			CountEncoding: V10CountVarInt,
+		}), "length %d", length)
+		require.False(t, VerifyV10TxID(tx, refV10TxID(tx)),
+			"length %d", length)
+
+		_, err := CalculateTxIDErr(nil, tx)
+		require.ErrorIs(t, err, ErrBadPrevoutHashLength,
+			"length %d", length)
+		require.ErrorIs(t, err, ErrInvalidTxForHashing,
+			"length %d", length)
+		require.ErrorContains(t, err, "input 1", "length %d", length)
+
+		_, err = ConvertCommonTransactionToWireMsgTx(tx)
+		require.ErrorIs(t, err, ErrBadPrevoutHashLength,
+			"length %d", length)
+
+		// The layers are still computed for diagnosis.
+		inputsHash, _, _ := CalculateV10Layers(tx)
+		require.NotEqual(t, [32]byte{}, inputsHash, "length %d", length)
+	}
+}
+
+// TestCalculateTxIDNilTx ensures the unchecked txid functions return nil for a
+// nil transaction rather than panic, while the checked variant reports it.
+func TestCalculateTxIDNilTx(t *testing.T) {
+	t.Parallel()
+
+	raw := []byte{0x01, 0x00, 0x00, 0x00}
+	require.Nil(t, CalculateTxID(raw, nil))
+	require.Nil(t, CalculateTxIDUnsigned(nil))
+	require.Nil(t, CalculateTxIDWith(raw, nil, nil))
+	require.Nil(t, CalculateWitnessTxID(nil))
+	internal, display := CalculateTxIDBoth(raw, nil)
+	require.Nil(t, internal)
+	require.Nil(t, display)
+	require.Equal(t, TxIDResult{}, CalculateTxIDResult(raw, nil))
+
+	_, err := CalculateTxIDErr(raw, nil)
+	require.ErrorIs(t, err, ErrNilTx)
+}
+
+// TestCalculateV10TxIDNilEntries ensures a nil transaction, input, or output
+// makes the layered txid functions return nil rather than panic.
+func TestCalculateV10TxIDNilEntries(t *testing.T) {
+	t.Parallel()
+
+	nilInput := v10TestTx()
+	nilInput.TxIn[1] = nil
+	nilOutput := v10TestTx()
+	nilOutput.TxOut[0] = nil
+
+	for _, tx := range []*Transaction{nil, nilInput, nilOutput} {
+		require.Nil(t, CalculateV10TxID(tx))
+		require.Nil(t, CalculateV10TxIDWithOptions(tx, V10Options{
+			CountEncoding: V10CountVarInt,
+		}))
+	}
+	require.Nil(t, CalculateTxID(nil, nilInput))
+	require.Nil(t, CalculateTxIDWith(nil, nilInput, Sha256HashFunc))
+	require.Nil(t, CalculateTxIDWith(nil, nilOutput, Sha256HashFunc))
+
+	err := checkPrevoutHashes("op", nilInput)
+	require.ErrorIs(t, err, ErrNilTx)
+	var txErr *TxHashError
+	require.ErrorAs(t, err, &txErr)
+	require.Equal(t, 1, txErr.Index)
+	require.ErrorIs(t, checkPrevoutHashes("op", nil), ErrNilTx)
+}
+
+// TestCalculateV10Layers ensures the layer hashes match those committed to by
+// the preimage, that each section of the transaction only affects its own
+// layer, and that nil entries are rejected.
+func TestCalculateV10Layers(t *testing.T) {
+	t.Parallel()
+
+	tx := v10TestTx()
+	preimage := refV10Preimage(tx)
+	inputs, scripts, outputs := CalculateV10Layers(tx)
+	require.Equal(t, preimage[16:48], inputs[:])
+	require.Equal(t, preimage[48:80], scripts[:])
+	require.Equal(t, preimage[80:112], outputs[:])
+
+	tests := []struct {
+		name   string
+		mutate func(tx *Transaction)
+		want   [3]bool
+	}{{
+		name:   "sequence",
+		mutate: func(tx *Transaction) { tx.TxIn[0].Sequence++ },
+		want:   [3]bool{true, false, false},
+	}, {
+		name: "signature script",
+		mutate: func(tx *Transaction) {
+			tx.TxIn[1].SignatureScript = []byte{0x51}
+		},
+		want: [3]bool{false, true, false},
+	}, {
+		name:   "value",
+		mutate: func(tx *Transaction) { tx.TxOut[0].Value++ },
+		want:   [3]bool{false, false, true},
+	}, {
+		name:   "locktime",
+		mutate: func(tx *Transaction) { tx.LockTime++ },
+		want:   [3]bool{false, false, false},
+	}}
+
+	for _, test := range tests {
+		tx := v10TestTx()
+		test.mutate(tx)
+
+		i, s, o := CalculateV10Layers(tx)
+		got := [3]bool{i != inputs, s != scripts, o != outputs}
+		require.Equal(t, test.want, got, test.name)
+	}
+
+	// A nil transaction, input, or output produces zero layers rather
+	// than a panic.
+	nilInput := v10TestTx()
+	nilInput.TxIn[0] = nil
+	nilOutput := v10TestTx()
+	nilOutput.TxOut[0] = nil
+	for _, tx := range []*Transaction{nil, nilInput, nilOutput} {
+		i, s, o := CalculateV10Layers(tx)
+		require.Equal(t, [3][32]byte{}, [3][32]byte{i, s, o})
+	}
+}
+
+// TestV10FinalPreimage ensures the exposed preimage has the documented layout
+// and is the data double hashed into the layered txid.
+func TestV10FinalPreimage(t *testing.T) {
+	t.Parallel()
+
+	for _, tx := range []*Transaction{
+		v10TestTx(), largeV10TestTx(30, 20), largeV10TestTx(0, 0),
+	} {
+		preimage := V10FinalPreimage(tx)
+		require.Len(t, preimage, 4+4+4+4+32*3)
+		require.Equal(t, refV10Preimage(tx), preimage)
+		require.Equal(t, CalculateV10TxID(tx), DoubleSha256(preimage))
+
+		inputs, scripts, outputs := CalculateV10Layers(tx)
+		require.Equal(t, inputs[:], preimage[16:48])
+		require.Equal(t, scripts[:], preimage[48:80])
+		require.Equal(t, outputs[:], preimage[80:112])
+	}
+
+	// The previous outpoint hashes are committed to in internal byte
+	// order regardless of the HashOrder.
+	tx := v10TestTx()
+	msgTx, err := ConvertCommonTransactionToWireMsgTx(tx)
+	require.NoError(t, err)
+	be := ConvertWireMsgTxToCommonTransactionBE(msgTx)
+	require.Equal(t, V10FinalPreimage(tx), V10FinalPreimage(be))
+
+	// A nil transaction, input, or output produces no preimage rather
+	// than a panic.
+	nilInput := v10TestTx()
+	nilInput.TxIn[1] = nil
+	nilOutput := v10TestTx()
+	nilOutput.TxOut[0] = nil
+	for _, tx := range []*Transaction{nil, nilInput, nilOutput} {
+		require.Nil(t, V10FinalPreimage(tx))
+	}
+}
+
+// TestCombineV10Layers ensures combining the layers of a transaction produces
+// its layered txid over the documented preimage.
+func TestCombineV10Layers(t *testing.T) {
+	t.Parallel()
+
+	for _, tx := range []*Transaction{
+		v10TestTx(), v10TestCoinbase(), largeV10TestTx(20, 30),
+		{Version: 10},
+	} {
+		inputs, scripts, outputs := CalculateV10Layers(tx)
+		got := CombineV10Layers(tx.Version, tx.LockTime,
+			uint32(len(tx.TxIn)), uint32(len(tx.TxOut)),
+			inputs, scripts, outputs)
+		require.Equal(t, CalculateV10TxID(tx), got)
+		require.Equal(t, DoubleSha256(refV10Preimage(tx)), got)
+	}
+
+	// Every field is committed to.
+	var layer [32]byte
+	want := CombineV10Layers(10, 1, 2, 3, layer, layer, layer)
+	for i, got := range [][]byte{
+		CombineV10Layers(11, 1, 2, 3, layer, layer, layer),
+		CombineV10Layers(10, 2, 2, 3, layer, layer, layer),
+		CombineV10Layers(10, 1, 3, 3, layer, layer, layer),
+		CombineV10Layers(10, 1, 2, 4, layer, layer, layer),
+		CombineV10Layers(10, 1, 2, 3, [32]byte{1}, layer, layer),
+		CombineV10Layers(10, 1, 2, 3, layer, [32]byte{1}, layer),
+		CombineV10Layers(10, 1, 2, 3, layer, layer, [32]byte{1}),
+	} {
+		require.NotEqual(t, want, got, i)
+	}
+}
+
+// TestCalculateV10TxIDWithOptions ensures the default options reproduce the
+// layered txid and that the varint count encoding only changes the counts in
+// the preimage.
+func TestCalculateV10TxIDWithOptions(t *testing.T) {
+	t.Parallel()
+
+	for _, tx := range []*Transaction{
+		v10TestTx(), v10TestCoinbase(), largeV10TestTx(300, 2),
+		{Version: 10},
+	} {
+		want := CalculateV10TxID(tx)
+		require.Equal(t, want, CalculateV10TxIDWithOptions(tx,
+			V10Options{}))
+		require.Equal(t, want, CalculateV10TxIDWithOptions(tx,
+			V10Options{CountEncoding: V10CountFixed32}))
+
+		var preimage bytes.Buffer
+		ref := refV10Preimage(tx)
+		preimage.Write(ref[:8])
+		require.NoError(t, WriteVarInt(&preimage, 0,
+			uint64(len(tx.TxIn))))
+		require.NoError(t, WriteVarInt(&preimage, 0,
+			uint64(len(tx.TxOut))))
+		preimage.Write(ref[16:])
+
+		got := CalculateV10TxIDWithOptions(tx,
+			V10Options{CountEncoding: V10CountVarInt})
+		require.Equal(t, DoubleSha256(preimage.Bytes()), got)
+		require.NotEqual(t, want, got)
+	}
+
+	require.Nil(t, CalculateV10TxIDWithOptions(v10TestTx(),
+		V10Options{CountEncoding: V10CountVarInt + 1}))
+}
+
+// TestCalculateOutputsCommitment ensures the outputs commitment covers the
+// version, locktime, and outputs of a transaction but none of its inputs, and
+// that nil entries are rejected.
+func TestCalculateOutputsCommitment(t *testing.T) {
+	t.Parallel()
+
+	tx := v10TestTx()
+	_, _, outputsHash := CalculateV10Layers(tx)
+	preimage := binary.LittleEndian.AppendUint32(nil, tx.Version)
+	preimage = binary.LittleEndian.AppendUint32(preimage, tx.LockTime)
+	preimage = binary.LittleEndian.AppendUint32(preimage,
+		uint32(len(tx.TxOut)))
+	preimage = append(preimage, outputsHash[:]...)
+	want := sha256.Sum256(preimage)
+	require.Equal(t, want[:], CalculateOutputsCommitment(tx))
+
+	tests := []struct {
+		name       string
+		mutate     func(tx *Transaction)
+		wantChange bool
+	}{{
+		name: "signature script",
+		mutate: func(tx *Transaction) {
+			tx.TxIn[0].SignatureScript = []byte{0x51}
+		},
+	}, {
+		name:   "input removed",
+		mutate: func(tx *Transaction) { tx.TxIn = tx.TxIn[:1] },
+	}, {
+		name:       "version",
+		mutate:     func(tx *Transaction) { tx.Version++ },
+		wantChange: true,
+	}, {
+		name:       "locktime",
+		mutate:     func(tx *Transaction) { tx.LockTime++ },
+		wantChange: true,
+	}, {
+		name:       "value",
+		mutate:     func(tx *Transaction) { tx.TxOut[0].Value++ },
+		wantChange: true,
+	}, {
+		name: "public key script",
+		mutate: func(tx *Transaction) {
+			tx.TxOut[0].PkScript.Pkscript = []byte{0x6a}
+		},
+		wantChange: true,
+	}, {
+		name: "output added",
+		mutate: func(tx *Transaction) {
+			tx.TxOut = append(tx.TxOut, &TxOutput{})
+		},
+		wantChange: true,
+	}}
+
+	for _, test := range tests {
+		tx := v10TestTx()
+		test.mutate(tx)
+		got := CalculateOutputsCommitment(tx)
+		require.Equal(t, test.wantChange, !bytes.Equal(want[:], got),
+			test.name)
+	}
+
+	// A nil transaction or output produces no commitment rather than a
+	// panic, while a nil input is not committed to.
+	nilOutput := v10TestTx()
+	nilOutput.TxOut[0] = nil
+	require.Nil(t, CalculateOutputsCommitment(nil))
+	require.Nil(t, CalculateOutputsCommitment(nilOutput))
+
+	nilInput := v10TestTx()
+	nilInput.TxIn[0] = nil
+	require.Equal(t, want[:], CalculateOutputsCommitment(nilInput))
+}
+
+// TestTxInputScriptHash ensures the per-input script hashes make up the
+// scripts layer of the layered txid.
+func TestTxInputScriptHash(t *testing.T) {
+	t.Parallel()
+
+	tx := largeV10TestTx(5, 1)
+	var scripts []byte
+	for _, in := range tx.TxIn {
+		hash := in.ScriptHash()
+		require.Equal(t, sha256.Sum256(in.SignatureScript), hash)
+		scripts = append(scripts, hash[:]...)
+	}
+
+	_, want, _ := CalculateV10Layers(tx)
+	require.Equal(t, want, sha256.Sum256(scripts))
+}
+
+// TestTxOutputHash ensures the output hash is over the same entry the outputs
+// layer of the version 10 txid commits to.
+func TestTxOutputHash(t *testing.T) {
+	t.Parallel()
+
+	tx := largeV10TestTx(1, 5)
+	tx.TxOut[2].Value = math.MaxUint64
+	var outputs []byte
+	for _, out := range tx.TxOut {
+		scriptHash := sha256.Sum256(out.PkScript.Pkscript)
+		entry := binary.LittleEndian.AppendUint64(nil, out.Value)
+		entry = append(entry, scriptHash[:]...)
+		require.Equal(t, sha256.Sum256(entry), out.Hash())
+		outputs = append(outputs, entry...)
+	}
+
+	_, _, want := CalculateV10Layers(tx)
+	require.Equal(t, want, sha256.Sum256(outputs))
+
+	// Each field of the output changes its hash.
+	out := *tx.TxOut[1]
+	hash := out.Hash()
+	out.Value++
+	require.NotEqual(t, hash, out.Hash())
+	out.Value--
+	out.PkScript.Pkscript = append(out.PkScript.Pkscript, 0x00)
+	require.NotEqual(t, hash, out.Hash())
+}
+
+// TestConvertWireMsgTxToCommonTransaction ensures all fields of a MsgTx,
+// including witness stacks, are carried over by the conversion.
+func TestConvertWireMsgTxToCommonTransaction(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		msgTx       *MsgTx
+		wantWitness bool
+	}{
+		{name: "no witness", msgTx: multiTx, wantWitness: false},
+		{name: "witness", msgTx: multiWitnessTx, wantWitness: true},
+	}
+
+	for _, test := range tests {
+		tx := ConvertWireMsgTxToCommonTransaction(test.msgTx)
+
+		require.Equal(t, uint32(test.msgTx.Version), tx.Version,
+			test.name)
+		require.Equal(t, test.msgTx.LockTime, tx.LockTime, test.name)
+		require.Equal(t, uint(len(test.msgTx.TxIn)), tx.TxInCount,
+			test.name)
+		require.Equal(t, uint(len(test.msgTx.TxOut)), tx.TxOutCount,
+			test.name)
+		require.Equal(t, test.wantWitness, tx.HasWitness(), test.name)
+
+		for i, txIn := range test.msgTx.TxIn {
+			in := tx.TxIn[i]
+			prevOut := txIn.PreviousOutPoint
+			require.Equal(t, prevOut.Hash[:], in.Hash, test.name)
+			require.Equal(t, prevOut.Index, in.Index, test.name)
+			require.Equal(t, txIn.SignatureScript,
+				in.SignatureScript, test.name)
+			require.Equal(t, txIn.Sequence, in.Sequence, test.name)
+			require.Equal(t, [][]byte(txIn.Witness), in.Witness,
+				test.name)
+		}
+		for i, txOut := range test.msgTx.TxOut {
+			out := tx.TxOut[i]
+			require.Equal(t, uint64(txOut.Value), out.Value,
+				test.name)
+			require.Equal(t, txOut.PkScript, out.PkScript.Pkscript,
+				test.name)
+		}
+	}
+}
+
+// TestConvertWireMsgTxToCommonTransactionBE ensures the display order
+// conversion reverses the previous outpoint hashes without affecting the
+// MsgTx, and that the txid, wtxid, version 10 layers, and serialization
+// match those of the internal order conversion.
+func TestConvertWireMsgTxToCommonTransactionBE(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		msgTx *MsgTx
+	}{
+		{name: "standard", msgTx: multiTx},
+		{name: "standard witness", msgTx: multiWitnessTx},
+		{name: "v10", msgTx: v10TestMsgTx(t, false)},
+		{name: "v10 witness", msgTx: v10TestMsgTx(t, true)},
+	}
+
+	for _, test := range tests {
+		internal := ConvertWireMsgTxToCommonTransaction(test.msgTx)
+		require.Equal(t, HashOrderInternal, internal.HashOrder)
+		require.Same(t, internal, internal.InternalOrder(), test.name)
+
+		display := ConvertWireMsgTxToCommonTransactionBE(test.msgTx)
+		require.Equal(t, HashOrderDisplay, display.HashOrder, test.name)
+		for i, txIn := range test.msgTx.TxIn {
+			hash := txIn.PreviousOutPoint.Hash
+			require.Equal(t, ReverseBytes(hash[:]),
+				display.TxIn[i].Hash, test.name)
+			require.Equal(t, hash.String(),
+				hex.EncodeToString(display.TxIn[i].Hash),
+				test.name)
+		}
+
+		// Converting back to internal order must not modify the
+		// display order transaction.
+		converted := display.InternalOrder()
+		require.Equal(t, HashOrderInternal, converted.HashOrder,
+			test.name)
+		for i, txIn := range internal.TxIn {
+			require.Equal(t, txIn.Hash, converted.TxIn[i].Hash,
+				test.name)
+			require.Equal(t, ReverseBytes(txIn.Hash),
+				display.TxIn[i].Hash, test.name)
+		}
+		require.True(t, display.Equal(internal), test.name)
+
+		want := test.msgTx.TxHash()
+		raw, err := display.Bytes()
+		require.NoError(t, err, test.name)
+		wantRaw, err := internal.Bytes()
+		require.NoError(t, err, test.name)
+		require.Equal(t, wantRaw, raw, test.name)
+
+		require.Equal(t, want[:], CalculateTxID(raw, display),
+			test.name)
+		require.Equal(t, want[:], display.TxID(), test.name)
+		require.Equal(t, CalculateWitnessTxID(internal),
+			CalculateWitnessTxID(display), test.name)
+		require.Equal(t, CalculateV10TxID(internal),
+			CalculateV10TxID(display), test.name)
+		require.Equal(t,
+			CalculateTxIDWith(raw, internal, Sha256HashFunc),
+			CalculateTxIDWith(raw, display, Sha256HashFunc),
+			test.name)
+
+		msgTx, err := ConvertCommonTransactionToWireMsgTx(display)
+		require.NoError(t, err, test.name)
+		require.Equal(t, test.msgTx.TxHash(), msgTx.TxHash(), test.name)
+	}
+}
+
+// TestConvertCommonTransactionToWireMsgTx ensures converting a MsgTx to a
+// Transaction and back yields a byte-identical serialization, and that values
+// which can't be represented on the wire are rejected.
+func TestConvertCommonTransactionToWireMsgTx(t *testing.T) {
+	t.Parallel()
+
+	for _, msgTx := range []*MsgTx{multiTx, multiWitnessTx} {
+		var want bytes.Buffer
+		require.NoError(t, msgTx.Serialize(&want))
+
+		tx := ConvertWireMsgTxToCommonTransaction(msgTx)
+		roundTripped, err := ConvertCommonTransactionToWireMsgTx(tx)
+		require.NoError(t, err)
+
+		var got bytes.Buffer
+		require.NoError(t, roundTripped.Serialize(&got))
+		require.Equal(t, want.Bytes(), got.Bytes())
+		require.Equal(t, msgTx.TxHash(), roundTripped.TxHash())
+	}
+
+	_, err := ConvertCommonTransactionToWireMsgTx(nil)
+	require.Error(t, err)
+
+	tx := v10TestTx()
+	tx.TxOut[0].Value = 1 << 63
+	_, err = ConvertCommonTransactionToWireMsgTx(tx)
+	require.Error(t, err)
+
+	tx = v10TestTx()
+	tx.TxIn[1].Hash = tx.TxIn[1].Hash[1:]
+	_, err = ConvertCommonTransactionToWireMsgTx(tx)
+	require.Error(t, err)
+}
+
+// TestReverseBytes ensures both byte reversal helpers produce the expected
+// result for a range of lengths and that ReverseBytes does not modify its
+// input.
+func TestReverseBytes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		in   []byte
+		want []byte
+	}{
+		{name: "nil", in: nil, want: []byte{}},
+		{name: "empty", in: []byte{}, want: []byte{}},
+		{name: "single", in: []byte{0x01}, want: []byte{0x01}},
+		{
+			name: "even",
+			in:   []byte{0x01, 0x02, 0x03, 0x04},
+			want: []byte{0x04, 0x03, 0x02, 0x01},
+		},
+		{
+			name: "odd",
+			in:   []byte{0x01, 0x02, 0x03},
+			want: []byte{0x03, 0x02, 0x01},
+		},
+	}
+
+	for _, test := range tests {
+		orig := append([]byte(nil), test.in...)
+		require.Equal(t, test.want, ReverseBytes(test.in), test.name)
+		require.True(t, bytes.Equal(orig, test.in), test.name)
+
+		inPlace := append([]byte{}, test.in...)
+		ReverseBytesInPlace(inPlace)
+		require.Equal(t, test.want, inPlace, test.name)
+	}
+}
+
+// TestDoubleSha256Into ensures doubleSha256Into agrees with doubleSha256 and
+// only writes the first 32 bytes of the destination.
+func TestDoubleSha256Into(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("The Times 03/Jan/2009 Chancellor on brink")
+	want := doubleSha256(data)
+
+	dst := bytes.Repeat([]byte{0xff}, 40)
+	got := doubleSha256Into(dst, data)
+	require.Equal(t, want, got)
+	require.Equal(t, want, dst[:32])
+	require.Equal(t, bytes.Repeat([]byte{0xff}, 8), dst[32:])
+}
+
+// BenchmarkDoubleSha256 benchmarks doubleSha256 on a typical transaction
+// sized input.
+func BenchmarkDoubleSha256(b *testing.B) {
+	data := bytes.Repeat([]byte{0x01}, 250)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		hashSink = doubleSha256(data)
+	}
+}
+
+// BenchmarkDoubleSha256Into benchmarks doubleSha256Into on a typical
+// transaction sized input while reusing a single scratch buffer.
+func BenchmarkDoubleSha256Into(b *testing.B) {
+	data := bytes.Repeat([]byte{0x01}, 250)
+	var dst [32]byte
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		hashSink = doubleSha256Into(dst[:], data)
+	}
+}
+
+// TestDoubleSha256 ensures the exported digest reproduces the well known hashes
+// of the main network genesis block and its coinbase transaction.
+func TestDoubleSha256(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, genesisCoinbaseTx.Serialize(&buf))
+	require.Equal(t, mainNetGenesisMerkleRoot[:], DoubleSha256(buf.Bytes()))
+
+	header := NewBlockHeader(1, &chainhash.Hash{},
+		&mainNetGenesisMerkleRoot, 0x1d00ffff, 0x7c2bac1d)
+	header.Timestamp = time.Unix(0x495fab29, 0)
+	buf.Reset()
+	require.NoError(t, header.Serialize(&buf))
+	require.Equal(t, mainNetGenesisHash[:], DoubleSha256(buf.Bytes()))
+	require.Equal(t, "000000000019d6689c085ae165831e934ff763ae46a2a6c1"+
+		"72b3f1b60a8ce26f", TxIDString(DoubleSha256(buf.Bytes())))
+
+	require.Equal(t, DoubleSha256(nil), doubleSha256(nil))
+}
+
+// txIDBenchSizes are the representative transaction shapes used to compare
+// the throughput of the standard and layered txids.
+var txIDBenchSizes = []struct {
+	name          string
+	numIn, numOut int
+}{
+	{name: "1-in-2-out", numIn: 1, numOut: 2},
+	{name: "100-in-100-out", numIn: 100, numOut: 100},
+}
+
+// BenchmarkCalculateTxID_Standard benchmarks the double sha256 txid of
+// standard transactions.  The raw bytes are serialized up front since callers
+// of CalculateTxID already have them.
+func BenchmarkCalculateTxID_Standard(b *testing.B) {
+	for _, size := range txIDBenchSizes {
+		tx := largeV10TestTx(size.numIn, size.numOut)
+		tx.Version = 1
+		raw, err := tx.Bytes()
+		require.NoError(b, err)
+
+		b.Run(size.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				hashSink = CalculateTxID(raw, tx)
+			}
+		})
+	}
+}
+
+// BenchmarkCalculateTxIDInto benchmarks CalculateTxIDInto on the same
+// standard transactions as BenchmarkCalculateTxID_Standard, which must not
+// allocate.
+func BenchmarkCalculateTxIDInto(b *testing.B) {
+	for _, size := range txIDBenchSizes {
+		tx := largeV10TestTx(size.numIn, size.numOut)
+		tx.Version = 1
+		raw, err := tx.Bytes()
+		require.NoError(b, err)
+		dst := make([]byte, chainhash.HashSize)
+
+		b.Run(size.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_ = CalculateTxIDInto(dst, raw, tx)
+			}
+		})
+	}
+}
+
+// BenchmarkCalculateTxID_V10 benchmarks the layered txid of version 10
+// transactions of the same shapes as BenchmarkCalculateTxID_Standard.
+func BenchmarkCalculateTxID_V10(b *testing.B) {
+	for _, size := range txIDBenchSizes {
+		tx := largeV10TestTx(size.numIn, size.numOut)
+
+		b.Run(size.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				hashSink = CalculateTxID(nil, tx)
+			}
+		})
+	}
+}
+
+// BenchmarkCalculateTxIDParallel benchmarks computing layered txids on many
+// goroutines at once.  The unpooled variant allocates a new hasher for every
+// transaction, as CalculateTxID did before hashers were pooled, for
+// comparison of the allocations.
+func BenchmarkCalculateTxIDParallel(b *testing.B) {
+	for _, size := range txIDBenchSizes {
+		tx := largeV10TestTx(size.numIn, size.numOut)
+
+		b.Run(size.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					_ = CalculateTxID(nil, tx)
+				}
+			})
+		})
+
+		b.Run(size.name+"-unpooled", func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					_ = calcV10TxID(tx, NewTxIDHasher())
+				}
+			})
+		})
+	}
+}
+
+// TestTransactionTxID ensures the cached txid matches the computed txid for
+// both hashing schemes, is only refreshed once invalidated, is safe to access
+// concurrently, and is carried over to copies made by value.
+func TestTransactionTxID(t *testing.T) {
+	t.Parallel()
+
+	var raw bytes.Buffer
+	require.NoError(t, multiTx.SerializeNoWitness(&raw))
+
+	standardTx := ConvertWireMsgTxToCommonTransaction(multiTx)
+	require.Equal(t, CalculateTxID(raw.Bytes(), standardTx),
+		standardTx.TxID())
+
+	tx := v10TestTx()
+	want := CalculateTxID(nil, tx)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.Equal(t, want, tx.TxID())
+		}()
+	}
+	wg.Wait()
+
+	// Modifying the returned txid must not affect the cache.
+	tx.TxID()[0] ^= 0xff
+	require.Equal(t, want, tx.TxID())
+
+	// The cached txid is returned until it is invalidated.
+	tx.LockTime++
+	require.Equal(t, want, tx.TxID())
+	tx.InvalidateTxID()
+	require.Equal(t, CalculateTxID(nil, tx), tx.TxID())
+	require.NotEqual(t, want, tx.TxID())
+
+	// A copy made by value starts with the cached txid of the original
+	// and keeps its own cache once invalidated.
+	cached := tx.TxID()
+	txCopy := *tx
+	require.Equal(t, cached, txCopy.TxID())
+	txCopy.LockTime++
+	txCopy.InvalidateTxID()
+	require.Equal(t, CalculateTxID(nil, &txCopy), txCopy.TxID())
+	require.NotEqual(t, cached, txCopy.TxID())
+	require.Equal(t, cached, tx.TxID())
+
+	// Transactions which can't be serialized have no txid.
+	tx = v10TestTx()
+	tx.Version = 1
+	tx.TxIn[0].Hash = nil
+	require.Nil(t, tx.TxID())
+}
+
+// TestTxInputDisplayHash ensures previous outpoint hashes convert between the
+// display and internal byte orders consistently with chainhash.
+func TestTxInputDisplayHash(t *testing.T) {
+	t.Parallel()
+
+	const display = "3ba27aa200b1cecaad478d2b00432346" +
+		"c3f1f3986da1afd33e506c4f3e49e06e"
+	want, err := chainhash.NewHashFromStr(display)
+	require.NoError(t, err)
+
+	hash, err := ParseTxInputHash(display)
+	require.NoError(t, err)
+	require.Equal(t, want[:], hash)
+
+	in := &TxInput{Hash: hash}
+	require.Equal(t, display, in.DisplayHash())
+	require.Equal(t, want.String(), in.DisplayHash())
+
+	invalid := []string{
+		"",
+		display[:62],
+		display + "00",
+		"zz" + display[2:],
+	}
+	for _, str := range invalid {
+		_, err := ParseTxInputHash(str)
+		require.Error(t, err, str)
+	}
+}
+
+// TestTxInputOutPoint ensures the outpoint of an input is formatted and
+// compared in the same way as a wire outpoint.
+func TestTxInputOutPoint(t *testing.T) {
+	t.Parallel()
+
+	tx := ConvertWireMsgTxToCommonTransaction(multiTx)
+	seen := make(map[string]struct{})
+	for i, in := range tx.TxIn {
+		op := in.OutPoint()
+		want := multiTx.TxIn[i].PreviousOutPoint
+		require.Equal(t, want.Hash[:], op.Hash)
+		require.Equal(t, want.Index, op.Index)
+		require.Equal(t, want.String(), op.String())
+		require.True(t, op.Equal(op))
+
+		seen[op.String()] = struct{}{}
+	}
+	require.Len(t, seen, len(tx.TxIn))
+
+	op := OutPointRef{Hash: bytes.Repeat([]byte{0x01}, 32), Index: 7}
+	require.True(t, op.Equal(OutPointRef{
+		Hash:  bytes.Repeat([]byte{0x01}, 32),
+		Index: 7,
+	}))
+	require.False(t, op.Equal(OutPointRef{Hash: op.Hash, Index: 8}))
+	require.False(t, op.Equal(OutPointRef{Hash: op.Hash[:31], Index: 7}))
+
+	coinbase := v10TestCoinbase().TxIn[0].OutPoint()
+	require.Equal(t, strings.Repeat("0", 64)+":4294967295",
+		coinbase.String())
+}
+
+// TestConvertWireMsgTxToCommonTransactionErr ensures output values which are
+// negative or exceed the maximum allowed value are rejected by the checked
+// conversion.
+func TestConvertWireMsgTxToCommonTransactionErr(t *testing.T) {
+	t.Parallel()
+
+	maxValue := int64(MaxTxOutputValue)
+	tests := []struct {
+		name    string
+		values  []int64
+		wantErr error
+		index   int
+	}{
+		{name: "no outputs", values: nil},
+		{name: "zero", values: []int64{0}},
+		{name: "max", values: []int64{maxValue}},
+		{name: "max total", values: []int64{maxValue - 1, 1}},
+		{
+			name:    "negative",
+			values:  []int64{1, -1},
+			wantErr: ErrNegativeValue,
+			index:   1,
+		},
+		{
+			name:    "min int64",
+			values:  []int64{-1 << 63},
+			wantErr: ErrNegativeValue,
+		},
+		{
+			name:    "above max",
+			values:  []int64{maxValue + 1},
+			wantErr: ErrValueTooLarge,
+		},
+		{
+			name:    "total above max",
+			values:  []int64{maxValue, 1},
+			wantErr: ErrValueTooLarge,
+			index:   1,
+		},
+	}
+
+	for _, test := range tests {
+		msgTx := NewMsgTx(1)
+		for _, value := range test.values {
+			msgTx.AddTxOut(NewTxOut(value, nil))
+		}
+
+		tx, err := ConvertWireMsgTxToCommonTransactionErr(msgTx)
+		if test.wantErr != nil {
+			require.ErrorIs(t, err, test.wantErr, test.name)
+			var txErr *TxHashError
+			require.True(t, errors.As(err, &txErr), test.name)
+			require.Equal(t, test.index, txErr.Index, test.name)
+			continue
+		}
+		require.NoError(t, err, test.name)
+		want := ConvertWireMsgTxToCommonTransaction(msgTx)
+		require.Equal(t, want.TxOut, tx.TxOut, test.name)
+	}
+
+	_, err := ConvertWireMsgTxToCommonTransactionErr(nil)
+	require.Error(t, err)
+}
+
+// TestTxIDEqual ensures txids only compare equal when both are exactly 32
+// bytes with the same contents.
+func TestTxIDEqual(t *testing.T) {
+	t.Parallel()
+
+	txid := refV10TxID(v10TestTx())
+	other := bytes.Clone(txid)
+	other[chainhash.HashSize-1] ^= 0x01
+	hash := chainhash.Hash(txid)
+
+	tests := []struct {
+		name string
+		a, b []byte
+		want bool
+	}{
+		{name: "equal", a: txid, b: bytes.Clone(txid), want: true},
+		{name: "chainhash", a: txid, b: hash[:], want: true},
+		{name: "differ", a: txid, b: other, want: false},
+		{
+			name: "reversed",
+			a:    txid,
+			b:    ReverseBytes(txid),
+			want: false,
+		},
+		{name: "both nil", a: nil, b: nil, want: false},
+		{name: "both empty", a: []byte{}, b: []byte{}, want: false},
+		{name: "short", a: txid[:31], b: txid[:31], want: false},
+		{name: "prefix", a: txid, b: txid[:31], want: false},
+		{
+			name: "long",
+			a:    append(bytes.Clone(txid), 0),
+			b:    append(bytes.Clone(txid), 0),
+			want: false,
+		},
+	}
+
+	for _, test := range tests {
+		got := TxIDEqual(test.a, test.b)
+		require.Equal(t, test.want, got, test.name)
+	}
+}
+
+// TestMatchesTxID ensures txids are matched in either byte order and that the
+// orientation they were given in is reported.
+func TestMatchesTxID(t *testing.T) {
+	t.Parallel()
+
+	tx := ConvertWireMsgTxToCommonTransaction(multiTx)
+	raw := mustBytes(t, tx)
+	hash := multiTx.TxHash()
+	display := hash.String()
+	internal := hex.EncodeToString(hash[:])
+
+	tests := []struct {
+		name            string
+		expected        string
+		wantMatched     bool
+		wantOrientation string
+	}{{
+		name:            "display",
+		expected:        display,
+		wantMatched:     true,
+		wantOrientation: TxIDOrientationDisplay,
+	}, {
+		name:            "internal",
+		expected:        internal,
+		wantMatched:     true,
+		wantOrientation: TxIDOrientationInternal,
+	}, {
+		name:            "upper case with whitespace",
+		expected:        " " + strings.ToUpper(display) + "\n",
+		wantMatched:     true,
+		wantOrientation: TxIDOrientationDisplay,
+	}, {
+		name:     "different txid",
+		expected: strings.Repeat("00", 32),
+	}, {
+		name:     "truncated",
+		expected: display[:62],
+	}, {
+		name:     "invalid hex",
+		expected: "zz" + display[2:],
+	}, {
+		name: "empty",
+	}}
+
+	for _, test := range tests {
+		matched, orientation := MatchesTxID(tx, raw, test.expected)
+		require.Equal(t, test.wantMatched, matched, test.name)
+		require.Equal(t, test.wantOrientation, orientation, test.name)
+	}
+
+	v10 := v10TestTx()
+	matched, orientation := MatchesTxID(v10, nil,
+		TxIDString(CalculateV10TxID(v10)))
+	require.True(t, matched)
+	require.Equal(t, TxIDOrientationDisplay, orientation)
+}
+
+// TestCalculateTxIDNoAliasing ensures CalculateTxID neither modifies nor
+// retains references to the caller's slices by building transactions whose
+// raw bytes, hashes, and scripts all share one buffer that is overwritten
+// between calls.
+func TestCalculateTxIDNoAliasing(t *testing.T) {
+	t.Parallel()
+
+	buf := make([]byte, 128)
+	for i := range buf {
+		buf[i] = byte(i)
+	}
+	snapshot := bytes.Clone(buf)
+
+	tx := &Transaction{
+		Version: 10,
+		TxIn: []*TxInput{{
+			Hash:            buf[0:32],
+			SignatureScript: buf[32:48],
+			Sequence:        0xffffffff,
+		}},
+		TxOut: []*TxOutput{{
+			Value:    1000,
+			PkScript: PkScript{Pkscript: buf[48:80]},
+		}},
+		TxInCount:  1,
+		TxOutCount: 1,
+	}
+	raw := buf[80:]
+
+	stdTx := &Transaction{
+		Version:    1,
+		TxIn:       tx.TxIn,
+		TxOut:      tx.TxOut,
+		TxInCount:  1,
+		TxOutCount: 1,
+	}
+
+	v10ID := CalculateTxID(nil, tx)
+	stdID := CalculateTxID(raw, stdTx)
+	require.Equal(t, snapshot, buf)
+	require.Equal(t, refV10TxID(tx), v10ID)
+	require.Equal(t, doubleSha256(snapshot[80:]), stdID)
+	v10Want := bytes.Clone(v10ID)
+	stdWant := bytes.Clone(stdID)
+
+	// Reuse the buffer as a pooled decoder would and compute the txids of
+	// the transactions it now describes.
+	for i := range buf {
+		buf[i] = ^buf[i]
+	}
+	v10Next := CalculateTxID(nil, tx)
+	stdNext := CalculateTxID(raw, stdTx)
+
+	require.Equal(t, v10Want, v10ID)
+	require.Equal(t, stdWant, stdID)
+	require.NotEqual(t, v10ID, v10Next)
+	require.NotEqual(t, stdID, stdNext)
+	require.Equal(t, refV10TxID(tx), v10Next)
+}
+
+// v10TestCoinbase returns a version 10 coinbase transaction.
+func v10TestCoinbase() *Transaction {
+	return &Transaction{
+		Version: 10,
+		TxIn: []*TxInput{{
+			Hash:            make([]byte, chainhash.HashSize),
+			Index:           MaxPrevOutIndex,
+			SignatureScript: []byte{0x03, 0x01, 0x02, 0x03, 0xab},
+			Sequence:        MaxTxInSequenceNum,
+		}},
+		TxOut: []*TxOutput{{
+			Value: 5000000000,
+			PkScript: PkScript{
+				Pkscript: []byte{opReturn},
+			},
+		}},
+		TxInCount:  1,
+		TxOutCount: 1,
+	}
+}
+
+// TestTransactionIsCoinbase ensures only transactions with a single input
+// spending the null outpoint are identified as coinbases.
+func TestTransactionIsCoinbase(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		mutate func(tx *Transaction)
+		want   bool
+	}{{
+		name:   "coinbase",
+		mutate: func(*Transaction) {},
+		want:   true,
+	}, {
+		name: "non v10 coinbase",
+		mutate: func(tx *Transaction) {
+			tx.Version = 1
+		},
+		want: true,
+	}, {
+		name: "nonzero hash",
+		mutate: func(tx *Transaction) {
+			tx.TxIn[0].Hash[31] = 0x01
+		},
+		want: false,
+	}, {
+		name: "short hash",
+		mutate: func(tx *Transaction) {
+			tx.TxIn[0].Hash = tx.TxIn[0].Hash[:31]
+		},
+		want: false,
+	}, {
+		name: "index not max",
+		mutate: func(tx *Transaction) {
+			tx.TxIn[0].Index = 0
+		},
+		want: false,
+	}, {
+		name: "two inputs",
+		mutate: func(tx *Transaction) {
+			tx.TxIn = append(tx.TxIn, tx.TxIn[0])
+		},
+		want: false,
+	}, {
+		name: "no inputs",
+		mutate: func(tx *Transaction) {
+			tx.TxIn = nil
+		},
+		want: false,
+	}, {
+		name: "nil input",
+		mutate: func(tx *Transaction) {
+			tx.TxIn[0] = nil
+		},
+		want: false,
+	}}
+
+	for _, test := range tests {
+		tx := v10TestCoinbase()
+		test.mutate(tx)
+		require.Equal(t, test.want, tx.IsCoinbase(), test.name)
+	}
+
+	require.False(t, v10TestTx().IsCoinbase())
+}
+
+// TestTransactionHasDuplicateInputs ensures inputs are only reported as
+// duplicates when they spend the same previous outpoint.
+func TestTransactionHasDuplicateInputs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		mutate func(tx *Transaction)
+		want   bool
+	}{{
+		name:   "distinct",
+		mutate: func(*Transaction) {},
+	}, {
+		name: "same hash",
+		mutate: func(tx *Transaction) {
+			tx.TxIn[7].Hash = bytes.Clone(tx.TxIn[3].Hash)
+		},
+	}, {
+		name: "same index",
+		mutate: func(tx *Transaction) {
+			tx.TxIn[7].Index = tx.TxIn[3].Index
+		},
+	}, {
+		name: "same outpoint",
+		mutate: func(tx *Transaction) {
+			tx.TxIn[7].Hash = bytes.Clone(tx.TxIn[3].Hash)
+			tx.TxIn[7].Index = tx.TxIn[3].Index
+		},
+		want: true,
+	}, {
+		name: "nil inputs",
+		mutate: func(tx *Transaction) {
+			tx.TxIn[3], tx.TxIn[7] = nil, nil
+		},
+	}}
+
+	for _, test := range tests {
+		tx := largeV10TestTx(10, 1)
+		test.mutate(tx)
+		require.Equal(t, test.want, tx.HasDuplicateInputs(), test.name)
+	}
+
+	require.False(t, v10TestCoinbase().HasDuplicateInputs())
+	require.False(t, (&Transaction{}).HasDuplicateInputs())
+}
+
+// TestCalculateTxIDErrWithOptions ensures duplicate inputs are only rejected
+// when RejectDuplicateInputs is set, and that the options otherwise behave
+// like CalculateTxIDErr.
+func TestCalculateTxIDErrWithOptions(t *testing.T) {
+	t.Parallel()
+
+	strict := &TxIDErrOptions{RejectDuplicateInputs: true}
+
+	tx := v10TestTx()
+	want, err := CalculateTxIDErr(nil, tx)
+	require.NoError(t, err)
+	for _, opts := range []*TxIDErrOptions{nil, {}, strict} {
+		id, err := CalculateTxIDErrWithOptions(nil, tx, opts)
+		require.NoError(t, err)
+		require.Equal(t, want, id)
+	}
+
+	dup := v10TestTx()
+	dup.TxIn[1].Hash = dup.TxIn[0].Hash
+	dup.TxIn[1].Index = dup.TxIn[0].Index
+	require.True(t, dup.HasDuplicateInputs())
+
+	want = CalculateTxID(nil, dup)
+	for _, opts := range []*TxIDErrOptions{nil, {}} {
+		id, err := CalculateTxIDErrWithOptions(nil, dup, opts)
+		require.NoError(t, err)
+		require.Equal(t, want, id)
+	}
+	id, err := CalculateTxIDErr(nil, dup)
+	require.NoError(t, err)
+	require.Equal(t, want, id)
+
+	id, err = CalculateTxIDErrWithOptions(nil, dup, strict)
+	require.Nil(t, id)
+	require.ErrorIs(t, err, ErrDuplicateInputs)
+	require.ErrorIs(t, err, ErrInvalidTxForHashing)
+	var txErr *TxHashError
+	require.ErrorAs(t, err, &txErr)
+	require.Equal(t, "CalculateTxIDErr", txErr.Op)
+	require.Equal(t, -1, txErr.Index)
+
+	_, err = CalculateTxIDErrWithOptions(nil, nil, strict)
+	require.ErrorIs(t, err, ErrNilTx)
+}
+
+// TestCalculateV10TxIDCoinbase ensures the layered txid of a version 10
+// coinbase is computed over the null outpoint like any other input and does
+// not change between calls.
+func TestCalculateV10TxIDCoinbase(t *testing.T) {
+	t.Parallel()
+
+	tx := v10TestCoinbase()
+	want := "9049e6cf05bd98641b4644686658742a" +
+		"89f89166c894b7dd360abc4bac854124"
+
+	txid := CalculateTxID(nil, tx)
+	require.Equal(t, refV10TxID(tx), txid)
+	require.Equal(t, want, hex.EncodeToString(txid))
+	require.Equal(t, txid, CalculateTxID(nil, tx))
+
+	txid, err := CalculateTxIDErr(nil, tx)
+	require.NoError(t, err)
+	require.Equal(t, want, hex.EncodeToString(txid))
+}
+
+// TestCalculateWitnessTxID ensures the wtxid commits to the witness data of
+// both standard and version 10 transactions, and that it is the same as the
+// txid when there is none.
+func TestCalculateWitnessTxID(t *testing.T) {
+	t.Parallel()
+
+	for _, version := range []int32{1, 10} {
+		for _, msgTx := range []*MsgTx{multiTx, multiWitnessTx} {
+			msgTx := msgTx.Copy()
+			msgTx.Version = version
+			tx := ConvertWireMsgTxToCommonTransaction(msgTx)
+
+			want := msgTx.WitnessHash()
+			wtxid := CalculateWitnessTxID(tx)
+			require.Equal(t, want[:], wtxid)
+
+			txid := CalculateTxID(mustBytes(t, tx), tx)
+			if msgTx.HasWitness() {
+				require.NotEqual(t, txid, wtxid)
+			} else {
+				require.Equal(t, txid, wtxid)
+			}
+
+			// Changing a witness item changes only the wtxid.
+			if msgTx.HasWitness() {
+				tx.TxIn[0].Witness = [][]byte{{0x01}}
+				newWTxID := CalculateWitnessTxID(tx)
+				require.NotEqual(t, wtxid, newWTxID)
+				newTxID := CalculateTxID(mustBytes(t, tx), tx)
+				require.Equal(t, txid, newTxID)
+			}
+		}
+	}
+
+	// Nil is returned when the transaction can't be serialized.
+	tx := v10TestTx()
+	tx.TxIn[0].Witness = [][]byte{{0x01}}
+	tx.TxIn[1].Hash = nil
+	require.Nil(t, CalculateWitnessTxID(tx))
+}
+
+// mustBytes returns the serialization of the transaction produced by Bytes,
+// failing the test on error.
+func mustBytes(t *testing.T, tx *Transaction) []byte {
+	t.Helper()
+
+	raw, err := tx.Bytes()
+	require.NoError(t, err)
+	return raw
+}
+
+// TestTransactionString ensures the human-readable dump of a transaction
+// includes every input and output.
+func TestTransactionString(t *testing.T) {
+	t.Parallel()
+
+	tx := v10TestTx()
+	tx.LockTime = 500
+	tx.TxIn[1].Witness = [][]byte{{0x01}, {0x02}}
+	txid := hex.EncodeToString(ReverseBytes(CalculateTxID(nil, tx)))
+
+	want := "tx " + txid + " version 10 locktime 500\n" +
+		"  in 0: " + strings.Repeat("11", 32) + ":0 script 1 bytes " +
+		"sequence 0xffffffff\n" +
+		"  in 1: " + strings.Repeat("22", 32) + ":1 script 2 bytes " +
+		"sequence 0xfffffffe witness 2 items\n" +
+		"  out 0: value 5000000000 script 2 bytes nonstandard"
+	require.Equal(t, want, tx.String())
+
+	// Transactions which can't be serialized are still dumped.
+	tx = v10TestTx()
+	tx.TxIn[0] = nil
+	tx.TxOut = append(tx.TxOut, nil)
+	want = "tx unknown version 10 locktime 0\n" +
+		"  in 0: nil\n" +
+		"  in 1: " + strings.Repeat("22", 32) + ":1 script 2 bytes " +
+		"sequence 0xfffffffe\n" +
+		"  out 0: value 5000000000 script 2 bytes nonstandard\n" +
+		"  out 1: nil"
+	require.Equal(t, want, tx.String())
+}
+
+// TestConvertWireMsgTxToCommonTransactionScriptLimits ensures scripts larger
+// than the configured maximum sizes are rejected by the checked conversion.
+func TestConvertWireMsgTxToCommonTransactionScriptLimits(t *testing.T) {
+	t.Parallel()
+
+	// scriptsTx returns a transaction with a signature script and public
+	// key script of the given sizes.
+	scriptsTx := func(sigScriptSize, pkScriptSize int) *MsgTx {
+		msgTx := NewMsgTx(1)
+		msgTx.AddTxIn(NewTxIn(
+			&OutPoint{}, make([]byte, sigScriptSize), nil,
+		))
+		msgTx.AddTxOut(NewTxOut(1, make([]byte, pkScriptSize)))
+		return msgTx
+	}
+
+	tests := []struct {
+		name         string
+		sigScriptLen int
+		pkScriptLen  int
+		opts         *ConvertOptions
+		wantErr      bool
+	}{{
+		name:         "default limits",
+		sigScriptLen: DefaultMaxSignatureScriptSize,
+		pkScriptLen:  DefaultMaxPkScriptSize,
+	}, {
+		name:         "signature script above default",
+		sigScriptLen: DefaultMaxSignatureScriptSize + 1,
+		wantErr:      true,
+	}, {
+		name:        "public key script above default",
+		pkScriptLen: DefaultMaxPkScriptSize + 1,
+		wantErr:     true,
+	}, {
+		name:         "zero options use defaults",
+		sigScriptLen: DefaultMaxSignatureScriptSize + 1,
+		opts:         &ConvertOptions{},
+		wantErr:      true,
+	}, {
+		name:         "custom signature script limit",
+		sigScriptLen: 101,
+		opts:         &ConvertOptions{MaxSignatureScriptSize: 100},
+		wantErr:      true,
+	}, {
+		name:        "custom public key script limit",
+		pkScriptLen: 26,
+		opts:        &ConvertOptions{MaxPkScriptSize: 25},
+		wantErr:     true,
+	}, {
+		name:         "raised limits",
+		sigScriptLen: 20000,
+		pkScriptLen:  20000,
+		opts: &ConvertOptions{
+			MaxSignatureScriptSize: 20000,
+			MaxPkScriptSize:        20000,
+		},
+	}}
+
+	for _, test := range tests {
+		msgTx := scriptsTx(test.sigScriptLen, test.pkScriptLen)
+		tx, err := ConvertWireMsgTxToCommonTransactionWithOptions(
+			msgTx, test.opts,
+		)
+		if test.wantErr {
+			require.ErrorIs(t, err, ErrScriptTooLarge, test.name)
+			continue
+		}
+		require.NoError(t, err, test.name)
+		require.Len(t, tx.TxIn[0].SignatureScript, test.sigScriptLen)
+		require.Len(t, tx.TxOut[0].PkScript.Pkscript, test.pkScriptLen)
+	}
+
+	// The default variant enforces the default limits.
+	msgTx := scriptsTx(DefaultMaxSignatureScriptSize+1, 0)
+	_, err := ConvertWireMsgTxToCommonTransactionErr(msgTx)
+	require.ErrorIs(t, err, ErrScriptTooLarge)
+}
+
+// TestConvertWireMsgTxToCommonTransactionRejectV10Witness ensures witness data
+// on version 10 transactions is only rejected in strict mode.
+func TestConvertWireMsgTxToCommonTransactionRejectV10Witness(t *testing.T) {
+	t.Parallel()
+
+	strict := &ConvertOptions{RejectV10Witness: true}
+	tests := []struct {
+		name    string
+		msgTx   *MsgTx
+		opts    *ConvertOptions
+		wantErr bool
+	}{
+		{name: "lenient v10 witness", msgTx: v10TestMsgTx(t, true)},
+		{
+			name:  "explicitly lenient v10 witness",
+			msgTx: v10TestMsgTx(t, true),
+			opts:  &ConvertOptions{},
+		},
+		{
+			name:    "strict v10 witness",
+			msgTx:   v10TestMsgTx(t, true),
+			opts:    strict,
+			wantErr: true,
+		},
+		{
+			name:  "strict v10 without witness",
+			msgTx: v10TestMsgTx(t, false),
+			opts:  strict,
+		},
+		{
+			name:  "strict standard witness",
+			msgTx: multiWitnessTx,
+			opts:  strict,
+		},
+	}
+
+	for _, test := range tests {
+		tx, err := ConvertWireMsgTxToCommonTransactionWithOptions(
+			test.msgTx, test.opts,
+		)
+		if test.wantErr {
+			require.ErrorIs(t, err, ErrWitnessOnV10, test.name)
+			require.Nil(t, tx, test.name)
+			continue
+		}
+		require.NoError(t, err, test.name)
+		require.Equal(t, test.msgTx.HasWitness(), tx.HasWitness(),
+			test.name)
+	}
+
+	// An empty witness stack on every input is not witness data.
+	msgTx := v10TestMsgTx(t, false)
+	for _, txIn := range msgTx.TxIn {
+		txIn.Witness = TxWitness{}
+	}
+	_, err := ConvertWireMsgTxToCommonTransactionWithOptions(msgTx, strict)
+	require.NoError(t, err)
+}
+
+// TestTransactionEntryAccess ensures inputs and outputs are only returned for
+// indexes in range and that the copied slices don't affect the transaction.
+func TestTransactionEntryAccess(t *testing.T) {
+	t.Parallel()
+
+	tx := largeV10TestTx(3, 2)
+	tx.TxIn[1] = nil
+
+	for i := -1; i <= 3; i++ {
+		in, ok := tx.Input(i)
+		wantOk := i == 0 || i == 2
+		require.Equal(t, wantOk, ok, "input %d", i)
+		if wantOk {
+			require.Same(t, tx.TxIn[i], in, "input %d", i)
+		} else {
+			require.Nil(t, in, "input %d", i)
+		}
+
+		out, ok := tx.Output(i)
+		wantOk = i >= 0 && i < 2
+		require.Equal(t, wantOk, ok, "output %d", i)
+		if wantOk {
+			require.Same(t, tx.TxOut[i], out, "output %d", i)
+		} else {
+			require.Nil(t, out, "output %d", i)
+		}
+	}
+
+	inputs := tx.Inputs()
+	require.Equal(t, tx.TxIn, inputs)
+	inputs[0], inputs[2] = inputs[2], inputs[0]
+	require.NotEqual(t, tx.TxIn, inputs)
+
+	outputs := tx.Outputs()
+	require.Equal(t, tx.TxOut, outputs)
+	outputs[0] = nil
+	require.NotNil(t, tx.TxOut[0])
+
+	var empty Transaction
+	_, ok := empty.Input(0)
+	require.False(t, ok)
+	_, ok = empty.Output(0)
+	require.False(t, ok)
+	require.Empty(t, empty.Inputs())
+}
+
+// TestTransactionClone ensures a cloned transaction shares no backing arrays
+// with the original, so mutating the clone leaves the original and its txid
+// unchanged.
+func TestTransactionClone(t *testing.T) {
+	t.Parallel()
+
+	for _, version := range []int32{1, 10} {
+		msgTx := multiWitnessTx.Copy()
+		msgTx.Version = version
+		tx := ConvertWireMsgTxToCommonTransaction(msgTx)
+		origTxID := tx.TxID()
+		origWTxID := CalculateWitnessTxID(tx)
+
+		clone := tx.Clone()
+		require.Equal(t, tx.TxIn, clone.TxIn)
+		require.Equal(t, tx.TxOut, clone.TxOut)
+		require.Equal(t, origTxID, clone.TxID())
+
+		// Mutate every non-empty byte slice of the clone in place.
+		flip := func(b []byte) {
+			for i := range b {
+				b[i] ^= 0xff
+			}
+		}
+		for _, txIn := range clone.TxIn {
+			flip(txIn.Hash)
+			flip(txIn.SignatureScript)
+			for _, item := range txIn.Witness {
+				flip(item)
+			}
+		}
+		for _, txOut := range clone.TxOut {
+			flip(txOut.PkScript.Pkscript)
+		}
+		clone.InvalidateTxID()
+
+		require.NotEqual(t, origTxID, clone.TxID())
+		tx.InvalidateTxID()
+		require.Equal(t, origTxID, tx.TxID())
+		require.Equal(t, origWTxID, CalculateWitnessTxID(tx))
+		want := ConvertWireMsgTxToCommonTransaction(msgTx)
+		require.Equal(t, want.TxIn, tx.TxIn)
+		require.Equal(t, want.TxOut, tx.TxOut)
+	}
+
+	// Nil entries and slices are preserved.
+	tx := v10TestTx()
+	tx.TxIn[0] = nil
+	tx.TxIn[1].SignatureScript = nil
+	tx.TxOut = nil
+	clone := tx.Clone()
+	require.Nil(t, clone.TxIn[0])
+	require.Nil(t, clone.TxIn[1].SignatureScript)
+	require.Nil(t, clone.TxIn[1].Witness)
+	require.Nil(t, clone.TxOut)
+	require.Equal(t, tx.TxInCount, clone.TxInCount)
+}
+
+// TestTxIDString ensures txids are converted to and from display order
+// strings matching chainhash.Hash.
+func TestTxIDString(t *testing.T) {
+	t.Parallel()
+
+	msgTx := multiTx.Copy()
+	hash := msgTx.TxHash()
+	tx := ConvertWireMsgTxToCommonTransaction(msgTx)
+	txid := CalculateTxID(mustBytes(t, tx), tx)
+
+	str := TxIDString(txid)
+	require.Equal(t, hash.String(), str)
+
+	decoded, err := TxIDFromString(str)
+	require.NoError(t, err)
+	require.Equal(t, txid, decoded)
+
+	for _, id := range [][]byte{nil, txid[:31], append(txid, 0)} {
+		require.Panics(t, func() { TxIDString(id) })
+	}
+
+	for _, s := range []string{"", str[:62], str + "00", "zz" + str[2:]} {
+		_, err := TxIDFromString(s)
+		require.Error(t, err)
+	}
+}
+
+// TestCalculateMsgTxID ensures the txid computed directly from a wire
+// transaction matches the one computed via the individual steps and that
+// malformed transactions are rejected.
+func TestCalculateMsgTxID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		msgTx *MsgTx
+	}{
+		{name: "standard", msgTx: multiTx},
+		{name: "standard witness", msgTx: multiWitnessTx},
+		{name: "v10", msgTx: v10TestMsgTx(t, false)},
+		{name: "v10 witness", msgTx: v10TestMsgTx(t, true)},
+	}
+
+	for _, test := range tests {
+		tx := ConvertWireMsgTxToCommonTransaction(test.msgTx)
+		want := CalculateTxID(mustBytes(t, tx), tx)
+		hash := test.msgTx.TxHash()
+		require.Equal(t, hash[:], want, test.name)
+
+		got, err := CalculateMsgTxID(test.msgTx)
+		require.NoError(t, err, test.name)
+		require.Equal(t, want, got, test.name)
+	}
+
+	_, err := CalculateMsgTxID(nil)
+	require.Error(t, err)
+
+	msgTx := multiTx.Copy()
+	msgTx.TxOut[0].Value = -1
+	_, err = CalculateMsgTxID(msgTx)
+	require.Error(t, err)
+
+	msgTx = v10TestMsgTx(t, false)
+	msgTx.TxIn = nil
+	msgTx.TxOut = nil
+	_, err = CalculateMsgTxID(msgTx)
+	require.ErrorIs(t, err, ErrInvalidTxForHashing)
+}
+
+// TestTransactionEqual ensures transactions are compared by content while
+// ignoring the stored counts.
+func TestTransactionEqual(t *testing.T) {
+	t.Parallel()
+
+	base := v10TestTx()
+	base.TxIn[0].Witness = [][]byte{{0x01}, {0x02, 0x03}}
+
+	tests := []struct {
+		name   string
+		mutate func(tx *Transaction) *Transaction
+		want   bool
+	}{{
+		name:   "clone",
+		mutate: func(tx *Transaction) *Transaction { return tx },
+		want:   true,
+	}, {
+		name: "stale counts",
+		mutate: func(tx *Transaction) *Transaction {
+			tx.TxInCount = 100
+			tx.TxOutCount = 0
+			return tx
+		},
+		want: true,
+	}, {
+		name:   "nil",
+		mutate: func(*Transaction) *Transaction { return nil },
+	}, {
+		name: "version",
+		mutate: func(tx *Transaction) *Transaction {
+			tx.Version++
+			return tx
+		},
+	}, {
+		name: "locktime",
+		mutate: func(tx *Transaction) *Transaction {
+			tx.LockTime++
+			return tx
+		},
+	}, {
+		name: "input hash",
+		mutate: func(tx *Transaction) *Transaction {
+			tx.TxIn[0].Hash[31] ^= 0xff
+			return tx
+		},
+	}, {
+		name: "input index",
+		mutate: func(tx *Transaction) *Transaction {
+			tx.TxIn[0].Index++
+			return tx
+		},
+	}, {
+		name: "input sequence",
+		mutate: func(tx *Transaction) *Transaction {
+			tx.TxIn[0].Sequence++
+			return tx
+		},
+	}, {
+		name: "witness item",
+		mutate: func(tx *Transaction) *Transaction {
+			tx.TxIn[0].Witness[1][0] ^= 0xff
+			return tx
+		},
+	}, {
+		name: "witness length",
+		mutate: func(tx *Transaction) *Transaction {
+			tx.TxIn[0].Witness = tx.TxIn[0].Witness[:1]
+			return tx
+		},
+	}, {
+		name: "missing input",
+		mutate: func(tx *Transaction) *Transaction {
+			tx.TxIn = tx.TxIn[1:]
+			return tx
+		},
+	}, {
+		name: "nil input",
+		mutate: func(tx *Transaction) *Transaction {
+			tx.TxIn[0] = nil
+			return tx
+		},
+	}, {
+		name: "output value",
+		mutate: func(tx *Transaction) *Transaction {
+			tx.TxOut[0].Value++
+			return tx
+		},
+	}, {
+		name: "output script",
+		mutate: func(tx *Transaction) *Transaction {
+			tx.TxOut[0].PkScript.Pkscript = append(
+				tx.TxOut[0].PkScript.Pkscript, 0x00,
+			)
+			return tx
+		},
+	}}
+
+	for _, test := range tests {
+		other := test.mutate(base.Clone())
+		require.Equal(t, test.want, base.Equal(other), test.name)
+		require.Equal(t, test.want, other.Equal(base), test.name)
+	}
+
+	// Empty and nil byte slices and nil entries compare equal.
+	a, b := base.Clone(), base.Clone()
+	a.TxIn[1].SignatureScript = nil
+	b.TxIn[1].SignatureScript = []byte{}
+	a.TxOut[0], b.TxOut[0] = nil, nil
+	require.True(t, a.Equal(b))
+
+	var nilTx *Transaction
+	require.True(t, nilTx.Equal(nil))
+}
+
+// TestCalculateTxIDUnsigned ensures the pre-signature identifier ignores the
+// signature scripts and witnesses but commits to everything else.
+func TestCalculateTxIDUnsigned(t *testing.T) {
+	t.Parallel()
+
+	for _, signed := range []*Transaction{
+		v10TestTx(),
+		ConvertWireMsgTxToCommonTransaction(multiTx),
+	} {
+		unsigned := signed.Clone()
+		for _, txIn := range unsigned.TxIn {
+			txIn.SignatureScript = nil
+			txIn.Witness = nil
+		}
+		want := CalculateTxID(mustBytes(t, unsigned), unsigned)
+
+		orig := signed.Clone()
+		got := CalculateTxIDUnsigned(signed)
+		require.Equal(t, want, got)
+		require.Equal(t, got, CalculateTxIDUnsigned(unsigned))
+		require.NotEqual(t, got, CalculateTxID(mustBytes(t, signed),
+			signed))
+		require.True(t, orig.Equal(signed))
+
+		// Signing again with different scripts doesn't change it.
+		signed.TxIn[0].SignatureScript = []byte{0x51, 0x52}
+		signed.TxIn[0].Witness = [][]byte{{0x01}}
+		require.Equal(t, got, CalculateTxIDUnsigned(signed))
+
+		signed.TxIn[0].Sequence++
+		require.NotEqual(t, got, CalculateTxIDUnsigned(signed))
+	}
+
+	tx := v10TestTx()
+	tx.Version = 1
+	tx.TxInCount++
+	require.Nil(t, CalculateTxIDUnsigned(tx))
+}
+
+// TestCalculateTxIDBoth ensures both byte orders of the txid are returned and
+// agree with chainhash.
+func TestCalculateTxIDBoth(t *testing.T) {
+	t.Parallel()
+
+	for _, msgTx := range []*MsgTx{multiTx, v10TestMsgTx(t, false)} {
+		tx := ConvertWireMsgTxToCommonTransaction(msgTx)
+		raw := mustBytes(t, tx)
+
+		internal, display := CalculateTxIDBoth(raw, tx)
+		require.Equal(t, CalculateTxID(raw, tx), internal)
+
+		hash := msgTx.TxHash()
+		require.Equal(t, hash[:], internal)
+		require.Equal(t, hash.String(), hex.EncodeToString(display))
+
+		// The display form must be a separate copy.
+		display[0] ^= 0xff
+		require.Equal(t, hash[:], internal)
+	}
+}
+
+// recordingLogger is a Logger which records the messages logged to it.
+type recordingLogger struct {
+	mtx      sync.Mutex
+	debugs   []string
+	warnings []string
+}
+
+// Debugf records a debug message.
+func (l *recordingLogger) Debugf(format string, params ...interface{}) {
+	l.mtx.Lock()
+	l.debugs = append(l.debugs, fmt.Sprintf(format, params...))
+	l.mtx.Unlock()
+}
+
+// Warnf records a warning.
+func (l *recordingLogger) Warnf(format string, params ...interface{}) {
+	l.mtx.Lock()
+	l.warnings = append(l.warnings, fmt.Sprintf(format, params...))
+	l.mtx.Unlock()
+}
+
+// TestConversionLogging ensures silently reinterpreted or discarded data is
+// logged.  It must not be run in parallel since it replaces the logger.
+func TestConversionLogging(t *testing.T) {
+	logger := new(recordingLogger)
+	UseLogger(logger)
+	t.Cleanup(DisableLog)
+
+	msgTx := multiTx.Copy()
+	msgTx.TxOut[0].Value = -1
+	ConvertWireMsgTxToCommonTransaction(msgTx)
+	require.Len(t, logger.warnings, 1)
+	require.Contains(t, logger.warnings[0], "negative value -1")
+
+	tx := v10TestTx()
+	tx.TxInCount++
+	_, err := ConvertCommonTransactionToWireMsgTx(tx)
+	require.NoError(t, err)
+	require.Len(t, logger.warnings, 2)
+	require.Contains(t, logger.warnings[1], "Ignoring input and output")
+
+	tx.Version = 1
+	require.Nil(t, tx.TxID())
+	require.Len(t, logger.warnings, 3)
+	require.Contains(t, logger.warnings[2], "Unable to compute txid")
+
+	_, err = CalculateMsgTxID(multiWitnessTx)
+	require.NoError(t, err)
+	require.Len(t, logger.debugs, 1)
+	require.Contains(t, logger.debugs[0], "Witness data")
+
+	// Nothing is logged for well formed transactions.
+	_, err = CalculateMsgTxID(multiTx)
+	require.NoError(t, err)
+	require.Len(t, logger.warnings, 3)
+	require.Len(t, logger.debugs, 1)
+}
+
+// TestCalculateStandardTxID ensures the standard txid is computed for every
+// version, including version 10, and that witness data is stripped from raw
+// bytes in the witness serialization.
+func TestCalculateStandardTxID(t *testing.T) {
+	t.Parallel()
+
+	standard := ConvertWireMsgTxToCommonTransaction(multiTx)
+	raw := mustBytes(t, standard)
+	require.Equal(t, CalculateTxID(raw, standard),
+		CalculateStandardTxID(raw))
+
+	v10 := v10TestTx()
+	v10Raw := mustBytes(t, v10)
+	id := CalculateStandardTxID(v10Raw)
+	require.Equal(t, DoubleSha256(v10Raw), id)
+	require.NotEqual(t, CalculateTxID(v10Raw, v10), id)
+
+	// The txid of a segwit transaction excludes its witness data.
+	witnessRaw, err := hex.DecodeString(segnetWitnessTxHex)
+	require.NoError(t, err)
+	var msgTx MsgTx
+	require.NoError(t, msgTx.Deserialize(bytes.NewReader(witnessRaw)))
+	wantTxID := msgTx.TxHash()
+	require.Equal(t, wantTxID[:], CalculateStandardTxID(witnessRaw))
+	require.NotEqual(t, DoubleSha256(witnessRaw),
+		CalculateStandardTxID(witnessRaw))
+
+	// A transaction without any inputs and a single output begins with
+	// the same bytes as the marker and flag, but is hashed as is.
+	noInputs := NewMsgTx(1)
+	noInputs.AddTxOut(NewTxOut(1000, []byte{0x51}))
+	var buf bytes.Buffer
+	require.NoError(t, noInputs.Serialize(&buf))
+	noInputsRaw := buf.Bytes()
+	require.Equal(t, byte(TxFlagMarker), noInputsRaw[4])
+	require.Equal(t, byte(WitnessFlag), noInputsRaw[5])
+	require.Equal(t, DoubleSha256(noInputsRaw),
+		CalculateStandardTxID(noInputsRaw))
+}
+
+// TestCalculateTxIDHashesRawData ensures the standard txid is the double sha256
+// of exactly the provided raw bytes, which are hashed in place without the
+// transaction being serialized, so callers can rely on passing the span of a
+// larger buffer to avoid serializing each transaction.  It is not run in
+// parallel since it replaces the sha256 backend and testing.AllocsPerRun
+// requires it.
+func TestCalculateTxIDHashesRawData(t *testing.T) {
+	backend := new(recordingSha256Backend)
+	SetSha256Backend(backend)
+	t.Cleanup(func() {
+		SetSha256Backend(nil)
+	})
+
+	standard := ConvertWireMsgTxToCommonTransaction(multiTx)
+	witness := ConvertWireMsgTxToCommonTransaction(multiWitnessTx)
+	unregistered := ConvertWireMsgTxToCommonTransaction(multiTx)
+	unregistered.Version = 7
+
+	// The raw bytes are a span of a larger buffer, and the last span
+	// doesn't correspond to the transaction at all, so the txid can
+	// only match if the raw bytes are hashed as is.
+	var block []byte
+	var spans [][]byte
+	for _, tx := range []*Transaction{standard, witness, unregistered} {
+		raw, err := tx.StandardSerialize()
+		require.NoError(t, err)
+		block = append(block, raw...)
+		spans = append(spans, raw)
+	}
+	block = append(block, 0x01, 0x02, 0x03)
+	offset := 0
+	for i, span := range spans {
+		spans[i] = block[offset : offset+len(span)]
+		offset += len(span)
+	}
+	bogus := block[offset-4:]
+
+	tests := []struct {
+		tx  *Transaction
+		raw []byte
+	}{
+		{tx: standard, raw: spans[0]},
+		{tx: witness, raw: spans[1]},
+		{tx: unregistered, raw: spans[2]},
+		{tx: standard, raw: bogus},
+	}
+	for i, test := range tests {
+		want := DoubleSha256(test.raw)
+		backend.reset()
+		txid := CalculateTxID(test.raw, test.tx)
+		require.Equal(t, want, txid, "test %d", i)
+
+		// The first sha256 is over the raw bytes themselves rather
+		// than a copy of them.
+		require.Len(t, backend.sums, 2, "test %d", i)
+		require.Same(t, &test.raw[0], &backend.sums[0][0], "test %d",
+			i)
+		require.Len(t, backend.sums[0], len(test.raw), "test %d", i)
+
+		// Only the returned txid is allocated.
+		allocs := testing.AllocsPerRun(50, func() {
+			_ = CalculateTxID(test.raw, test.tx)
+		})
+		require.Equal(t, 1.0, allocs, "test %d", i)
+	}
+}
+
+// TestCalculateTxIDAsVersion ensures the hypothetical txid under another
+// version matches the txid of the same transaction with that version, whether
+// the raw bytes are given, in the witness serialization, or absent, and that
+// the transaction is not modified.
+func TestCalculateTxIDAsVersion(t *testing.T) {
+	t.Parallel()
+
+	withVersion := func(msgTx *MsgTx, version int32) chainhash.Hash {
+		msgTx = msgTx.Copy()
+		msgTx.Version = version
+		return msgTx.TxHash()
+	}
+
+	standard := ConvertWireMsgTxToCommonTransaction(multiTx)
+	raw := mustBytes(t, standard)
+	want := withVersion(multiTx, 2)
+	require.Equal(t, want[:], CalculateTxIDAsVersion(standard, 2, raw))
+	require.Equal(t, want[:], CalculateTxIDAsVersion(standard, 2, nil))
+	require.Equal(t, CalculateTxID(raw, standard),
+		CalculateTxIDAsVersion(standard, standard.Version, raw))
+	require.Equal(t, uint32(multiTx.Version), standard.Version)
+
+	witness := ConvertWireMsgTxToCommonTransaction(multiWitnessTx)
+	var buf bytes.Buffer
+	require.NoError(t, multiWitnessTx.Serialize(&buf))
+	want = withVersion(multiWitnessTx, 3)
+	require.Equal(t, want[:],
+		CalculateTxIDAsVersion(witness, 3, buf.Bytes()))
+
+	// A version 10 transaction has no standard raw bytes, so it is
+	// serialized under the hypothetical version.
+	v10 := v10TestTx()
+	v10MsgTx, err := ConvertCommonTransactionToWireMsgTx(v10)
+	require.NoError(t, err)
+	want = withVersion(v10MsgTx, 1)
+	require.Equal(t, want[:], CalculateTxIDAsVersion(v10, 1, nil))
+	require.NotEqual(t, CalculateTxID(nil, v10), want[:])
+	require.Equal(t, uint32(10), v10.Version)
+
+	layered := standard.Clone()
+	layered.Version = LayeredTxIDVersion
+	require.Equal(t, CalculateV10TxID(layered),
+		CalculateTxIDAsVersion(standard, LayeredTxIDVersion, raw))
+
+	require.Nil(t, CalculateTxIDAsVersion(nil, 1, raw))
+	require.Nil(t, CalculateTxIDAsVersion(standard, 2, raw[:3]))
+}
+
+// TestCalculateTxIDWithLockTime ensures the hypothetical txid under another
+// locktime matches the txid of the same transaction with that locktime for
+// both the layered and standard txids, and that the transaction is not
+// modified.
+func TestCalculateTxIDWithLockTime(t *testing.T) {
+	t.Parallel()
+
+	locktimes := []uint32{
+		0, lockTimeThreshold - 1, lockTimeThreshold, math.MaxUint32,
+	}
+	for _, lockTime := range locktimes {
+		v10 := v10TestTx()
+		changed := v10.Clone()
+		changed.LockTime = lockTime
+		require.Equal(t, CalculateTxID(nil, changed),
+			CalculateTxIDWithLockTime(v10, lockTime, nil),
+			"locktime %d", lockTime)
+		require.Equal(t, uint32(0), v10.LockTime)
+
+		msgTx := multiTx.Copy()
+		msgTx.LockTime = lockTime
+		want := msgTx.TxHash()
+
+		standard := ConvertWireMsgTxToCommonTransaction(multiTx)
+		raw := mustBytes(t, standard)
+		require.Equal(t, want[:],
+			CalculateTxIDWithLockTime(standard, lockTime, raw),
+			"locktime %d", lockTime)
+		require.Equal(t, want[:],
+			CalculateTxIDWithLockTime(standard, lockTime, nil),
+			"locktime %d", lockTime)
+		require.Equal(t, multiTx.LockTime, standard.LockTime)
+
+		msgTx = multiWitnessTx.Copy()
+		msgTx.LockTime = lockTime
+		want = msgTx.TxHash()
+
+		var buf bytes.Buffer
+		require.NoError(t, multiWitnessTx.Serialize(&buf))
+		witness := ConvertWireMsgTxToCommonTransaction(multiWitnessTx)
+		got := CalculateTxIDWithLockTime(witness, lockTime, buf.Bytes())
+		require.Equal(t, want[:], got, "locktime %d", lockTime)
+	}
+
+	standard := ConvertWireMsgTxToCommonTransaction(multiTx)
+	require.Nil(t, CalculateTxIDWithLockTime(nil, 0, nil))
+	require.Nil(t, CalculateTxIDWithLockTime(standard, 0, []byte{1, 2}))
+}
+
+// TestTransactionWitnessCommitment ensures the witness commitment is found in
+// the last coinbase output holding one.
+func TestTransactionWitnessCommitment(t *testing.T) {
+	t.Parallel()
+
+	commitment := func(b byte) []byte {
+		return append(bytes.Clone(witnessCommitmentPrefix),
+			bytes.Repeat([]byte{b}, 32)...)
+	}
+	output := func(script []byte) *TxOutput {
+		return &TxOutput{PkScript: PkScript{Pkscript: script}}
+	}
+
+	tx := v10TestCoinbase()
+	_, ok := tx.WitnessCommitment()
+	require.False(t, ok)
+
+	// Trailing bytes are allowed, and the last commitment wins.
+	tx.TxOut = append(tx.TxOut,
+		output(commitment(0x01)),
+		output(append(commitment(0x02), 0xff, 0xff)),
+		output(commitment(0x03)[:37]),
+		output([]byte{0x6a, 0x24}),
+	)
+	got, ok := tx.WitnessCommitment()
+	require.True(t, ok)
+	require.Equal(t, bytes.Repeat([]byte{0x02}, 32), got)
+
+	// Only coinbase transactions hold a commitment.
+	notCoinbase := v10TestTx()
+	notCoinbase.TxOut = tx.TxOut
+	_, ok = notCoinbase.WitnessCommitment()
+	require.False(t, ok)
+
+	// A script of exactly 38 bytes holds just the commitment.
+	script := commitment(0x04)
+	tx.TxOut = []*TxOutput{output(script)}
+	got, ok = tx.WitnessCommitment()
+	require.True(t, ok)
+	require.Equal(t, script[6:38], got)
+}
+
+// TestCalculateTxIDInto ensures the txid written into the destination matches
+// CalculateTxID and that the standard path does not allocate.  It must not be
+// run in parallel since it measures allocations.
+func TestCalculateTxIDInto(t *testing.T) {
+	standard := ConvertWireMsgTxToCommonTransaction(multiTx)
+	raw := mustBytes(t, standard)
+	v10 := v10TestTx()
+
+	var dst [chainhash.HashSize + 1]byte
+	for _, tx := range []*Transaction{standard, v10} {
+		require.NoError(t, CalculateTxIDInto(dst[:], raw, tx))
+		require.Equal(t, CalculateTxID(raw, tx),
+			dst[:chainhash.HashSize])
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = CalculateTxIDInto(dst[:], raw, standard)
+	})
+	require.Zero(t, allocs)
+
+	require.Error(t, CalculateTxIDInto(dst[:31], raw, standard))
+	require.ErrorIs(t, CalculateTxIDInto(dst[:], raw, nil),
+		ErrInvalidTxForHashing)
+}
+
+// segnetWitnessTxHex is the witness serialization of the transaction from
+// block 23157 in a past version of segnet, which is also used by TestWTxSha.
+const segnetWitnessTxHex = "01000000000101a53352d5135766f03076597418263da2d9" +
+	"c958315968fea823529467481ff9cd1300000000ffffffff010b0706000000000016" +
+	"00149ddac6f39d51e0398e532a22c41ba189406a852302463043021f4d2381dc97f1" +
+	"82abd8185f51753018523212f5ddc07cc4e63a8dc03658da190220608b5c4d92b86b" +
+	"6de7d78ef23a2fa735bcb59b914a48b0e187c5e7569a18197001210307ead084807e" +
+	"b76346df6977000c89392f45c76425b26181f521d7f370066a8f00000000"
+
+// TestCalculateTxIDWitnessSerialization ensures the standard txid excludes the
+// marker, flag and witness data when the raw transaction data is the witness
+// serialization of the transaction.
+func TestCalculateTxIDWitnessSerialization(t *testing.T) {
+	t.Parallel()
+
+	const (
+		wantTxID = "0f167d1385a84d1518cfee208b653fc9163b605ccf1b753" +
+			"47e2850b3e2eb19f3"
+		wantWTxID = "0858eab78e77b6b033da30f46699996396cf48fcf625a7" +
+			"83c85a51403e175e74"
+	)
+
+	raw, err := hex.DecodeString(segnetWitnessTxHex)
+	require.NoError(t, err)
+
+	var msgTx MsgTx
+	require.NoError(t, msgTx.Deserialize(bytes.NewReader(raw)))
+	tx := ConvertWireMsgTxToCommonTransaction(&msgTx)
+	require.True(t, isWitnessSerialization(raw, tx))
+
+	require.Equal(t, wantTxID, TxIDString(CalculateTxID(raw, tx)))
+	require.Equal(t, wantWTxID, TxIDString(CalculateWitnessTxID(tx)))
+
+	txid, err := CalculateTxIDErr(raw, tx)
+	require.NoError(t, err)
+	require.Equal(t, wantTxID, TxIDString(txid))
+
+	var dst [chainhash.HashSize]byte
+	require.NoError(t, CalculateTxIDInto(dst[:], raw, tx))
+	require.Equal(t, wantTxID, TxIDString(dst[:]))
+
+	ids, err := CalculateTxIDs([][]byte{raw}, []*Transaction{tx})
+	require.NoError(t, err)
+	require.Equal(t, wantTxID, TxIDString(ids[0]))
+
+	stripped, err := tx.StandardSerialize()
+	require.NoError(t, err)
+	require.Equal(t, wantTxID, TxIDString(CalculateStandardTxID(stripped)))
+	require.Equal(t, wantTxID, TxIDString(CalculateTxID(stripped, tx)))
+
+	// The witness data can't be stripped from a transaction which can't
+	// be serialized.
+	tx.TxInCount++
+	require.Nil(t, CalculateTxID(raw, tx))
+	_, err = CalculateTxIDErr(raw, tx)
+	require.Error(t, err)
+	require.Error(t, CalculateTxIDInto(dst[:], raw, tx))
+
+	// A transaction without any inputs has the same leading bytes as the
+	// marker and flag when it has a single output, but it can't have
+	// witness data, so its raw bytes are hashed as they are.
+	noInputs := &Transaction{
+		Version:    1,
+		TxOutCount: 1,
+		TxOut:      []*TxOutput{{Value: 1}},
+	}
+	raw = mustBytes(t, noInputs)
+	require.Equal(t, []byte{TxFlagMarker, WitnessFlag}, raw[4:6])
+	require.False(t, isWitnessSerialization(raw, noInputs))
+	require.Equal(t, DoubleSha256(raw), CalculateTxID(raw, noInputs))
+}
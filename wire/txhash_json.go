@@ -0,0 +1,162 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// txJSON is the JSON representation of a Transaction.
+type txJSON struct {
+	TxID     string      `json:"txid,omitempty"`
+	Version  uint32      `json:"version"`
+	LockTime uint32      `json:"locktime"`
+	Vin      []*TxInput  `json:"vin"`
+	Vout     []*TxOutput `json:"vout"`
+}
+
+// txInputJSON is the JSON representation of a TxInput.
+type txInputJSON struct {
+	Hash            string   `json:"hash"`
+	Index           uint32   `json:"index"`
+	SignatureScript string   `json:"signatureScript"`
+	Sequence        uint32   `json:"sequence"`
+	Witness         []string `json:"witness,omitempty"`
+}
+
+// txOutputJSON is the JSON representation of a TxOutput.
+type txOutputJSON struct {
+	Value    uint64 `json:"value"`
+	PkScript string `json:"pkScript"`
+}
+
+// MarshalJSON encodes the transaction as a JSON object with its inputs under
+// "vin" and its outputs under "vout".  The object also includes the txid of
+// the transaction under "txid" in display byte order, which is omitted when
+// it can't be computed.  The txid is informational only and is ignored by
+// UnmarshalJSON.  It has a value receiver so a Transaction is encoded the
+// same way whether it is marshaled by value or through a pointer.
+func (tx Transaction) MarshalJSON() ([]byte, error) {
+	internal := tx.InternalOrder()
+	vin, vout := internal.TxIn, internal.TxOut
+	if vin == nil {
+		vin = []*TxInput{}
+	}
+	if vout == nil {
+		vout = []*TxOutput{}
+	}
+
+	return json.Marshal(&txJSON{
+		TxID:     internal.displayTxID(),
+		Version:  internal.Version,
+		LockTime: internal.LockTime,
+		Vin:      vin,
+		Vout:     vout,
+	})
+}
+
+// UnmarshalJSON decodes a transaction encoded by MarshalJSON.  TxInCount and
+// TxOutCount are set to the number of decoded inputs and outputs, HashOrder
+// is set to HashOrderInternal, and any cached txid is invalidated.
+func (tx *Transaction) UnmarshalJSON(data []byte) error {
+	var v txJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	tx.Version = v.Version
+	tx.LockTime = v.LockTime
+	tx.TxIn = v.Vin
+	tx.TxOut = v.Vout
+	tx.TxInCount = uint(len(v.Vin))
+	tx.TxOutCount = uint(len(v.Vout))
+	tx.HashOrder = HashOrderInternal
+	tx.InvalidateTxID()
+
+	return nil
+}
+
+// MarshalJSON encodes the input as a JSON object with its byte fields hex
+// encoded.  The previous outpoint hash is given in display byte order, as
+// returned by DisplayHash.
+func (in *TxInput) MarshalJSON() ([]byte, error) {
+	v := txInputJSON{
+		Hash:            in.DisplayHash(),
+		Index:           in.Index,
+		SignatureScript: hex.EncodeToString(in.SignatureScript),
+		Sequence:        in.Sequence,
+	}
+	for _, item := range in.Witness {
+		v.Witness = append(v.Witness, hex.EncodeToString(item))
+	}
+
+	return json.Marshal(&v)
+}
+
+// UnmarshalJSON decodes an input encoded by MarshalJSON, converting the
+// previous outpoint hash back to internal byte order.  An error is returned
+// if the hash does not encode exactly 32 bytes.
+func (in *TxInput) UnmarshalJSON(data []byte) error {
+	var v txInputJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	hash, err := ParseTxInputHash(v.Hash)
+	if err != nil {
+		return fmt.Errorf("invalid hash: %w", err)
+	}
+	sigScript, err := hex.DecodeString(v.SignatureScript)
+	if err != nil {
+		return fmt.Errorf("invalid signature script: %w", err)
+	}
+	var witness [][]byte
+	for i, item := range v.Witness {
+		b, err := hex.DecodeString(item)
+		if err != nil {
+			return fmt.Errorf("invalid witness item %d: %w", i, err)
+		}
+		witness = append(witness, b)
+	}
+
+	*in = TxInput{
+		Hash:            hash,
+		Index:           v.Index,
+		SignatureScript: sigScript,
+		Sequence:        v.Sequence,
+		Witness:         witness,
+	}
+	return nil
+}
+
+// MarshalJSON encodes the output as a JSON object with its public key script
+// hex encoded.
+func (out *TxOutput) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&txOutputJSON{
+		Value:    out.Value,
+		PkScript: hex.EncodeToString(out.PkScript.Pkscript),
+	})
+}
+
+// UnmarshalJSON decodes an output encoded by MarshalJSON.
+func (out *TxOutput) UnmarshalJSON(data []byte) error {
+	var v txOutputJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	pkScript, err := hex.DecodeString(v.PkScript)
+	if err != nil {
+		return fmt.Errorf("invalid public key script: %w", err)
+	}
+
+	*out = TxOutput{
+		Value:    v.Value,
+		PkScript: PkScript{Pkscript: pkScript},
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestTransactionJSON ensures transactions are encoded to JSON with hex
+// encoded byte fields and display order hashes, and that decoding the result
+// preserves the txid.
+func TestTransactionJSON(t *testing.T) {
+	t.Parallel()
+
+	tx := v10TestTx()
+	tx.TxIn[0].Hash[0] = 0x01
+	tx.TxIn[1].Witness = [][]byte{{0xde, 0xad}, {}}
+
+	// The hash of the first input is reversed into display order.
+	txid := tx.displayTxID()
+	want := `{"txid":"` + txid + `","version":10,"locktime":0,"vin":[` +
+		`{"hash":"` + strings.Repeat("11", 31) + `01","index":0,` +
+		`"signatureScript":"51","sequence":4294967295},` +
+		`{"hash":"` + strings.Repeat("22", 32) + `","index":1,` +
+		`"signatureScript":"5253","sequence":4294967294,` +
+		`"witness":["dead",""]}],` +
+		`"vout":[{"value":5000000000,"pkScript":"76a9"}]}`
+	got, err := json.Marshal(tx)
+	require.NoError(t, err)
+	require.Equal(t, want, string(got))
+
+	// A transaction marshaled by value, such as a field of another
+	// struct, is encoded the same way.
+	got, err = json.Marshal(*tx)
+	require.NoError(t, err)
+	require.Equal(t, want, string(got))
+	got, err = json.Marshal(struct{ Tx Transaction }{*tx})
+	require.NoError(t, err)
+	require.Equal(t, `{"Tx":`+want+`}`, string(got))
+	got, err = json.Marshal(tx)
+	require.NoError(t, err)
+
+	var decoded Transaction
+	require.NoError(t, json.Unmarshal(got, &decoded))
+	require.Equal(t, tx.TxID(), decoded.TxID())
+	require.Equal(t, tx.TxIn[0].Hash, decoded.TxIn[0].Hash)
+	require.Equal(t, uint(2), decoded.TxInCount)
+	require.Equal(t, uint(1), decoded.TxOutCount)
+
+	// Standard transactions, which are hashed over their serialization,
+	// also keep their txid.
+	for _, msgTx := range []*MsgTx{multiTx, multiWitnessTx} {
+		tx := ConvertWireMsgTxToCommonTransaction(msgTx)
+		b, err := json.Marshal(tx)
+		require.NoError(t, err)
+
+		var decoded Transaction
+		require.NoError(t, json.Unmarshal(b, &decoded))
+		wantHash := msgTx.TxHash()
+		require.Equal(t, wantHash[:], decoded.TxID())
+		require.Equal(t, wantHash.String(), decoded.displayTxID())
+		require.Equal(t, tx.TxIn[0].Witness, decoded.TxIn[0].Witness)
+	}
+
+	// An empty transaction has empty input and output lists.
+	got, err = json.Marshal(&Transaction{Version: 10})
+	require.NoError(t, err)
+	require.Contains(t, string(got), `"vin":[],"vout":[]`)
+}
+
+// TestTransactionUnmarshalJSONErrors ensures malformed fields are rejected.
+func TestTransactionUnmarshalJSONErrors(t *testing.T) {
+	t.Parallel()
+
+	hash := strings.Repeat("00", 32)
+	tests := []struct {
+		name string
+		json string
+	}{{
+		name: "short hash",
+		json: `{"vin":[{"hash":"00"}]}`,
+	}, {
+		name: "bad hash hex",
+		json: `{"vin":[{"hash":"` + strings.Repeat("zz", 32) + `"}]}`,
+	}, {
+		name: "bad signature script",
+		json: `{"vin":[{"hash":"` + hash + `","signatureScript":"0"}]}`,
+	}, {
+		name: "bad witness item",
+		json: `{"vin":[{"hash":"` + hash + `","witness":["x"]}]}`,
+	}, {
+		name: "bad public key script",
+		json: `{"vout":[{"pkScript":"xy"}]}`,
+	}, {
+		name: "wrong type",
+		json: `{"version":"10"}`,
+	}}
+
+	for _, test := range tests {
+		var tx Transaction
+		err := json.Unmarshal([]byte(test.json), &tx)
+		require.Error(t, err, test.name)
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// CalculateMerkleRoot returns the merkle root of the provided txids, which
+// must each be 32 bytes in internal byte order such as those returned by
+// CalculateTxID, in the order the transactions appear in the block.
+//
+// The tree is built the same way as the block header merkle root: each level
+// is produced by taking the double sha256 of the concatenation of each pair of
+// hashes, and when a level has an odd number of hashes the last one is paired
+// with itself.  The merkle root of a single txid is the txid itself, and the
+// merkle root of no txids is the all-zero hash.
+//
+// Nil is returned if any txid is not exactly 32 bytes, since it would
+// otherwise be silently padded or truncated when paired, producing a root no
+// one else computes.  Use MerkleProof to obtain an error describing the
+// offending txid instead.  The provided slices are not modified.
+func CalculateMerkleRoot(txids [][]byte) []byte {
+	for i, txid := range txids {
+		if len(txid) != chainhash.HashSize {
+			log.Warnf("Unable to compute merkle root: txid %d is "+
+				"%d bytes, want %d", i, len(txid),
+				chainhash.HashSize)
+			return nil
+		}
+	}
+
+	switch len(txids) {
+	case 0:
+		return make([]byte, chainhash.HashSize)
+
+	case 1:
+		return append([]byte(nil), txids[0]...)
+	}
+
+	level := make([][]byte, len(txids), len(txids)+1)
+	copy(level, txids)
+
+	var concat [chainhash.HashSize * 2]byte
+	for len(level) > 1 {
+		if len(level)%2 != 0 {
+			level = append(level, level[len(level)-1])
+		}
+
+		// Each parent replaces the entry at its own index, which is
+		// always at or before the children still to be read, so the
+		// level can be reduced in place.
+		parents := make([]byte, len(level)/2*chainhash.HashSize)
+		for i := 0; i < len(level)/2; i++ {
+			copy(concat[:chainhash.HashSize], level[2*i])
+			copy(concat[chainhash.HashSize:], level[2*i+1])
+
+			parent := parents[i*chainhash.HashSize:]
+			level[i] = doubleSha256Into(parent, concat[:])
+		}
+		level = level[:len(level)/2]
+	}
+
+	return level[0]
+}
+
+// ProcessBlockTxIDs computes the txids of the serialized transactions of a
+// block, in block order, along with their merkle root.  Each transaction is
+// hashed with CalculateTxIDFromReader, so the built-in version 10 txid and
+// any other registered TxIDStrategy are respected, and transactions which
+// don't require a strategy are hashed without being deserialized.
+//
+// Since the last hash of a level with an odd number of hashes is paired with
+// itself, a block which repeats its trailing transactions has the same merkle
+// root as the block without them (CVE-2012-2459).  An error is therefore
+// returned when two transactions have the same txid, in addition to when
+// there are no transactions, when any of them is malformed, or when the bytes
+// for any transaction contain trailing data.
+func ProcessBlockTxIDs(txBytes [][]byte) (txids [][]byte, merkleRoot []byte,
+	err error) {
+
+	if len(txBytes) == 0 {
+		return nil, nil, errors.New("block has no transactions")
+	}
+
+	txids = make([][]byte, len(txBytes))
+	seen := make(map[chainhash.Hash]int, len(txBytes))
+	for i, raw := range txBytes {
+		r := bytes.NewReader(raw)
+		txid, err := CalculateTxIDFromReader(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("transaction %d: %w", i,
+				err)
+		}
+		if r.Len() != 0 {
+			return nil, nil, fmt.Errorf("transaction %d: %d bytes "+
+				"of trailing data", i, r.Len())
+		}
+		if len(txid) != chainhash.HashSize {
+			return nil, nil, fmt.Errorf("transaction %d: %w", i,
+				invalidTxError("ProcessBlockTxIDs", -1,
+					ErrBadTxIDLen, "%d byte txid",
+					len(txid)))
+		}
+
+		hash := chainhash.Hash(txid)
+		if j, ok := seen[hash]; ok {
+			return nil, nil, fmt.Errorf("transactions %d and %d "+
+				"have the same txid %v", j, i, hash)
+		}
+		seen[hash] = i
+		txids[i] = txid
+	}
+
+	return txids, CalculateMerkleRoot(txids), nil
+}
+
+// MerkleProof returns the proof that the txid at index is included in the
+// merkle tree of the provided txids as built by CalculateMerkleRoot, which
+// allows a light client to verify the inclusion with VerifyMerkleProof
+// without the other txids.  The proof holds the sibling hash at each level of
+// the tree, starting with the sibling of the txid itself and ending with a
+// child of the root, so a tree of a single txid has an empty proof.  A hash
+// which is the last of a level with an odd number of hashes is its own
+// sibling.
+//
+// An error is returned if index is out of range or if any txid is not 32
+// bytes.  The returned hashes do not alias the provided slices.
+func MerkleProof(txids [][]byte, index int) ([][]byte, error) {
+	if index < 0 || index >= len(txids) {
+		return nil, fmt.Errorf("index %d out of range for %d txids",
+			index, len(txids))
+	}
+	for i, txid := range txids {
+		if len(txid) != chainhash.HashSize {
+			return nil, fmt.Errorf("txid %d is %d bytes, want %d",
+				i, len(txid), chainhash.HashSize)
+		}
+	}
+
+	level := make([][]byte, len(txids), len(txids)+1)
+	copy(level, txids)
+
+	var proof [][]byte
+	var concat [chainhash.HashSize * 2]byte
+	for len(level) > 1 {
+		if len(level)%2 != 0 {
+			level = append(level, level[len(level)-1])
+		}
+		proof = append(proof, bytes.Clone(level[index^1]))
+
+		// Unlike CalculateMerkleRoot, the level can't be reduced in
+		// place since the first level is the caller's txids.
+		parents := make([][]byte, len(level)/2, len(level)/2+1)
+		for i := range parents {
+			copy(concat[:chainhash.HashSize], level[2*i])
+			copy(concat[chainhash.HashSize:], level[2*i+1])
+			parents[i] = doubleSha256(concat[:])
+		}
+		level = parents
+		index /= 2
+	}
+
+	return proof, nil
+}
+
+// VerifyMerkleProof returns whether the proof, as returned by MerkleProof,
+// shows that the txid is included at index in the merkle tree with the given
+// root.  The txid, root, and every hash of the proof must be 32 bytes in
+// internal byte order, and false is returned otherwise or when index is
+// outside of the tree described by the proof.
+//
+// Since the last hash of a level with an odd number of hashes is paired with
+// itself, a valid proof for the last transaction is also accepted at the
+// index of its duplicate, so callers must still ensure index is less than the
+// number of transactions in the block.
+func VerifyMerkleProof(txid []byte, proof [][]byte, root []byte,
+	index int) bool {
+
+	if len(txid) != chainhash.HashSize || len(root) != chainhash.HashSize ||
+		index < 0 {
+
+		return false
+	}
+
+	var hash [chainhash.HashSize]byte
+	var concat [chainhash.HashSize * 2]byte
+	copy(hash[:], txid)
+	for _, sibling := range proof {
+		if len(sibling) != chainhash.HashSize {
+			return false
+		}
+
+		if index%2 == 0 {
+			copy(concat[:chainhash.HashSize], hash[:])
+			copy(concat[chainhash.HashSize:], sibling)
+		} else {
+			copy(concat[:chainhash.HashSize], sibling)
+			copy(concat[chainhash.HashSize:], hash[:])
+		}
+		doubleSha256Into(hash[:], concat[:])
+		index /= 2
+	}
+
+	return index == 0 && bytes.Equal(hash[:], root)
+}
@@ -0,0 +1,259 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/stretchr/testify/require"
+)
+
+// txidsFromStrs converts display order txid strings into internal byte order
+// txids.
+func txidsFromStrs(t *testing.T, strs ...string) [][]byte {
+	t.Helper()
+
+	txids := make([][]byte, len(strs))
+	for i, str := range strs {
+		hash, err := chainhash.NewHashFromStr(str)
+		require.NoError(t, err)
+		txids[i] = hash[:]
+	}
+
+	return txids
+}
+
+// TestCalculateMerkleRoot ensures the merkle root is computed correctly for
+// the empty, single, odd, and even cases, and that txids which are not 32
+// bytes are rejected.
+func TestCalculateMerkleRoot(t *testing.T) {
+	t.Parallel()
+
+	// The transactions of mainnet block 100000.
+	block100000 := txidsFromStrs(t,
+		"8c14f0db3df150123e6f3dbbf30f8b955a8249b62ac1d1ff16284aefa3d06d87",
+		"fff2525b8931402dd09222c50775608f75787bd2b87e56995a7bdd30f79702c4",
+		"6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+		"e9a66845e05d5abc0ad04ec80f774a7e585c6e8db975962d069a522137b80c1d",
+	)
+	block100000Root := txidsFromStrs(t,
+		"f3e94742aca4b5ef85488dc37c06c3282295ffec960994b2c0d5ac2a25a95766",
+	)[0]
+
+	// With three txids, the last is paired with itself on the first level.
+	a, b, c := block100000[0], block100000[1], block100000[2]
+	ab := doubleSha256(append(append([]byte{}, a...), b...))
+	cc := doubleSha256(append(append([]byte{}, c...), c...))
+	oddRoot := doubleSha256(append(append([]byte{}, ab...), cc...))
+
+	tests := []struct {
+		name  string
+		txids [][]byte
+		want  []byte
+	}{
+		{
+			name:  "empty",
+			txids: [][]byte{},
+			want:  make([]byte, 32),
+		},
+		{
+			name:  "single",
+			txids: block100000[:1],
+			want:  block100000[0],
+		},
+		{
+			name:  "odd",
+			txids: block100000[:3],
+			want:  oddRoot,
+		},
+		{
+			name:  "block 100000",
+			txids: block100000,
+			want:  block100000Root,
+		},
+		{
+			name:  "single short txid",
+			txids: [][]byte{block100000[0][:31]},
+		},
+		{
+			name:  "short txid",
+			txids: [][]byte{a, b, c[:31]},
+		},
+		{
+			name:  "long txid",
+			txids: [][]byte{a, append(bytes.Clone(b), 0x00), c},
+		},
+		{
+			name:  "nil txid",
+			txids: [][]byte{a, nil},
+		},
+	}
+
+	for _, test := range tests {
+		// Deep copy the txids to ensure they are not modified.
+		orig := make([][]byte, 0, len(test.txids))
+		for _, txid := range test.txids {
+			orig = append(orig, append([]byte(nil), txid...))
+		}
+
+		got := CalculateMerkleRoot(test.txids)
+		require.Equal(t, test.want, got, test.name)
+		require.Equal(t, orig, test.txids, test.name)
+	}
+}
+
+// TestProcessBlockTxIDs ensures the txids and merkle root of serialized block
+// transactions are computed in one pass and that malformed blocks, including
+// those with duplicated transactions, are rejected.
+func TestProcessBlockTxIDs(t *testing.T) {
+	t.Parallel()
+
+	serialize := func(msgTxs ...*MsgTx) [][]byte {
+		raws := make([][]byte, len(msgTxs))
+		for i, msgTx := range msgTxs {
+			var buf bytes.Buffer
+			require.NoError(t, msgTx.Serialize(&buf))
+			raws[i] = buf.Bytes()
+		}
+		return raws
+	}
+
+	// The merkle root of block one is committed to by its header.
+	blockOneRaws := serialize(blockOne.Transactions...)
+	txids, root, err := ProcessBlockTxIDs(blockOneRaws)
+	require.NoError(t, err)
+	require.Len(t, txids, 1)
+	require.Equal(t, blockOne.Header.MerkleRoot[:], root)
+
+	msgTxs := []*MsgTx{
+		blockOne.Transactions[0], multiTx, multiWitnessTx,
+		v10TestMsgTx(t, true),
+	}
+	raws := serialize(msgTxs...)
+	txids, root, err = ProcessBlockTxIDs(raws)
+	require.NoError(t, err)
+	for i, msgTx := range msgTxs {
+		hash := msgTx.TxHash()
+		require.Equal(t, hash[:], txids[i])
+	}
+	require.Equal(t, CalculateMerkleRoot(txids), root)
+
+	// Repeating the trailing transactions of a block with an odd number of
+	// transactions doesn't change the merkle root, so it must be rejected.
+	odd := raws[:3]
+	_, oddRoot, err := ProcessBlockTxIDs(odd)
+	require.NoError(t, err)
+	mutated := append(odd[:3:3], odd[2])
+	require.Equal(t, oddRoot, CalculateMerkleRoot(append(
+		txids[:3:3], txids[2],
+	)))
+	_, _, err = ProcessBlockTxIDs(mutated)
+	require.ErrorContains(t, err, "transactions 2 and 3")
+
+	_, _, err = ProcessBlockTxIDs(nil)
+	require.Error(t, err)
+
+	truncated := append(raws[:1:1], raws[1][:len(raws[1])-1])
+	_, _, err = ProcessBlockTxIDs(truncated)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	trailing := append(raws[:1:1], append(bytes.Clone(raws[1]), 0x00))
+	_, _, err = ProcessBlockTxIDs(trailing)
+	require.ErrorContains(t, err, "trailing data")
+}
+
+// TestMerkleProof ensures the proof of every txid of trees of a range of sizes
+// verifies against the merkle root, that tampered proofs and other indices are
+// rejected, and that invalid arguments are reported.
+func TestMerkleProof(t *testing.T) {
+	t.Parallel()
+
+	for n := 1; n <= 9; n++ {
+		txids := make([][]byte, n)
+		for i := range txids {
+			txids[i] = doubleSha256([]byte{byte(n), byte(i)})
+		}
+		root := CalculateMerkleRoot(txids)
+
+		// The number of levels below the root is ceil(log2(n)).
+		var depth int
+		for 1<<depth < n {
+			depth++
+		}
+
+		for index, txid := range txids {
+			proof, err := MerkleProof(txids, index)
+			require.NoError(t, err, "n %d index %d", n, index)
+			require.Len(t, proof, depth, "n %d index %d", n, index)
+			require.True(t, VerifyMerkleProof(txid, proof, root,
+				index), "n %d index %d", n, index)
+
+			for other := range txids {
+				if other == index {
+					continue
+				}
+				require.False(t, VerifyMerkleProof(txid, proof,
+					root, other), "n %d index %d at %d", n,
+					index, other)
+			}
+			require.False(t, VerifyMerkleProof(txid, proof, root,
+				1<<depth), "n %d index %d", n, index)
+			wrongRoot := doubleSha256(root)
+			require.False(t, VerifyMerkleProof(txid, proof,
+				wrongRoot, index), "n %d index %d", n, index)
+
+			for i := range proof {
+				proof[i][0] ^= 0x01
+				require.False(t, VerifyMerkleProof(txid, proof,
+					root, index), "n %d index %d", n, index)
+				proof[i][0] ^= 0x01
+			}
+		}
+	}
+
+	// A proof from a real block must verify against its header.
+	block100000 := txidsFromStrs(t,
+		"8c14f0db3df150123e6f3dbbf30f8b955a8249b62ac1d1ff16284aefa3d06d87",
+		"fff2525b8931402dd09222c50775608f75787bd2b87e56995a7bdd30f79702c4",
+		"6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+		"e9a66845e05d5abc0ad04ec80f774a7e585c6e8db975962d069a522137b80c1d",
+	)
+	block100000Root := txidsFromStrs(t,
+		"f3e94742aca4b5ef85488dc37c06c3282295ffec960994b2c0d5ac2a25a95766",
+	)[0]
+	proof, err := MerkleProof(block100000, 2)
+	require.NoError(t, err)
+	require.Equal(t, block100000[3], proof[0])
+	require.True(t, VerifyMerkleProof(block100000[2], proof,
+		block100000Root, 2))
+
+	// The last txid of an odd level is its own sibling, so its proof
+	// also verifies at the index of its duplicate.
+	odd := block100000[:3]
+	proof, err = MerkleProof(odd, 2)
+	require.NoError(t, err)
+	require.Equal(t, odd[2], proof[0])
+	require.True(t, VerifyMerkleProof(odd[2], proof,
+		CalculateMerkleRoot(odd), 3))
+
+	_, err = MerkleProof(block100000, -1)
+	require.Error(t, err)
+	_, err = MerkleProof(block100000, len(block100000))
+	require.Error(t, err)
+	_, err = MerkleProof(nil, 0)
+	require.Error(t, err)
+	_, err = MerkleProof([][]byte{block100000[0], {0x01}}, 0)
+	require.Error(t, err)
+
+	require.False(t, VerifyMerkleProof(block100000[2], proof,
+		block100000Root, -1))
+	require.False(t, VerifyMerkleProof(block100000[2][1:], proof,
+		block100000Root, 2))
+	require.False(t, VerifyMerkleProof(block100000[2],
+		[][]byte{{0x01}}, block100000Root, 2))
+}
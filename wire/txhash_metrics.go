@@ -0,0 +1,64 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// txIDMetrics holds the hook set by SetTxIDMetrics.  A nil pointer means no
+// hook is set.
+var txIDMetrics atomic.Pointer[func(version uint32, elapsed time.Duration)]
+
+// SetTxIDMetrics sets a hook which is called after each txid computed by
+// CalculateTxID, CalculateTxIDErrWithOptions, CalculateTxIDInto, or
+// MsgTx.TxHash with the version of the transaction and the time taken to
+// compute its txid, so services can count the txids they compute and how many
+// use the layered scheme, such as with Prometheus counters and histograms,
+// without wrapping every call site.  Each of them reports every txid once,
+// whether it is hashed over the raw bytes or with a registered TxIDStrategy,
+// including those computed by MsgTx.TxID and by MsgTx.WitnessHash for a
+// transaction without witness data.  The functions which compute txids
+// through them, such as CalculateTxIDErr, CalculateTxIDBoth, and
+// CalculateMsgTxID, invoke it as well, while those with separate
+// implementations, such as CalculateTxIDs and Transaction.TxID, do not.  The
+// hook is still called when no txid could be computed, including when
+// CalculateTxIDErr rejects the transaction, except for a nil transaction,
+// which has no version.
+//
+// The hook is called on the goroutine computing the txid, so it must be safe
+// for concurrent use and should be cheap.  Passing nil removes the hook, in
+// which case none of them reads the clock and the only overhead is a single
+// atomic load.  It is safe to call SetTxIDMetrics concurrently with computing
+// txids.
+func SetTxIDMetrics(onHash func(version uint32, elapsed time.Duration)) {
+	if onHash == nil {
+		txIDMetrics.Store(nil)
+		return
+	}
+	txIDMetrics.Store(&onHash)
+}
+
+// startTxIDMetrics returns the hook set by SetTxIDMetrics along with the time
+// computing a txid started, or nil and the zero time when no hook is set, so
+// the clock is only read when the elapsed time is reported.
+func startTxIDMetrics() (*func(uint32, time.Duration), time.Time) {
+	onHash := txIDMetrics.Load()
+	if onHash == nil {
+		return nil, time.Time{}
+	}
+	return onHash, time.Now()
+}
+
+// reportTxIDMetrics calls the hook returned by startTxIDMetrics, if any, with
+// the version of the transaction and the time elapsed since start.
+func reportTxIDMetrics(onHash *func(uint32, time.Duration), version uint32,
+	start time.Time) {
+
+	if onHash != nil {
+		(*onHash)(version, time.Since(start))
+	}
+}
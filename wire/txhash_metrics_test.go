@@ -0,0 +1,185 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// restoreTxIDMetrics arranges for the hook set by SetTxIDMetrics to be removed
+// once the test or benchmark completes.  Tests which set it must not be run in
+// parallel.
+func restoreTxIDMetrics(tb testing.TB) {
+	tb.Helper()
+	tb.Cleanup(func() {
+		SetTxIDMetrics(nil)
+	})
+}
+
+// TestSetTxIDMetrics ensures the hook is called once for each txid computed by
+// CalculateTxID or MsgTx.TxHash with the version of the transaction,
+// including by functions built on them, and is no longer called once removed.
+func TestSetTxIDMetrics(t *testing.T) {
+	restoreTxIDMetrics(t)
+
+	var mtx sync.Mutex
+	counts := make(map[uint32]int)
+	SetTxIDMetrics(func(version uint32, elapsed time.Duration) {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		require.GreaterOrEqual(t, elapsed, time.Duration(0))
+		counts[version]++
+	})
+
+	v10 := v10TestTx()
+	standard := ConvertWireMsgTxToCommonTransaction(multiTx)
+	raw := mustBytes(t, standard)
+
+	want := CalculateV10TxID(v10)
+	require.Equal(t, want, CalculateTxID(nil, v10))
+	require.Equal(t, want, CalculateTxID(nil, v10))
+	require.NotNil(t, CalculateTxID(raw, standard))
+	internal, _ := CalculateTxIDBoth(raw, standard)
+	require.NotNil(t, internal)
+
+	// A txid which can't be computed is still reported.
+	shortHash := v10TestTx()
+	shortHash.TxIn[0].Hash = nil
+	require.Nil(t, CalculateTxID(nil, shortHash))
+
+	// Hashes generated by MsgTx.TxHash are reported for both the standard
+	// and the layered scheme, and only once each.
+	_ = multiTx.TxHash()
+	_ = v10TestMsgTx(t, false).TxHash()
+	_ = multiTx.TxID()
+
+	wantCounts := map[uint32]int{
+		LayeredTxIDVersion:      4,
+		uint32(multiTx.Version): 4,
+	}
+	require.Equal(t, wantCounts, counts)
+
+	SetTxIDMetrics(nil)
+	require.Equal(t, want, CalculateTxID(nil, v10))
+	_, err := CalculateTxIDErr(nil, v10)
+	require.NoError(t, err)
+	_ = multiTx.TxHash()
+	require.Equal(t, wantCounts, counts)
+}
+
+// TestTxIDMetricsEntryPoints ensures every entry point documented by
+// SetTxIDMetrics reports each txid it computes exactly once for both the
+// standard and the layered scheme.
+func TestTxIDMetricsEntryPoints(t *testing.T) {
+	restoreTxIDMetrics(t)
+
+	v10 := v10TestTx()
+	v10MsgTx := v10TestMsgTx(t, false)
+	standard := ConvertWireMsgTxToCommonTransaction(multiTx)
+	raw := mustBytes(t, standard)
+
+	var dst [32]byte
+	entryPoints := []struct {
+		name string
+		calc func(raw []byte, tx *Transaction, msgTx *MsgTx)
+	}{{
+		name: "CalculateTxID",
+		calc: func(raw []byte, tx *Transaction, _ *MsgTx) {
+			require.NotNil(t, CalculateTxID(raw, tx))
+		},
+	}, {
+		name: "CalculateTxIDErr",
+		calc: func(raw []byte, tx *Transaction, _ *MsgTx) {
+			_, err := CalculateTxIDErr(raw, tx)
+			require.NoError(t, err)
+		},
+	}, {
+		name: "CalculateTxIDInto",
+		calc: func(raw []byte, tx *Transaction, _ *MsgTx) {
+			require.NoError(t, CalculateTxIDInto(dst[:], raw, tx))
+		},
+	}, {
+		name: "CalculateMsgTxID",
+		calc: func(_ []byte, _ *Transaction, msgTx *MsgTx) {
+			_, err := CalculateMsgTxID(msgTx)
+			require.NoError(t, err)
+		},
+	}, {
+		name: "MsgTx.TxHash",
+		calc: func(_ []byte, _ *Transaction, msgTx *MsgTx) {
+			_ = msgTx.TxHash()
+		},
+	}}
+
+	for _, entryPoint := range entryPoints {
+		counts := make(map[uint32]int)
+		SetTxIDMetrics(func(version uint32, _ time.Duration) {
+			counts[version]++
+		})
+
+		entryPoint.calc(raw, standard, multiTx)
+		entryPoint.calc(nil, v10, v10MsgTx)
+
+		wantCounts := map[uint32]int{
+			LayeredTxIDVersion:      1,
+			uint32(multiTx.Version): 1,
+		}
+		require.Equal(t, wantCounts, counts, entryPoint.name)
+	}
+
+	// A transaction rejected by CalculateTxIDErr is still reported, while
+	// a nil transaction is not.
+	var count int
+	SetTxIDMetrics(func(uint32, time.Duration) { count++ })
+	shortHash := v10TestTx()
+	shortHash.TxIn[0].Hash = nil
+	_, err := CalculateTxIDErr(nil, shortHash)
+	require.Error(t, err)
+	_, err = CalculateTxIDErr(nil, nil)
+	require.Error(t, err)
+	require.Error(t, CalculateTxIDInto(dst[:], nil, nil))
+	require.Equal(t, 1, count)
+}
+
+// BenchmarkCalculateTxIDMetrics benchmarks computing txids with and without a
+// hook set by SetTxIDMetrics, which shows the overhead of timing each txid
+// when a hook is set and that there is none to speak of otherwise.
+func BenchmarkCalculateTxIDMetrics(b *testing.B) {
+	restoreTxIDMetrics(b)
+
+	v10 := v10TestTx()
+	standard := ConvertWireMsgTxToCommonTransaction(multiTx)
+	raw, err := standard.Bytes()
+	require.NoError(b, err)
+
+	var count int
+	hooks := []struct {
+		name   string
+		onHash func(uint32, time.Duration)
+	}{
+		{name: "unset"},
+		{name: "set", onHash: func(uint32, time.Duration) { count++ }},
+	}
+	for _, hook := range hooks {
+		SetTxIDMetrics(hook.onHash)
+		b.Run(hook.name+"/v10", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = CalculateTxID(nil, v10)
+			}
+		})
+		b.Run(hook.name+"/standard", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = CalculateTxID(raw, standard)
+			}
+		})
+	}
+}
@@ -0,0 +1,428 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+
+	"github.com/btcsuite/btcd/btcutil/base58"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// The opcodes needed to recognize the standard public key script forms.  They
+// are duplicated here from txscript since that package depends on this one.
+const (
+	op0             = 0x00
+	opData20        = 0x14
+	opData33        = 0x21
+	opData65        = 0x41
+	opPushData1     = 0x4c
+	opPushData2     = 0x4d
+	opPushData4     = 0x4e
+	op1             = 0x51
+	op16            = 0x60
+	opReturn        = 0x6a
+	opDup           = 0x76
+	opEqual         = 0x87
+	opEqualVerify   = 0x88
+	opHash160       = 0xa9
+	opCheckSig      = 0xac
+	opCheckMultiSig = 0xae
+)
+
+// maxNullDataSize is the maximum number of bytes a standard null data script
+// may push.  It is the same as txscript.MaxDataCarrierSize.
+const maxNullDataSize = 80
+
+// ScriptClass is an enumeration for the standard types of public key script
+// recognized by PkScript.ScriptType.  The values and names match those of
+// txscript.ScriptClass for the classes both recognize.
+type ScriptClass byte
+
+// Classes of public key script recognized by PkScript.ScriptType.
+const (
+	NonStandardTy ScriptClass = iota // None of the recognized forms.
+	PubKeyTy                         // Pay pubkey.
+	PubKeyHashTy                     // Pay pubkey hash.
+	ScriptHashTy                     // Pay to script hash.
+	MultiSigTy                       // Multi signature.
+	NullDataTy                       // Empty data-only (provably prunable).
+)
+
+// scriptClassToName houses the human-readable strings which describe each
+// script class.
+var scriptClassToName = []string{
+	NonStandardTy: "nonstandard",
+	PubKeyTy:      "pubkey",
+	PubKeyHashTy:  "pubkeyhash",
+	ScriptHashTy:  "scripthash",
+	MultiSigTy:    "multisig",
+	NullDataTy:    "nulldata",
+}
+
+// String returns the name of the script class, or "Invalid" if it is not one
+// of the defined classes.
+func (c ScriptClass) String() string {
+	if int(c) >= len(scriptClassToName) {
+		return "Invalid"
+	}
+	return scriptClassToName[c]
+}
+
+// ScriptType returns the class of the public key script.  NonStandardTy is
+// returned for scripts which are not one of the recognized forms, including
+// those which can't be parsed.
+func (p PkScript) ScriptType() ScriptClass {
+	script := p.Pkscript
+	switch {
+	case extractPubKey(script) != nil:
+		return PubKeyTy
+	case extractPubKeyHash(script) != nil:
+		return PubKeyHashTy
+	case extractScriptHash(script) != nil:
+		return ScriptHashTy
+	case isMultiSigScript(script):
+		return MultiSigTy
+	case isNullDataScript(script):
+		return NullDataTy
+	default:
+		return NonStandardTy
+	}
+}
+
+// Equal returns whether the two public key scripts consist of the same bytes.
+// Nil and empty scripts are considered equal.
+func (p PkScript) Equal(other PkScript) bool {
+	return bytes.Equal(p.Pkscript, other.Pkscript)
+}
+
+// Key returns the bytes of the public key script as a string, which can be used
+// as a map key to aggregate outputs by script since a PkScript itself is not
+// comparable.  Scripts have the same key exactly when they are Equal.  The
+// key is an arbitrary binary string and is not suitable for display.
+func (p PkScript) Key() string {
+	return string(p.Pkscript)
+}
+
+// Hash160 returns the RIPEMD160 hash of the SHA256 hash of the public key
+// script.  This is the hash a pay-to-script-hash output committing to the
+// script would contain, and provides a fixed size 20 byte key which is useful
+// for indexing outputs by script.
+func (p PkScript) Hash160() []byte {
+	return hash160(p.Pkscript)
+}
+
+// extractPubKey returns the public key of a standard pay-to-pubkey script with
+// either a compressed or uncompressed key, or nil otherwise.
+func extractPubKey(script []byte) []byte {
+	// A pay-to-pubkey script is of the form:
+	//  OP_DATA_33 <33-byte compressed pubkey> OP_CHECKSIG
+	//  OP_DATA_65 <65-byte uncompressed pubkey> OP_CHECKSIG
+	switch {
+	case len(script) == 35 &&
+		script[0] == opData33 &&
+		script[34] == opCheckSig:
+
+		return checkPubKey(script[1:34])
+
+	case len(script) == 67 &&
+		script[0] == opData65 &&
+		script[66] == opCheckSig:
+
+		return checkPubKey(script[1:66])
+	}
+
+	return nil
+}
+
+// checkPubKey returns the passed data if it has the length and leading byte
+// of a compressed, uncompressed, or hybrid secp256k1 public key, and nil
+// otherwise.
+func checkPubKey(data []byte) []byte {
+	switch {
+	case len(data) == 33 && (data[0] == 0x02 || data[0] == 0x03):
+		return data
+	case len(data) == 65 &&
+		(data[0] == 0x04 || data[0] == 0x06 || data[0] == 0x07):
+
+		return data
+	}
+
+	return nil
+}
+
+// extractPubKeyHash returns the public key hash of a standard
+// pay-to-pubkey-hash script, or nil otherwise.
+func extractPubKeyHash(script []byte) []byte {
+	// A pay-to-pubkey-hash script is of the form:
+	//  OP_DUP OP_HASH160 OP_DATA_20 <20-byte hash>
+	//  OP_EQUALVERIFY OP_CHECKSIG
+	if len(script) == 25 &&
+		script[0] == opDup &&
+		script[1] == opHash160 &&
+		script[2] == opData20 &&
+		script[23] == opEqualVerify &&
+		script[24] == opCheckSig {
+
+		return script[3:23]
+	}
+
+	return nil
+}
+
+// extractScriptHash returns the script hash of a standard pay-to-script-hash
+// script, or nil otherwise.
+func extractScriptHash(script []byte) []byte {
+	// A pay-to-script-hash script is of the form:
+	//  OP_HASH160 OP_DATA_20 <20-byte scripthash> OP_EQUAL
+	if len(script) == 23 &&
+		script[0] == opHash160 &&
+		script[1] == opData20 &&
+		script[22] == opEqual {
+
+		return script[2:22]
+	}
+
+	return nil
+}
+
+// isSmallInt returns whether the opcode pushes a small integer, OP_0 through
+// OP_16.
+func isSmallInt(op byte) bool {
+	return op == op0 || (op >= op1 && op <= op16)
+}
+
+// asSmallInt returns the integer pushed by a small integer opcode.
+func asSmallInt(op byte) int {
+	if op == op0 {
+		return 0
+	}
+	return int(op - (op1 - 1))
+}
+
+// nextOpcode parses the opcode at the start of script, returning it along with
+// any data it pushes and the remainder of the script.  ok is false if the
+// script is empty or ends part way through a push.
+func nextOpcode(script []byte) (op byte, data, rest []byte, ok bool) {
+	if len(script) == 0 {
+		return 0, nil, nil, false
+	}
+	op, script = script[0], script[1:]
+
+	var n, lenBytes int
+	switch {
+	case op > op0 && op < opPushData1:
+		n = int(op)
+	case op == opPushData1:
+		lenBytes = 1
+	case op == opPushData2:
+		lenBytes = 2
+	case op == opPushData4:
+		lenBytes = 4
+	default:
+		return op, nil, script, true
+	}
+
+	if lenBytes > 0 {
+		if len(script) < lenBytes {
+			return 0, nil, nil, false
+		}
+		var l uint32
+		for i := lenBytes - 1; i >= 0; i-- {
+			l = l<<8 | uint32(script[i])
+		}
+		if uint64(l) > uint64(len(script)-lenBytes) {
+			return 0, nil, nil, false
+		}
+		n, script = int(l), script[lenBytes:]
+	}
+	if n > len(script) {
+		return 0, nil, nil, false
+	}
+
+	return op, script[:n], script[n:], true
+}
+
+// extractMultiSigPubKeys returns the number of required signatures and every
+// pushed public key of a standard multisig script.  ok is false if the script
+// is not a standard multisig script.
+func extractMultiSigPubKeys(script []byte) (requiredSigs int,
+	pubKeys [][]byte, ok bool) {
+
+	// A multi-signature script is of the form:
+	//  NUM_SIGS PUBKEY PUBKEY PUBKEY ... NUM_PUBKEYS OP_CHECKMULTISIG
+	if len(script) < 3 || script[len(script)-1] != opCheckMultiSig {
+		return 0, nil, false
+	}
+
+	op, _, rest, ok := nextOpcode(script)
+	if !ok || !isSmallInt(op) {
+		return 0, nil, false
+	}
+	requiredSigs = asSmallInt(op)
+
+	// The public keys are every push up to the small integer specifying
+	// their number.  Like txscript, every push is counted toward that
+	// number even if it does not look like a valid public key.
+	for {
+		var data []byte
+		op, data, rest, ok = nextOpcode(rest)
+		if !ok {
+			return 0, nil, false
+		}
+		if isSmallInt(op) {
+			break
+		}
+		pubKeys = append(pubKeys, data)
+	}
+
+	// The number of public keys must match and be followed only by the
+	// final OP_CHECKMULTISIG.
+	if asSmallInt(op) != len(pubKeys) || len(rest) != 1 {
+		return 0, nil, false
+	}
+
+	return requiredSigs, pubKeys, true
+}
+
+// isMultiSigScript returns whether the script is a standard multisig script.
+func isMultiSigScript(script []byte) bool {
+	_, _, ok := extractMultiSigPubKeys(script)
+	return ok
+}
+
+// isNullDataScript returns whether the script is a standard null data script.
+func isNullDataScript(script []byte) bool {
+	// A null data script is of the form:
+	//  OP_RETURN <optional data>
+	//
+	// Thus, it can either be a single OP_RETURN or an OP_RETURN followed
+	// by a single push of up to maxNullDataSize bytes.
+	if len(script) < 1 || script[0] != opReturn {
+		return false
+	}
+	if len(script) == 1 {
+		return true
+	}
+
+	op, data, rest, ok := nextOpcode(script[1:])
+	return ok && len(rest) == 0 &&
+		(isSmallInt(op) || op <= opPushData4) &&
+		len(data) <= maxNullDataSize
+}
+
+// isUnspendable returns whether the public key script is provably
+// unspendable, meaning it begins with OP_RETURN.  This is true of every
+// NullDataTy script as well as nonstandard scripts which follow OP_RETURN
+// with arbitrary data.
+func (p PkScript) isUnspendable() bool {
+	return len(p.Pkscript) > 0 && p.Pkscript[0] == opReturn
+}
+
+// NullDataPayload returns the data carried by a standard null data script,
+// which is an OP_RETURN optionally followed by a single push of at most 80
+// bytes, and true.  The payload of a bare OP_RETURN is empty, and that of a
+// small integer push, OP_0 through OP_16, is its minimal encoding.  The
+// returned payload aliases the script.
+//
+// False is returned for any script which is not standard null data, such as
+// one with multiple pushes or with non-push opcodes after the OP_RETURN.
+func (p PkScript) NullDataPayload() ([]byte, bool) {
+	if !isNullDataScript(p.Pkscript) {
+		return nil, false
+	}
+	if len(p.Pkscript) == 1 {
+		return p.Pkscript[1:], true
+	}
+
+	op, data, _, _ := nextOpcode(p.Pkscript[1:])
+	switch {
+	case op == op0:
+		return p.Pkscript[2:], true
+	case op >= op1 && op <= op16:
+		return []byte{byte(asSmallInt(op))}, true
+	default:
+		return data, true
+	}
+}
+
+// AddressParams houses the network specific prefixes used to encode the
+// addresses extracted by PkScript.ExtractAddresses.  It mirrors the fields of
+// the same name in chaincfg.Params, which can't be referenced here since
+// chaincfg depends on this package, so callers typically populate it from
+// those, for example:
+//
+//	params := &wire.AddressParams{
+//		PubKeyHashAddrID: chaincfg.MainNetParams.PubKeyHashAddrID,
+//		ScriptHashAddrID: chaincfg.MainNetParams.ScriptHashAddrID,
+//	}
+type AddressParams struct {
+	// PubKeyHashAddrID is the version byte of pay-to-pubkey-hash
+	// addresses.
+	PubKeyHashAddrID byte
+
+	// ScriptHashAddrID is the version byte of pay-to-script-hash addresses.
+	ScriptHashAddrID byte
+}
+
+// ExtractAddresses returns the base58check encoded addresses paid to by the
+// public key script for the network described by params.
+//
+// Pay-to-pubkey-hash and pay-to-script-hash scripts produce a single address
+// of the respective kind.  Pay-to-pubkey scripts, and each public key of a
+// multisig script, produce the pay-to-pubkey-hash address of the public key
+// as it is encoded in the script.  Public keys in a multisig script which are
+// not validly encoded are skipped.  No addresses are returned for null data
+// and non-standard scripts.
+func (p PkScript) ExtractAddresses(params *AddressParams) ([]string, error) {
+	if params == nil {
+		return nil, errors.New("nil address params")
+	}
+
+	script := p.Pkscript
+	switch p.ScriptType() {
+	case PubKeyTy:
+		pkHash := hash160(extractPubKey(script))
+		return []string{
+			base58.CheckEncode(pkHash, params.PubKeyHashAddrID),
+		}, nil
+
+	case PubKeyHashTy:
+		return []string{base58.CheckEncode(
+			extractPubKeyHash(script), params.PubKeyHashAddrID,
+		)}, nil
+
+	case ScriptHashTy:
+		return []string{base58.CheckEncode(
+			extractScriptHash(script), params.ScriptHashAddrID,
+		)}, nil
+
+	case MultiSigTy:
+		_, pubKeys, _ := extractMultiSigPubKeys(script)
+		addrs := make([]string, 0, len(pubKeys))
+		for _, pubKey := range pubKeys {
+			if checkPubKey(pubKey) == nil {
+				continue
+			}
+			addrs = append(addrs, base58.CheckEncode(
+				hash160(pubKey), params.PubKeyHashAddrID,
+			))
+		}
+		return addrs, nil
+
+	default:
+		return nil, nil
+	}
+}
+
+// hash160 returns the RIPEMD160 hash of the SHA256 hash of b.
+func hash160(b []byte) []byte {
+	sha := sha256.Sum256(b)
+	h := ripemd160.New()
+	h.Write(sha[:])
+	return h.Sum(nil)
+}
@@ -0,0 +1,358 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// hexScript decodes the passed hex string into a script, panicking if it is
+// not valid hex.  It is only intended for hard-coded test scripts.
+func hexScript(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// The public keys used to build the test scripts.
+var (
+	testCompressedPubKey = hexScript("02192d74d0cb94344c9569c2e77901" +
+		"573d8d7903c3ebec3a957724895dca52c6b4")
+	testUncompressedPubKey = hexScript("0411db93e1dcdb8a016b49840f8c53" +
+		"bc1eb68a382e97b1482ecad7b148a6909a5cb2e0eaddfb84ccf974" +
+		"4464f82e160bfa9b8b64f9d4c03f999b8643f656b412a3")
+)
+
+// cat concatenates the passed opcodes and data into a script.
+func cat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+// multiSigScript builds a multisig script requiring m of the passed keys.
+func multiSigScript(m int, keys ...[]byte) []byte {
+	script := []byte{byte(op1 - 1 + m)}
+	for _, key := range keys {
+		script = append(script, byte(len(key)))
+		script = append(script, key...)
+	}
+	return append(script, byte(op1-1+len(keys)), opCheckMultiSig)
+}
+
+// TestPkScriptScriptType ensures the standard public key script forms are
+// classified correctly and that malformed variants of them are rejected.
+func TestPkScriptScriptType(t *testing.T) {
+	t.Parallel()
+
+	compressed := testCompressedPubKey
+	uncompressed := testUncompressedPubKey
+	hash20 := bytes.Repeat([]byte{0xab}, 20)
+
+	var (
+		checkSig = []byte{opCheckSig}
+		p2pkh    = cat([]byte{opDup, opHash160, opData20}, hash20,
+			[]byte{opEqualVerify, opCheckSig})
+		p2sh = cat([]byte{opHash160, opData20}, hash20,
+			[]byte{opEqual})
+		maxData      = make([]byte, maxNullDataSize)
+		tooMuchData  = make([]byte, maxNullDataSize+1)
+		badKeyPrefix = cat([]byte{0x05}, compressed[1:])
+		oneOfOne     = multiSigScript(1, compressed)
+	)
+
+	tests := []struct {
+		name   string
+		script []byte
+		want   ScriptClass
+	}{{
+		name:   "empty",
+		script: nil,
+		want:   NonStandardTy,
+	}, {
+		name:   "p2pk compressed",
+		script: cat([]byte{opData33}, compressed, checkSig),
+		want:   PubKeyTy,
+	}, {
+		name:   "p2pk uncompressed",
+		script: cat([]byte{opData65}, uncompressed, checkSig),
+		want:   PubKeyTy,
+	}, {
+		name:   "p2pk bad key prefix",
+		script: cat([]byte{opData33}, badKeyPrefix, checkSig),
+		want:   NonStandardTy,
+	}, {
+		name:   "p2pkh",
+		script: p2pkh,
+		want:   PubKeyHashTy,
+	}, {
+		name:   "p2pkh truncated",
+		script: p2pkh[:len(p2pkh)-1],
+		want:   NonStandardTy,
+	}, {
+		name:   "p2sh",
+		script: p2sh,
+		want:   ScriptHashTy,
+	}, {
+		name:   "multisig 1 of 2",
+		script: multiSigScript(1, compressed, uncompressed),
+		want:   MultiSigTy,
+	}, {
+		name:   "multisig wrong key count",
+		script: cat(oneOfOne[:35], []byte{op1 + 1, opCheckMultiSig}),
+		want:   NonStandardTy,
+	}, {
+		name:   "multisig truncated push",
+		script: []byte{op1, opData33, 0x02, op1, opCheckMultiSig},
+		want:   NonStandardTy,
+	}, {
+		name:   "bare op_return",
+		script: []byte{opReturn},
+		want:   NullDataTy,
+	}, {
+		name:   "op_return small int",
+		script: []byte{opReturn, op1},
+		want:   NullDataTy,
+	}, {
+		name: "op_return max data",
+		script: cat([]byte{opReturn, opPushData1, maxNullDataSize},
+			maxData),
+		want: NullDataTy,
+	}, {
+		name: "op_return too much data",
+		script: cat([]byte{opReturn, opPushData1, maxNullDataSize + 1},
+			tooMuchData),
+		want: NonStandardTy,
+	}, {
+		name:   "op_return two pushes",
+		script: []byte{opReturn, 0x01, 0xff, 0x01, 0xff},
+		want:   NonStandardTy,
+	}, {
+		name:   "op_return non push",
+		script: []byte{opReturn, opDup},
+		want:   NonStandardTy,
+	}}
+
+	for _, test := range tests {
+		pkScript := PkScript{Pkscript: test.script}
+		require.Equal(t, test.want, pkScript.ScriptType(), test.name)
+	}
+}
+
+// TestScriptClassString ensures every script class has the expected name.
+func TestScriptClassString(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		class ScriptClass
+		want  string
+	}{
+		{NonStandardTy, "nonstandard"},
+		{PubKeyTy, "pubkey"},
+		{PubKeyHashTy, "pubkeyhash"},
+		{ScriptHashTy, "scripthash"},
+		{MultiSigTy, "multisig"},
+		{NullDataTy, "nulldata"},
+		{NullDataTy + 1, "Invalid"},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.want, test.class.String())
+	}
+}
+
+// TestPkScriptExtractAddresses ensures the addresses of the standard public
+// key script forms are extracted and encoded for the requested network.
+func TestPkScriptExtractAddresses(t *testing.T) {
+	t.Parallel()
+
+	mainNet := &AddressParams{
+		PubKeyHashAddrID: 0x00,
+		ScriptHashAddrID: 0x05,
+	}
+	testNet := &AddressParams{
+		PubKeyHashAddrID: 0x6f,
+		ScriptHashAddrID: 0xc4,
+	}
+
+	pkHash := hexScript("e34cce70c86373273efcc54ce7d2a491bb4a0e84")
+	scriptHash := hexScript("f815b036d9bbbce5e9f2a00abd1bf3dc91e95510")
+	p2pkh := cat([]byte{opDup, opHash160, opData20}, pkHash,
+		[]byte{opEqualVerify, opCheckSig})
+	p2sh := cat([]byte{opHash160, opData20}, scriptHash, []byte{opEqual})
+	p2pk := cat([]byte{opData33}, testCompressedPubKey, []byte{opCheckSig})
+
+	// The second key of the multisig script is not a valid public key, so
+	// it is skipped.
+	badKey := bytes.Repeat([]byte{0x05}, 33)
+	multiSig := multiSigScript(
+		1, testCompressedPubKey, badKey, testUncompressedPubKey,
+	)
+
+	tests := []struct {
+		name   string
+		script []byte
+		params *AddressParams
+		want   []string
+	}{{
+		name:   "p2pkh mainnet",
+		script: p2pkh,
+		params: mainNet,
+		want:   []string{"1MirQ9bwyQcGVJPwKUgapu5ouK2E2Ey4gX"},
+	}, {
+		name:   "p2pkh testnet",
+		script: p2pkh,
+		params: testNet,
+		want:   []string{"n2EohCgvnS3XGQsZ33exepJ8mJcvujsjzm"},
+	}, {
+		name:   "p2sh mainnet",
+		script: p2sh,
+		params: mainNet,
+		want:   []string{"3QJmV3qfvL9SuYo34YihAf3sRCW3qSinyC"},
+	}, {
+		name:   "p2sh testnet",
+		script: p2sh,
+		params: testNet,
+		want:   []string{"2NFryYnmhXneo7LRajgLZnc38dYiDePvf3G"},
+	}, {
+		name:   "p2pk",
+		script: p2pk,
+		params: mainNet,
+		want:   []string{"13CG6SJ3yHUXo4Cr2RY4THLLJrNFuG3gUg"},
+	}, {
+		name:   "multisig",
+		script: multiSig,
+		params: testNet,
+		want: []string{
+			"mhiDPVP2nJunaAgTjzWSHCYfAqxxrxzjmo",
+			"mh8YhPYEAYs3E7EVyKtB5xrcfMExkkdEMF",
+		},
+	}, {
+		name:   "nulldata",
+		script: []byte{opReturn, 0x01, 0xff},
+		params: mainNet,
+		want:   nil,
+	}, {
+		name:   "nonstandard",
+		script: []byte{opDup},
+		params: mainNet,
+		want:   nil,
+	}}
+
+	for _, test := range tests {
+		pkScript := PkScript{Pkscript: test.script}
+		addrs, err := pkScript.ExtractAddresses(test.params)
+		require.NoError(t, err, test.name)
+		require.Equal(t, test.want, addrs, test.name)
+	}
+
+	_, err := PkScript{Pkscript: p2pkh}.ExtractAddresses(nil)
+	require.Error(t, err)
+}
+
+// TestPkScriptNullDataPayload ensures the payload of standard null data
+// scripts is extracted and that other scripts are rejected.
+func TestPkScriptNullDataPayload(t *testing.T) {
+	t.Parallel()
+
+	payload := bytes.Repeat([]byte{0x42}, maxNullDataSize)
+	tests := []struct {
+		name   string
+		script []byte
+		want   []byte
+		wantOK bool
+	}{{
+		name:   "bare op_return",
+		script: []byte{opReturn},
+		want:   []byte{},
+		wantOK: true,
+	}, {
+		name:   "direct push",
+		script: []byte{opReturn, 0x03, 0x01, 0x02, 0x03},
+		want:   []byte{0x01, 0x02, 0x03},
+		wantOK: true,
+	}, {
+		name: "pushdata1 max size",
+		script: cat([]byte{opReturn, opPushData1, maxNullDataSize},
+			payload),
+		want:   payload,
+		wantOK: true,
+	}, {
+		name:   "op_0",
+		script: []byte{opReturn, op0},
+		want:   []byte{},
+		wantOK: true,
+	}, {
+		name:   "op_16",
+		script: []byte{opReturn, op16},
+		want:   []byte{16},
+		wantOK: true,
+	}, {
+		name:   "multiple pushes",
+		script: []byte{opReturn, 0x01, 0xaa, 0x01, 0xbb},
+	}, {
+		name:   "truncated push",
+		script: []byte{opReturn, 0x05, 0x01},
+	}, {
+		name:   "non push opcode",
+		script: []byte{opReturn, opDup},
+	}, {
+		name: "push too large",
+		script: cat([]byte{opReturn, opPushData1, maxNullDataSize + 1},
+			payload, []byte{0x42}),
+	}, {
+		name:   "op_return not first",
+		script: []byte{op1, opReturn},
+	}, {
+		name:   "empty script",
+		script: nil,
+	}}
+
+	for _, test := range tests {
+		pkScript := PkScript{Pkscript: test.script}
+		got, ok := pkScript.NullDataPayload()
+		require.Equal(t, test.wantOK, ok, test.name)
+		require.Equal(t, test.want, got, test.name)
+	}
+}
+
+// TestPkScriptKey ensures scripts can be compared, used to aggregate values in
+// a map, and hashed.
+func TestPkScriptKey(t *testing.T) {
+	t.Parallel()
+
+	p2pk := cat([]byte{opData33}, testCompressedPubKey, []byte{opCheckSig})
+	a := PkScript{Pkscript: p2pk}
+	b := PkScript{Pkscript: bytes.Clone(p2pk)}
+	c := PkScript{Pkscript: p2pk[:len(p2pk)-1]}
+
+	require.True(t, a.Equal(b))
+	require.False(t, a.Equal(c))
+	require.True(t, PkScript{}.Equal(PkScript{Pkscript: []byte{}}))
+	require.Equal(t, a.Key(), b.Key())
+	require.NotEqual(t, a.Key(), c.Key())
+
+	values := make(map[string]uint64)
+	outputs := []TxOutput{
+		{Value: 1, PkScript: a}, {Value: 2, PkScript: c},
+		{Value: 4, PkScript: b},
+	}
+	for _, out := range outputs {
+		values[out.PkScript.Key()] += out.Value
+	}
+	require.Equal(t, map[string]uint64{a.Key(): 5, c.Key(): 2}, values)
+
+	// The hash of a redeem script is what a P2SH script commits to.
+	p2sh := PkScript{Pkscript: cat(
+		[]byte{opHash160, opData20}, a.Hash160(), []byte{opEqual},
+	)}
+	require.Equal(t, ScriptHashTy, p2sh.ScriptType())
+	require.Equal(t, "b472a266d0bd89c13706a4132ccfb16f7c3b9fcb",
+		hex.EncodeToString(PkScript{}.Hash160()))
+}
@@ -0,0 +1,406 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// CalculateTxIDFromReader reads exactly one serialized transaction from r and
+// returns its txid in internal byte order, avoiding deserializing it into a
+// MsgTx or Transaction where possible.
+//
+// For transactions hashed over their raw bytes, the bytes are streamed
+// through the double sha256 as they are read, so no part of the transaction is
+// held in memory.  When the transaction uses the witness serialization, the
+// marker, flag, and witness data are not hashed so the result is the txid
+// rather than the wtxid.  For version 10 transactions using the built-in
+// strategy, only enough of each input and output is parsed to feed the
+// layered hash described by CalculateV10TxID, and scripts are again hashed as
+// they are read.  Any other
+// registered TxIDStrategy requires the transaction to be fully deserialized.
+//
+// The reader is left positioned immediately after the transaction.
+// io.ErrUnexpectedEOF is returned if the stream ends part way through it.
+func CalculateTxIDFromReader(r io.Reader) ([]byte, error) {
+	s := &txStreamReader{r: r}
+
+	if _, err := io.ReadFull(r, s.buf[:4]); err != nil {
+		return nil, err
+	}
+	version := littleEndian.Uint32(s.buf[:4])
+
+	// A zero input count is the marker that indicates the witness
+	// serialization, and it is then followed by the flag and the real
+	// input count.
+	numIn, err := s.readVarInt()
+	if err != nil {
+		return nil, err
+	}
+	var hasWitness bool
+	if numIn == TxFlagMarker {
+		if err := s.readFull(s.buf[:1]); err != nil {
+			return nil, err
+		}
+		if s.buf[0] != WitnessFlag {
+			str := fmt.Sprintf("witness tx but flag byte is %x",
+				s.buf[0])
+			return nil, messageError("CalculateTxIDFromReader", str)
+		}
+		hasWitness = true
+
+		if numIn, err = s.readVarInt(); err != nil {
+			return nil, err
+		}
+	}
+	if numIn > uint64(maxTxInPerMessage) {
+		str := fmt.Sprintf("too many input transactions to fit into "+
+			"max message size [count %d, max %d]", numIn,
+			maxTxInPerMessage)
+		return nil, messageError("CalculateTxIDFromReader", str)
+	}
+
+	entry, ok := lookupTxIDStrategy(version)
+	switch {
+	case !ok:
+		return s.standardTxID(version, numIn, hasWitness)
+
+	case entry.layered:
+		return s.v10TxID(version, numIn, hasWitness)
+
+	default:
+		return s.strategyTxID(
+			entry.strategy, version, numIn, hasWitness,
+		)
+	}
+}
+
+// CalculateTxIDAt returns the txid, in internal byte order, of the serialized
+// transaction occupying exactly length bytes at offset in r, such as a
+// transaction located in a block file by its TxLoc.
+//
+// Only the version and a possible witness marker are read up front.  When the
+// transaction is hashed over its raw bytes and doesn't use the witness
+// serialization, the length bytes are streamed through the double sha256
+// without being parsed at all, so they are not checked to form a valid
+// transaction.  Otherwise the transaction is hashed as by
+// CalculateTxIDFromReader and an error is returned unless it ends exactly at
+// length bytes.  io.ErrUnexpectedEOF is returned if r holds fewer than length
+// bytes at offset.
+func CalculateTxIDAt(r io.ReaderAt, offset int64, length int) ([]byte, error) {
+	if offset < 0 || length <= 0 {
+		str := fmt.Sprintf("invalid transaction location [offset %d, "+
+			"length %d]", offset, length)
+		return nil, messageError("CalculateTxIDAt", str)
+	}
+
+	// Peek the version followed by what is either the input count or the
+	// witness marker and flag.
+	var peek [6]byte
+	n := min(length, len(peek))
+	if read, err := r.ReadAt(peek[:n], offset); read < n {
+		if err == nil || errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	sr := io.NewSectionReader(r, offset, int64(length))
+	version := littleEndian.Uint32(peek[:4])
+	_, hasStrategy := lookupTxIDStrategy(version)
+	hasMarker := n == len(peek) && peek[4] == TxFlagMarker &&
+		peek[5] == WitnessFlag
+	if !hasStrategy && !hasMarker && n == len(peek) {
+		h := newSha256()
+		copied, err := io.Copy(h, sr)
+		if err != nil {
+			return nil, err
+		}
+		if copied < int64(length) {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		first := h.Sum(nil)
+		return sha256Into(first, first), nil
+	}
+
+	txid, err := CalculateTxIDFromReader(sr)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	// Seeking a SectionReader relative to its current position can't
+	// fail.
+	pos, _ := sr.Seek(0, io.SeekCurrent)
+	if rest := sr.Size() - pos; rest != 0 {
+		str := fmt.Sprintf("transaction ends %d bytes before the end "+
+			"of its %d byte location", rest, length)
+		return nil, messageError("CalculateTxIDAt", str)
+	}
+
+	return txid, nil
+}
+
+// txStreamReader provides the primitives used to hash a serialized
+// transaction as it is read, without buffering its variable length fields.
+type txStreamReader struct {
+	r io.Reader
+
+	// buf is used to read the fixed width fields and varints.
+	buf [chainhash.HashSize + 4]byte
+
+	// scratch is used to copy variable length fields in chunks.
+	scratch [512]byte
+}
+
+// readFull reads exactly len(b) bytes, reporting io.ErrUnexpectedEOF if the
+// stream ends first since the transaction has already been started.
+func (s *txStreamReader) readFull(b []byte) error {
+	_, err := io.ReadFull(s.r, b)
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// readVarInt reads a variable length integer.
+func (s *txStreamReader) readVarInt() (uint64, error) {
+	val, err := ReadVarIntBuf(s.r, 0, s.buf[:])
+	if errors.Is(err, io.EOF) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return val, err
+}
+
+// copyN reads the next n bytes and writes them to w.
+func (s *txStreamReader) copyN(w io.Writer, n uint64) error {
+	for n > 0 {
+		chunk := s.scratch[:]
+		if n < uint64(len(chunk)) {
+			chunk = chunk[:n]
+		}
+		if err := s.readFull(chunk); err != nil {
+			return err
+		}
+		w.Write(chunk)
+		n -= uint64(len(chunk))
+	}
+
+	return nil
+}
+
+// copyVarBytes reads a variable length byte field and writes it to w,
+// including its length prefix when withLen is true.
+func (s *txStreamReader) copyVarBytes(w io.Writer, withLen bool) error {
+	n, err := s.readVarInt()
+	if err != nil {
+		return err
+	}
+	if withLen {
+		if err := WriteVarIntBuf(w, 0, n, s.buf[:]); err != nil {
+			return err
+		}
+	}
+	return s.copyN(w, n)
+}
+
+// readOutputCount reads the number of outputs, rejecting counts which could
+// not possibly fit into a message.
+func (s *txStreamReader) readOutputCount() (uint64, error) {
+	numOut, err := s.readVarInt()
+	if err != nil {
+		return 0, err
+	}
+	if numOut > uint64(maxTxOutPerMessage) {
+		str := fmt.Sprintf("too many output transactions to fit into "+
+			"max message size [count %d, max %d]", numOut,
+			maxTxOutPerMessage)
+		return 0, messageError("CalculateTxIDFromReader", str)
+	}
+	return numOut, nil
+}
+
+// skipWitness discards the witness stacks of numIn inputs.
+func (s *txStreamReader) skipWitness(numIn uint64) error {
+	for i := uint64(0); i < numIn; i++ {
+		numItems, err := s.readVarInt()
+		if err != nil {
+			return err
+		}
+		for j := uint64(0); j < numItems; j++ {
+			err := s.copyVarBytes(io.Discard, false)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// standardTxID streams the remainder of a standard transaction through a
+// double sha256, re-encoding the input count that was already consumed.
+func (s *txStreamReader) standardTxID(version uint32, numIn uint64,
+	hasWitness bool) ([]byte, error) {
+
+	h := newSha256()
+	littleEndian.PutUint32(s.buf[:4], version)
+	h.Write(s.buf[:4])
+	if err := WriteVarIntBuf(h, 0, numIn, s.buf[:]); err != nil {
+		return nil, err
+	}
+
+	for i := uint64(0); i < numIn; i++ {
+		// Previous outpoint, signature script, and sequence.
+		if err := s.copyN(h, chainhash.HashSize+4); err != nil {
+			return nil, err
+		}
+		if err := s.copyVarBytes(h, true); err != nil {
+			return nil, err
+		}
+		if err := s.copyN(h, 4); err != nil {
+			return nil, err
+		}
+	}
+
+	numOut, err := s.readOutputCount()
+	if err != nil {
+		return nil, err
+	}
+	if err := WriteVarIntBuf(h, 0, numOut, s.buf[:]); err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < numOut; i++ {
+		// Value and public key script.
+		if err := s.copyN(h, 8); err != nil {
+			return nil, err
+		}
+		if err := s.copyVarBytes(h, true); err != nil {
+			return nil, err
+		}
+	}
+
+	if hasWitness {
+		if err := s.skipWitness(numIn); err != nil {
+			return nil, err
+		}
+	}
+
+	// Lock time.
+	if err := s.copyN(h, 4); err != nil {
+		return nil, err
+	}
+
+	first := h.Sum(s.scratch[:0])
+	second := sum256(first)
+	return second[:], nil
+}
+
+// v10TxID parses the remainder of a version 10 transaction just enough to
+// compute its layered txid.
+func (s *txStreamReader) v10TxID(version uint32, numIn uint64,
+	hasWitness bool) ([]byte, error) {
+
+	txh := getTxIDHasher()
+	defer putTxIDHasher(txh)
+
+	var (
+		sh         = newSha256()
+		scriptHash [sha256.Size]byte
+	)
+	hashScript := func() error {
+		sh.Reset()
+		if err := s.copyVarBytes(sh, false); err != nil {
+			return err
+		}
+		sh.Sum(scriptHash[:0])
+		return nil
+	}
+
+	for i := uint64(0); i < numIn; i++ {
+		var prevOut [chainhash.HashSize + 4]byte
+		if err := s.readFull(prevOut[:]); err != nil {
+			return nil, err
+		}
+		if err := hashScript(); err != nil {
+			return nil, err
+		}
+		if err := s.readFull(s.buf[:4]); err != nil {
+			return nil, err
+		}
+
+		index := littleEndian.Uint32(prevOut[chainhash.HashSize:])
+		sequence := littleEndian.Uint32(s.buf[:4])
+		txh.addInput(
+			prevOut[:chainhash.HashSize], index, sequence,
+			scriptHash[:],
+		)
+	}
+
+	numOut, err := s.readOutputCount()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < numOut; i++ {
+		if err := s.readFull(s.buf[:8]); err != nil {
+			return nil, err
+		}
+		value := littleEndian.Uint64(s.buf[:8])
+		if err := hashScript(); err != nil {
+			return nil, err
+		}
+		txh.addOutput(value, scriptHash[:])
+	}
+
+	if hasWitness {
+		if err := s.skipWitness(numIn); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.readFull(s.buf[:4]); err != nil {
+		return nil, err
+	}
+	lockTime := littleEndian.Uint32(s.buf[:4])
+
+	return txh.Sum(version, lockTime), nil
+}
+
+// strategyTxID fully deserializes the remainder of a transaction whose version
+// has a registered strategy and applies the strategy to it.
+func (s *txStreamReader) strategyTxID(strategy TxIDStrategy, version uint32,
+	numIn uint64, hasWitness bool) ([]byte, error) {
+
+	// Reconstruct the portion of the serialization which has already been
+	// consumed so the transaction can be decoded as usual.
+	var prefix bytes.Buffer
+	littleEndian.PutUint32(s.buf[:4], version)
+	prefix.Write(s.buf[:4])
+	if hasWitness {
+		prefix.Write([]byte{TxFlagMarker, WitnessFlag})
+	}
+	if err := WriteVarIntBuf(&prefix, 0, numIn, s.buf[:]); err != nil {
+		return nil, err
+	}
+
+	var msgTx MsgTx
+	err := msgTx.Deserialize(io.MultiReader(&prefix, s.r))
+	if errors.Is(err, io.EOF) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return strategy(ConvertWireMsgTxToCommonTransaction(&msgTx)), nil
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// v10TestMsgTx returns the wire form of v10TestTx, optionally with a witness
+// on its first input.
+func v10TestMsgTx(t *testing.T, withWitness bool) *MsgTx {
+	t.Helper()
+
+	tx := v10TestTx()
+	if withWitness {
+		tx.TxIn[0].Witness = [][]byte{{0x01, 0x02}, {}}
+	}
+	msgTx, err := ConvertCommonTransactionToWireMsgTx(tx)
+	require.NoError(t, err)
+
+	return msgTx
+}
+
+// TestCalculateTxIDFromReader ensures the txid computed while streaming a
+// serialized transaction matches the txid of the deserialized transaction and
+// that the reader is left positioned after it.
+func TestCalculateTxIDFromReader(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		msgTx *MsgTx
+	}{
+		{name: "standard", msgTx: multiTx},
+		{name: "standard witness", msgTx: multiWitnessTx},
+		{name: "v10", msgTx: v10TestMsgTx(t, false)},
+		{name: "v10 witness", msgTx: v10TestMsgTx(t, true)},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		require.NoError(t, test.msgTx.Serialize(&buf), test.name)
+		raw := buf.Bytes()
+
+		// Append trailing data which must not be consumed.
+		trailer := []byte{0xde, 0xad}
+		r := bytes.NewReader(
+			append(raw[:len(raw):len(raw)], trailer...),
+		)
+
+		want := test.msgTx.TxHash()
+		got, err := CalculateTxIDFromReader(r)
+		require.NoError(t, err, test.name)
+		require.Equal(t, want[:], got, test.name)
+
+		rest, err := io.ReadAll(r)
+		require.NoError(t, err, test.name)
+		require.Equal(t, trailer, rest, test.name)
+
+		// Every truncation of the transaction must be reported.
+		for i := 1; i < len(raw); i++ {
+			truncated := bytes.NewReader(raw[:i])
+			_, err := CalculateTxIDFromReader(truncated)
+			require.ErrorIs(t, err, io.ErrUnexpectedEOF,
+				"%s: truncated to %d bytes", test.name, i)
+		}
+	}
+
+	_, err := CalculateTxIDFromReader(bytes.NewReader(nil))
+	require.ErrorIs(t, err, io.EOF)
+}
+
+// TestCalculateTxIDAt ensures the txid of a transaction located within a
+// larger buffer matches the txid of the deserialized transaction and that
+// short reads and mismatched lengths are reported.
+func TestCalculateTxIDAt(t *testing.T) {
+	t.Parallel()
+
+	msgTxs := []*MsgTx{
+		multiTx, multiWitnessTx, v10TestMsgTx(t, false),
+		v10TestMsgTx(t, true),
+	}
+
+	// Lay the transactions out back to back behind a prefix, as they
+	// would be in a block.
+	var buf bytes.Buffer
+	buf.Write([]byte{0x01, 0x02, 0x03})
+	offsets := make([]int64, len(msgTxs))
+	lengths := make([]int, len(msgTxs))
+	for i, msgTx := range msgTxs {
+		offsets[i] = int64(buf.Len())
+		require.NoError(t, msgTx.Serialize(&buf))
+		lengths[i] = buf.Len() - int(offsets[i])
+	}
+	r := bytes.NewReader(buf.Bytes())
+
+	for i, msgTx := range msgTxs {
+		want := msgTx.TxHash()
+		got, err := CalculateTxIDAt(r, offsets[i], lengths[i])
+		require.NoError(t, err, "tx %d", i)
+		require.Equal(t, want[:], got, "tx %d", i)
+
+		// The parsed transactions must end exactly at the length.
+		if msgTx.HasWitness() ||
+			uint32(msgTx.Version) == LayeredTxIDVersion {
+			_, err = CalculateTxIDAt(r, offsets[i], lengths[i]-1)
+			require.ErrorIs(t, err, io.ErrUnexpectedEOF, "tx %d", i)
+
+			_, err = CalculateTxIDAt(r, offsets[i], lengths[i]+1)
+			require.Error(t, err, "tx %d", i)
+		}
+	}
+
+	// Reading past the end of the buffer is a short read.
+	last := len(msgTxs) - 1
+	_, err := CalculateTxIDAt(r, offsets[last]+1, lengths[last])
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	_, err = CalculateTxIDAt(r, int64(buf.Len())+10, 10)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	_, err = CalculateTxIDAt(r, -1, lengths[0])
+	require.Error(t, err)
+	_, err = CalculateTxIDAt(r, offsets[0], 0)
+	require.Error(t, err)
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"crypto/sha256"
+	"fmt"
+)
+
+// TxIDRecomputer recomputes the layered txid described by CalculateV10TxID as
+// individual outputs of a transaction are replaced, such as when bumping the
+// fee of a transaction by lowering the value of its change output.
+//
+// The inputs and scripts layers only depend on the inputs, so they are hashed
+// once when the recomputer is created.  The outputs layer is the sha256 of
+// the entries of every output, so it must be hashed again after each update,
+// but the sha256 of every public key script is retained so only the script of
+// the replaced output is hashed again.  The txid after any sequence of updates
+// is identical to that returned by CalculateTxID for the transaction with the
+// same outputs replaced.
+//
+// The recomputer does not retain or modify the transaction it was created
+// from, so the caller must apply the same updates to the transaction itself.
+// A TxIDRecomputer is not safe for concurrent use.
+type TxIDRecomputer struct {
+	version  uint32
+	lockTime uint32
+	numIn    uint32
+
+	inputsHash  [sha256.Size]byte
+	scriptsHash [sha256.Size]byte
+
+	// outputs holds the entry of every output in the outputs layer.
+	outputs []byte
+
+	// preimage is used to build the final preimage of the txid.
+	preimage [16 + 3*sha256.Size]byte
+}
+
+// NewTxIDRecomputer returns a TxIDRecomputer for the transaction, which must
+// use the built-in layered txid, as reported by UsesLayeredTxID.  An error is
+// returned if it doesn't or if the transaction is rejected by
+// CalculateTxIDErr.
+func NewTxIDRecomputer(tx *Transaction) (*TxIDRecomputer, error) {
+	if tx != nil && !UsesLayeredTxID(tx) {
+		return nil, fmt.Errorf("version %d transaction does not use "+
+			"the layered txid", tx.Version)
+	}
+	if err := validateTxForHashing(nil, tx, nil); err != nil {
+		return nil, err
+	}
+
+	h := getTxIDHasher()
+	defer putTxIDHasher(h)
+
+	internal := tx.InternalOrder()
+	for _, input := range internal.TxIn {
+		h.AddInput(input)
+	}
+	inputsHash, scriptsHash, _ := h.layers()
+
+	r := &TxIDRecomputer{
+		version:     tx.Version,
+		lockTime:    tx.LockTime,
+		numIn:       uint32(len(tx.TxIn)),
+		inputsHash:  inputsHash,
+		scriptsHash: scriptsHash,
+		outputs:     make([]byte, len(tx.TxOut)*v10OutputEntrySize),
+	}
+	for i, output := range tx.TxOut {
+		r.putOutput(i, output)
+	}
+
+	return r, nil
+}
+
+// putOutput writes the entry of the output at index i into the outputs layer.
+func (r *TxIDRecomputer) putOutput(i int, output *TxOutput) {
+	entry := r.outputs[i*v10OutputEntrySize:]
+	putV10OutputEntry(entry, output.Value, output.PkScript.Pkscript)
+}
+
+// UpdateOutput replaces the output at index with newOut and returns the
+// resulting txid in internal byte order, as returned by TxID.  Only the
+// public key script of newOut is hashed, along with the outputs layer.  An
+// error is returned if index is out of range or newOut is nil, in which case
+// the txid is unchanged.
+func (r *TxIDRecomputer) UpdateOutput(index int, newOut *TxOutput) ([]byte,
+	error) {
+
+	numOut := len(r.outputs) / v10OutputEntrySize
+	if index < 0 || index >= numOut {
+		return nil, fmt.Errorf("output index %d out of range for %d "+
+			"outputs", index, numOut)
+	}
+	if newOut == nil {
+		return nil, fmt.Errorf("output %d is nil", index)
+	}
+
+	r.putOutput(index, newOut)
+	return r.TxID(), nil
+}
+
+// TxID returns the txid of the transaction with all of the updates made so
+// far, in internal byte order.
+func (r *TxIDRecomputer) TxID() []byte {
+	outputsHash := sum256(r.outputs)
+	numOut := uint32(len(r.outputs) / v10OutputEntrySize)
+	putV10Preimage(r.preimage[:], r.version, r.lockTime, r.numIn, numOut,
+		&r.inputsHash, &r.scriptsHash, &outputsHash)
+
+	return doubleSha256(r.preimage[:])
+}
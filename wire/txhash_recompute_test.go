@@ -0,0 +1,132 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestTxIDRecomputer ensures the txid recomputed after each output update is
+// identical to the txid of the transaction with the same outputs replaced,
+// including for transactions with hashes in display order.
+func TestTxIDRecomputer(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		numIn   int
+		numOut  int
+		display bool
+	}{
+		{name: "1-in-1-out", numIn: 1, numOut: 1},
+		{name: "no inputs", numIn: 0, numOut: 3},
+		{name: "50-in-100-out", numIn: 50, numOut: 100},
+		{name: "display order", numIn: 5, numOut: 4, display: true},
+	}
+
+	for _, test := range tests {
+		tx := largeV10TestTx(test.numIn, test.numOut)
+		if test.display {
+			for _, input := range tx.TxIn {
+				input.Hash = ReverseBytes(input.Hash)
+			}
+			tx.HashOrder = HashOrderDisplay
+		}
+
+		r, err := NewTxIDRecomputer(tx)
+		require.NoError(t, err, test.name)
+		require.Equal(t, CalculateTxID(nil, tx), r.TxID(), test.name)
+
+		for i := 0; i < 2*test.numOut; i++ {
+			index := (i * 7) % test.numOut
+			script := bytes.Repeat([]byte{byte(i)}, i)
+			newOut := &TxOutput{
+				Value:    uint64(i) * 31,
+				PkScript: PkScript{Pkscript: script},
+			}
+			txid, err := r.UpdateOutput(index, newOut)
+			require.NoError(t, err, test.name)
+
+			tx.TxOut[index] = newOut
+			want := CalculateTxID(nil, tx)
+			require.Equal(t, want, txid, test.name)
+			require.Equal(t, txid, r.TxID(), test.name)
+		}
+	}
+}
+
+// TestTxIDRecomputerErrors ensures transactions which can't be recomputed and
+// invalid updates are rejected, and that rejected updates leave the txid
+// unchanged.
+func TestTxIDRecomputerErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewTxIDRecomputer(nil)
+	require.ErrorIs(t, err, ErrNilTx)
+
+	standard := largeV10TestTx(1, 1)
+	standard.Version = 2
+	_, err = NewTxIDRecomputer(standard)
+	require.Error(t, err)
+
+	shortHash := largeV10TestTx(2, 2)
+	shortHash.TxIn[1].Hash = shortHash.TxIn[1].Hash[:31]
+	_, err = NewTxIDRecomputer(shortHash)
+	require.ErrorIs(t, err, ErrBadHashLen)
+
+	nilOutput := largeV10TestTx(2, 2)
+	nilOutput.TxOut[0] = nil
+	_, err = NewTxIDRecomputer(nilOutput)
+	var txErr *TxHashError
+	require.True(t, errors.As(err, &txErr))
+	require.Equal(t, 0, txErr.Index)
+
+	tx := largeV10TestTx(2, 2)
+	r, err := NewTxIDRecomputer(tx)
+	require.NoError(t, err)
+	want := r.TxID()
+
+	for _, index := range []int{-1, 2} {
+		_, err = r.UpdateOutput(index, tx.TxOut[0])
+		require.Error(t, err)
+	}
+	_, err = r.UpdateOutput(0, nil)
+	require.Error(t, err)
+	require.Equal(t, want, r.TxID())
+	require.Equal(t, CalculateTxID(nil, tx), r.TxID())
+}
+
+// BenchmarkTxIDRecomputerUpdateOutput benchmarks updating a single output of a
+// large transaction against hashing the modified transaction in full.
+func BenchmarkTxIDRecomputerUpdateOutput(b *testing.B) {
+	tx := largeV10TestTx(500, 500)
+	newOut := &TxOutput{
+		Value:    1000,
+		PkScript: PkScript{Pkscript: bytes.Repeat([]byte{0xac}, 25)},
+	}
+
+	b.Run("recompute", func(b *testing.B) {
+		r, err := NewTxIDRecomputer(tx)
+		require.NoError(b, err)
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, _ = r.UpdateOutput(0, newOut)
+		}
+	})
+	b.Run("full", func(b *testing.B) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			tx.TxOut[0] = newOut
+			_ = CalculateTxID(nil, tx)
+		}
+	})
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+const (
+	// lockTimeThreshold is the number below which a lock time is
+	// interpreted to be a block height.  Since an average of one block is
+	// generated per 10 minutes, this allows blocks for about 9,512 years.
+	// It is the same as txscript.LockTimeThreshold.
+	lockTimeThreshold = 5e8 // Tue Nov 5 00:53:20 1985 UTC
+
+	// maxRBFSequence is the maximum sequence number an input can use to
+	// signal that the transaction it is included in can be replaced as
+	// defined by BIP0125.  It is the same as mempool.MaxRBFSequence.
+	maxRBFSequence = 0xfffffffd
+)
+
+// MinSequence returns the lowest sequence number of any input of the
+// transaction, or MaxTxInSequenceNum when it has no inputs.  Nil inputs are
+// ignored.
+func (tx *Transaction) MinSequence() uint32 {
+	minSequence := MaxTxInSequenceNum
+	for _, txIn := range tx.TxIn {
+		if txIn != nil && txIn.Sequence < minSequence {
+			minSequence = txIn.Sequence
+		}
+	}
+
+	return minSequence
+}
+
+// SignalsRBF returns whether the transaction signals that it can be replaced
+// as defined by BIP0125, which is when any of its inputs has a sequence number
+// below 0xfffffffe.  This only considers the transaction itself, so a
+// transaction which does not signal may still be replaceable when it spends
+// an unconfirmed transaction which does.
+func (tx *Transaction) SignalsRBF() bool {
+	return tx.MinSequence() <= maxRBFSequence
+}
+
+// IsFinal returns whether the transaction is finalized and may therefore be
+// included in a block at the given height and with the given time, which is a
+// unix timestamp.  It follows blockchain.IsFinalizedTransaction.
+//
+// A transaction is final when its lock time is zero or when the lock time has
+// passed, meaning it is strictly less than the block height or time.  The
+// lock time is a block height when it is below 500,000,000 and a unix
+// timestamp otherwise, so a lock time of 499,999,999 is compared against the
+// height while one of 500,000,000 is compared against the time.  Otherwise,
+// the transaction is only final when every input has the max sequence number
+// of MaxTxInSequenceNum, which disables the lock time.
+func (tx *Transaction) IsFinal(blockHeight uint32, blockTime int64) bool {
+	lockTime := tx.LockTime
+	if lockTime == 0 {
+		return true
+	}
+
+	blockTimeOrHeight := int64(blockHeight)
+	if lockTime >= lockTimeThreshold {
+		blockTimeOrHeight = blockTime
+	}
+	if int64(lockTime) < blockTimeOrHeight {
+		return true
+	}
+
+	for _, txIn := range tx.TxIn {
+		if txIn != nil && txIn.Sequence != MaxTxInSequenceNum {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// sequenceTestTx returns a transaction with the given lock time and an input
+// with each of the given sequence numbers.
+func sequenceTestTx(lockTime uint32, sequences ...uint32) *Transaction {
+	tx := largeV10TestTx(len(sequences), 1)
+	tx.LockTime = lockTime
+	for i, sequence := range sequences {
+		tx.TxIn[i].Sequence = sequence
+	}
+	return tx
+}
+
+// TestTransactionSequences ensures the lowest sequence number and BIP0125
+// replacement signaling are derived from every input.
+func TestTransactionSequences(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		sequences []uint32
+		wantMin   uint32
+		wantRBF   bool
+	}{{
+		name:    "no inputs",
+		wantMin: MaxTxInSequenceNum,
+	}, {
+		name:      "final",
+		sequences: []uint32{MaxTxInSequenceNum, MaxTxInSequenceNum},
+		wantMin:   MaxTxInSequenceNum,
+	}, {
+		name:      "lock time enabled without rbf",
+		sequences: []uint32{MaxTxInSequenceNum, 0xfffffffe},
+		wantMin:   0xfffffffe,
+	}, {
+		name:      "rbf on one input",
+		sequences: []uint32{MaxTxInSequenceNum, 0xfffffffd},
+		wantMin:   0xfffffffd,
+		wantRBF:   true,
+	}, {
+		name:      "zero",
+		sequences: []uint32{0, MaxTxInSequenceNum},
+		wantMin:   0,
+		wantRBF:   true,
+	}}
+
+	for _, test := range tests {
+		tx := sequenceTestTx(0, test.sequences...)
+		require.Equal(t, test.wantMin, tx.MinSequence(), test.name)
+		require.Equal(t, test.wantRBF, tx.SignalsRBF(), test.name)
+	}
+
+	tx := sequenceTestTx(0, 1, MaxTxInSequenceNum)
+	tx.TxIn[0] = nil
+	require.Equal(t, MaxTxInSequenceNum, tx.MinSequence())
+}
+
+// TestTransactionIsFinal ensures lock times below the threshold are compared
+// against the block height and those at or above it against the block time,
+// and that max sequence numbers disable the lock time.
+func TestTransactionIsFinal(t *testing.T) {
+	t.Parallel()
+
+	const (
+		height    = 1000
+		blockTime = 1700000000
+		enabled   = 0xfffffffe
+	)
+
+	tests := []struct {
+		name      string
+		lockTime  uint32
+		sequences []uint32
+		height    uint32
+		time      int64
+		want      bool
+	}{{
+		name:      "zero lock time",
+		sequences: []uint32{enabled},
+		want:      true,
+	}, {
+		name:      "height passed",
+		lockTime:  height - 1,
+		sequences: []uint32{enabled},
+		height:    height,
+		want:      true,
+	}, {
+		name:      "height reached but not passed",
+		lockTime:  height,
+		sequences: []uint32{enabled},
+		height:    height,
+	}, {
+		name:      "height not reached but sequences final",
+		lockTime:  height + 1,
+		sequences: []uint32{MaxTxInSequenceNum, MaxTxInSequenceNum},
+		height:    height,
+		want:      true,
+	}, {
+		name:      "height not reached",
+		lockTime:  height + 1,
+		sequences: []uint32{MaxTxInSequenceNum, enabled},
+		height:    height,
+	}, {
+		name:      "time passed",
+		lockTime:  blockTime - 1,
+		sequences: []uint32{enabled},
+		time:      blockTime,
+		want:      true,
+	}, {
+		name:      "time reached but not passed",
+		lockTime:  blockTime,
+		sequences: []uint32{enabled},
+		time:      blockTime,
+	}, {
+		// The largest height is compared against the height, so
+		// even a huge block time doesn't finalize it.
+		name:      "largest height lock time",
+		lockTime:  lockTimeThreshold - 1,
+		sequences: []uint32{enabled},
+		height:    lockTimeThreshold - 1,
+		time:      blockTime,
+	}, {
+		name:      "largest height lock time passed",
+		lockTime:  lockTimeThreshold - 1,
+		sequences: []uint32{enabled},
+		height:    lockTimeThreshold,
+		want:      true,
+	}, {
+		// The threshold itself is a timestamp, so it is compared
+		// against the time even when the height is larger.
+		name:      "smallest time lock time",
+		lockTime:  lockTimeThreshold,
+		sequences: []uint32{enabled},
+		height:    lockTimeThreshold + 1,
+		time:      lockTimeThreshold,
+	}, {
+		name:      "smallest time lock time passed",
+		lockTime:  lockTimeThreshold,
+		sequences: []uint32{enabled},
+		time:      lockTimeThreshold + 1,
+		want:      true,
+	}}
+
+	for _, test := range tests {
+		tx := sequenceTestTx(test.lockTime, test.sequences...)
+		got := tx.IsFinal(test.height, test.time)
+		require.Equal(t, test.want, got, test.name)
+	}
+}
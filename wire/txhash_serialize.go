@@ -0,0 +1,469 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// SerializeSize returns the number of bytes it would take to serialize the
+// transaction input.
+func (in *TxInput) SerializeSize() int {
+	// Outpoint Hash 32 bytes + Outpoint Index 4 bytes + Sequence 4 bytes +
+	// serialized varint size for the length of SignatureScript +
+	// SignatureScript bytes.
+	return 40 + VarIntSerializeSize(uint64(len(in.SignatureScript))) +
+		len(in.SignatureScript)
+}
+
+// SerializeSize returns the number of bytes it would take to serialize the
+// transaction output.
+func (out *TxOutput) SerializeSize() int {
+	// Value 8 bytes + serialized varint size for the length of PkScript +
+	// PkScript bytes.
+	return 8 + VarIntSerializeSize(uint64(len(out.PkScript.Pkscript))) +
+		len(out.PkScript.Pkscript)
+}
+
+// SerializeSize returns the number of bytes it would take to serialize the
+// transaction in the standard wire format, excluding any witness data.  This
+// is the serialization the standard txid is computed over, and it is the
+// same size reported by MsgTx.SerializeSizeStripped for the equivalent MsgTx.
+func (tx *Transaction) SerializeSize() int {
+	// Version 4 bytes + LockTime 4 bytes + Serialized varint size for the
+	// number of transaction inputs and outputs.
+	n := 8 + VarIntSerializeSize(uint64(len(tx.TxIn))) +
+		VarIntSerializeSize(uint64(len(tx.TxOut)))
+
+	for _, txIn := range tx.TxIn {
+		n += txIn.SerializeSize()
+	}
+
+	for _, txOut := range tx.TxOut {
+		n += txOut.SerializeSize()
+	}
+
+	return n
+}
+
+// witnessScaleFactor is the discount applied to witness data when computing
+// the weight of a transaction as defined by BIP0141.  It is the same as
+// blockchain.WitnessScaleFactor.
+const witnessScaleFactor = 4
+
+// witnessSerializeSize returns the number of additional bytes it would take to
+// serialize the transaction with its witness data, which is the marker and
+// flag followed by the witness stack of every input.  It is zero when no input
+// has witness data since the witness serialization is then not used.
+func (tx *Transaction) witnessSerializeSize() int {
+	if !tx.HasWitness() {
+		return 0
+	}
+
+	// The marker and flag fields take up two additional bytes.
+	n := 2
+	for _, txIn := range tx.TxIn {
+		n += TxWitness(txIn.Witness).SerializeSize()
+	}
+
+	return n
+}
+
+// Weight returns the weight of the transaction as defined by BIP0141, which
+// is the size of its serialization without witness data, as reported by
+// SerializeSize, scaled by 4, plus the size of its witness data including the
+// marker and flag.  For a transaction without witness data, it is simply 4
+// times its size.  It is the same weight reported by
+// blockchain.GetTransactionWeight for the equivalent MsgTx.
+func (tx *Transaction) Weight() int {
+	return tx.SerializeSize()*witnessScaleFactor + tx.witnessSerializeSize()
+}
+
+// VSize returns the virtual size of the transaction, which is its weight
+// divided by 4 and rounded up.  Fee rates are conventionally expressed per
+// virtual byte, so a transaction paying fee has a fee rate of fee /
+// tx.VSize().
+func (tx *Transaction) VSize() int {
+	return (tx.Weight() + witnessScaleFactor - 1) / witnessScaleFactor
+}
+
+// MaxStandardTxSize is the default maximum size in bytes, as reported by
+// SerializeSize, of a transaction which is considered standard for relay.  It
+// corresponds to the maximum standard transaction weight of 400,000 used by
+// the mempool policy for a transaction without witness data.
+const MaxStandardTxSize = 100000
+
+// ExceedsSize returns whether the serialized size of the transaction, as
+// reported by SerializeSize, is greater than maxBytes.  A transaction of
+// exactly maxBytes does not exceed it.  Relay policy should typically use
+// MaxStandardTxSize.
+func (tx *Transaction) ExceedsSize(maxBytes int) bool {
+	return tx.SerializeSize() > maxBytes
+}
+
+// checkSerializable ensures the transaction is consistent enough to be
+// serialized unambiguously.
+func (tx *Transaction) checkSerializable(op string) error {
+	if tx.TxInCount != uint(len(tx.TxIn)) {
+		str := fmt.Sprintf("input count %d does not match %d inputs",
+			tx.TxInCount, len(tx.TxIn))
+		return messageError(op, str)
+	}
+	if tx.TxOutCount != uint(len(tx.TxOut)) {
+		str := fmt.Sprintf("output count %d does not match %d outputs",
+			tx.TxOutCount, len(tx.TxOut))
+		return messageError(op, str)
+	}
+
+	for i, txIn := range tx.TxIn {
+		if txIn == nil {
+			str := fmt.Sprintf("input %d is nil", i)
+			return messageError(op, str)
+		}
+		if len(txIn.Hash) != chainhash.HashSize {
+			str := fmt.Sprintf("input %d has a %d byte previous "+
+				"outpoint hash, want %d", i, len(txIn.Hash),
+				chainhash.HashSize)
+			return messageError(op, str)
+		}
+	}
+	for i, txOut := range tx.TxOut {
+		if txOut == nil {
+			str := fmt.Sprintf("output %d is nil", i)
+			return messageError(op, str)
+		}
+	}
+
+	return nil
+}
+
+// Validate ensures the transaction is internally consistent.  An error is
+// returned if:
+//   - TxInCount or TxOutCount do not match the number of inputs and outputs
+//   - any input or output is nil
+//   - any previous outpoint hash is not exactly 32 bytes
+//   - any output or the sum of all outputs exceeds MaxTxOutputValue, which
+//     also catches negative values from a MsgTx that were reinterpreted as
+//     huge unsigned values by ConvertWireMsgTxToCommonTransaction
+//
+// Transactions built by ConvertWireMsgTxToCommonTransactionErr always pass,
+// so this is mainly useful for transactions which were constructed or
+// modified directly, before hashing or relaying them.
+func (tx *Transaction) Validate() error {
+	const op = "Transaction.Validate"
+	if err := tx.checkSerializable(op); err != nil {
+		return err
+	}
+
+	for i, txOut := range tx.TxOut {
+		if txOut.Value > MaxTxOutputValue {
+			str := fmt.Sprintf("output %d has value %d which is "+
+				"higher than max allowed value of %d", i,
+				txOut.Value, MaxTxOutputValue)
+			return messageError(op, str)
+		}
+	}
+
+	// No individual value exceeds the max, so the sum can only overflow
+	// with an implausibly large number of outputs, which is also rejected.
+	total, err := tx.TotalOutputValue()
+	if err != nil {
+		return messageError(op, err.Error())
+	}
+	if total > MaxTxOutputValue {
+		str := fmt.Sprintf("total value of all outputs %d is higher "+
+			"than max allowed value of %d", total, MaxTxOutputValue)
+		return messageError(op, str)
+	}
+
+	return nil
+}
+
+// Serialize encodes the transaction to w in the standard wire format without
+// any witness data.  These are exactly the bytes the standard txid is computed
+// over, so the output may be passed directly to CalculateTxID as the raw
+// transaction data.  The result is identical to MsgTx.SerializeNoWitness for
+// the equivalent MsgTx.
+//
+// As with MsgTx, the input and output counts and the script lengths are
+// encoded as CompactSize variable length integers using the same WriteVarInt
+// encoding as the rest of the wire protocol, so the result may be decoded with
+// ReadVarInt and MsgTx.Deserialize.  This differs from the layered version 10
+// txid, which commits to the counts as fixed width 32-bit values.
+//
+// An error is returned if TxInCount or TxOutCount do not match the number of
+// inputs and outputs, or if any previous outpoint hash is not 32 bytes.
+func (tx *Transaction) Serialize(w io.Writer) error {
+	return tx.serialize(w, "Transaction.Serialize", false)
+}
+
+// serialize encodes the transaction to w in the standard wire format.  When
+// witness is true and any input has witness data, the witness serialization
+// defined in BIP0144 is used instead, which is what the wtxid is computed
+// over.
+func (tx *Transaction) serialize(w io.Writer, op string, witness bool) error {
+	tx = tx.InternalOrder()
+	if err := tx.checkSerializable(op); err != nil {
+		return err
+	}
+	witness = witness && tx.HasWitness()
+
+	buf := binarySerializer.Borrow()
+	defer binarySerializer.Return(buf)
+
+	littleEndian.PutUint32(buf[:4], tx.Version)
+	if _, err := w.Write(buf[:4]); err != nil {
+		return err
+	}
+
+	if witness {
+		_, err := w.Write([]byte{TxFlagMarker, byte(WitnessFlag)})
+		if err != nil {
+			return err
+		}
+	}
+
+	err := WriteVarIntBuf(w, 0, uint64(len(tx.TxIn)), buf)
+	if err != nil {
+		return err
+	}
+	for _, txIn := range tx.TxIn {
+		if _, err := w.Write(txIn.Hash); err != nil {
+			return err
+		}
+		littleEndian.PutUint32(buf[:4], txIn.Index)
+		if _, err := w.Write(buf[:4]); err != nil {
+			return err
+		}
+
+		err := WriteVarBytesBuf(w, 0, txIn.SignatureScript, buf)
+		if err != nil {
+			return err
+		}
+
+		littleEndian.PutUint32(buf[:4], txIn.Sequence)
+		if _, err := w.Write(buf[:4]); err != nil {
+			return err
+		}
+	}
+
+	err = WriteVarIntBuf(w, 0, uint64(len(tx.TxOut)), buf)
+	if err != nil {
+		return err
+	}
+	for _, txOut := range tx.TxOut {
+		littleEndian.PutUint64(buf[:8], txOut.Value)
+		if _, err := w.Write(buf[:8]); err != nil {
+			return err
+		}
+
+		err := WriteVarBytesBuf(w, 0, txOut.PkScript.Pkscript, buf)
+		if err != nil {
+			return err
+		}
+	}
+
+	if witness {
+		for _, txIn := range tx.TxIn {
+			numItems := uint64(len(txIn.Witness))
+			err := WriteVarIntBuf(w, 0, numItems, buf)
+			if err != nil {
+				return err
+			}
+			for _, item := range txIn.Witness {
+				err := WriteVarBytesBuf(w, 0, item, buf)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	littleEndian.PutUint32(buf[:4], tx.LockTime)
+	_, err = w.Write(buf[:4])
+	return err
+}
+
+// Bytes returns the serialization of the transaction produced by Serialize.
+func (tx *Transaction) Bytes() ([]byte, error) {
+	// The transaction is checked before sizing the buffer since the size
+	// can't be determined when there are nil inputs or outputs.
+	if err := tx.checkSerializable("Transaction.Bytes"); err != nil {
+		return nil, err
+	}
+
+	w := bytes.NewBuffer(make([]byte, 0, tx.SerializeSize()))
+	if err := tx.Serialize(w); err != nil {
+		return nil, err
+	}
+
+	return w.Bytes(), nil
+}
+
+// StandardSerialize returns the raw transaction data the standard txid is
+// computed over, which allows CalculateTxID to be used for a transaction
+// whose original serialization is no longer available:
+//
+//	raw, err := tx.StandardSerialize()
+//	if err != nil {
+//		return err
+//	}
+//	txid := CalculateTxID(raw, tx)
+//
+// The result is identical to Bytes and is the inverse of converting the
+// deserialized MsgTx with ConvertWireMsgTxToCommonTransaction, with the
+// counts and script lengths encoded as variable length integers.  Any
+// witness data is not included.
+func (tx *Transaction) StandardSerialize() ([]byte, error) {
+	return tx.Bytes()
+}
+
+// witnessBytes returns the witness serialization of the transaction, which is
+// the same as that returned by Bytes when no input has witness data.
+func (tx *Transaction) witnessBytes() ([]byte, error) {
+	var w bytes.Buffer
+	err := tx.serialize(&w, "Transaction.witnessBytes", true)
+	if err != nil {
+		return nil, err
+	}
+
+	return w.Bytes(), nil
+}
+
+// ParseTransactionHex decodes a hex encoded serialized transaction, such as
+// one returned by the getrawtransaction RPC, and returns it converted with
+// ConvertWireMsgTxToCommonTransactionErr along with its raw serialization
+// without any witness data.  The raw bytes are exactly those the standard
+// txid is computed over, so they may be passed directly to CalculateTxID.
+//
+// When the transaction does not use the witness serialization, the raw bytes
+// are the decoded bytes themselves, avoiding re-encoding the transaction.
+// Otherwise, they are the result of Transaction.Bytes.  An error is returned
+// if the string is not valid hex, does not contain exactly one transaction,
+// or the transaction fails conversion.
+func ParseTransactionHex(s string) (*Transaction, []byte, error) {
+	raw, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var msgTx MsgTx
+	r := bytes.NewReader(raw)
+	if err := msgTx.Deserialize(r); err != nil {
+		return nil, nil, err
+	}
+	if r.Len() != 0 {
+		str := fmt.Sprintf("%d bytes of trailing data after "+
+			"transaction", r.Len())
+		return nil, nil, messageError("ParseTransactionHex", str)
+	}
+
+	tx, err := ConvertWireMsgTxToCommonTransactionErr(&msgTx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if msgTx.HasWitness() {
+		raw, err = tx.Bytes()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return tx, raw, nil
+}
+
+// ParseTransactions reads exactly count serialized transactions concatenated
+// in r, such as the transactions of a block body following the transaction
+// count, and returns them converted with ConvertWireMsgTxToCommonTransactionErr
+// along with the raw serialization of each without any witness data, as
+// described by ParseTransactionHex.  The raw bytes of transactions which do not
+// use the witness serialization are the spans of the data read for them, so
+// no offsets need to be tracked to recover them for CalculateTxID.
+//
+// Since the transactions must account for all of the data, r is read until
+// io.EOF after the last transaction, and an error is returned if there is any
+// data left, as well as if the data ends before count transactions have been
+// read, in which case the error wraps io.ErrUnexpectedEOF.  An error is also
+// returned if count is negative or exceeds the maximum number of transactions
+// a block can hold, or if any transaction fails conversion.  Errors for a
+// single transaction identify its index.
+func ParseTransactions(r io.Reader, count int) ([]*Transaction, [][]byte,
+	error) {
+
+	const op = "ParseTransactions"
+	if count < 0 || count > maxTxPerBlock {
+		str := fmt.Sprintf("transaction count %d is outside of the "+
+			"range [0, %d]", count, maxTxPerBlock)
+		return nil, nil, messageError(op, str)
+	}
+
+	// Every byte read is recorded, so the raw bytes of each transaction
+	// can be sliced from a single buffer once all of them are read.
+	var buf bytes.Buffer
+	tee := io.TeeReader(r, &buf)
+
+	txs := make([]*Transaction, 0, count)
+	raws := make([][]byte, 0, count)
+	ends := make([]int, 0, count)
+	for i := 0; i < count; i++ {
+		var msgTx MsgTx
+		if err := msgTx.Deserialize(tee); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, nil, fmt.Errorf("transaction %d: %w", i,
+				err)
+		}
+
+		tx, err := ConvertWireMsgTxToCommonTransactionErr(&msgTx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("transaction %d: %w", i,
+				err)
+		}
+
+		// The raw bytes read for witness transactions include their
+		// witness data, so they are serialized again without it.
+		var raw []byte
+		if msgTx.HasWitness() {
+			raw, err = tx.Bytes()
+			if err != nil {
+				return nil, nil, fmt.Errorf("transaction %d: "+
+					"%w", i, err)
+			}
+		}
+
+		txs = append(txs, tx)
+		raws = append(raws, raw)
+		ends = append(ends, buf.Len())
+	}
+
+	var trailing [1]byte
+	n, err := io.ReadFull(r, trailing[:])
+	if n != 0 {
+		str := fmt.Sprintf("trailing data after %d transactions",
+			count)
+		return nil, nil, messageError(op, str)
+	}
+	if err != io.EOF {
+		return nil, nil, err
+	}
+
+	data := buf.Bytes()
+	start := 0
+	for i, end := range ends {
+		if raws[i] == nil {
+			raws[i] = data[start:end:end]
+		}
+		start = end
+	}
+
+	return txs, raws, nil
+}
@@ -0,0 +1,426 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestTransactionSerializeSize ensures the serialized size of a Transaction
+// matches the stripped serialized size of the equivalent MsgTx.
+func TestTransactionSerializeSize(t *testing.T) {
+	t.Parallel()
+
+	// A transaction with more than 0xfc inputs and a script longer than
+	// 0xfc bytes exercises the multi-byte varints.
+	bigTx := largeV10TestTx(300, 2)
+	bigTx.TxOut[0].PkScript.Pkscript = bytes.Repeat([]byte{0x51}, 300)
+	bigMsgTx, err := ConvertCommonTransactionToWireMsgTx(bigTx)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		msgTx *MsgTx
+		want  int
+	}{
+		{name: "no witness", msgTx: multiTx, want: len(multiTxEncoded)},
+		{name: "witness", msgTx: multiWitnessTx, want: 82},
+		{name: "empty", msgTx: NewMsgTx(1), want: 10},
+		{name: "large varints", msgTx: bigMsgTx},
+	}
+
+	for _, test := range tests {
+		tx := ConvertWireMsgTxToCommonTransaction(test.msgTx)
+		require.Equal(t, test.msgTx.SerializeSizeStripped(),
+			tx.SerializeSize(), test.name)
+		if test.want != 0 {
+			require.Equal(t, test.want, tx.SerializeSize(),
+				test.name)
+		}
+	}
+}
+
+// TestTransactionSerialize ensures a Transaction serializes to the same bytes
+// as the stripped serialization of the equivalent MsgTx, that those bytes
+// produce the same standard txid, and that inconsistent transactions are
+// rejected.
+func TestTransactionSerialize(t *testing.T) {
+	t.Parallel()
+
+	for _, msgTx := range []*MsgTx{multiTx, multiWitnessTx} {
+		var want bytes.Buffer
+		require.NoError(t, msgTx.SerializeNoWitness(&want))
+
+		tx := ConvertWireMsgTxToCommonTransaction(msgTx)
+		raw, err := tx.Bytes()
+		require.NoError(t, err)
+		require.Equal(t, want.Bytes(), raw)
+
+		wantHash := msgTx.TxHash()
+		require.Equal(t, wantHash[:], CalculateTxID(raw, tx))
+
+		// The witness serialization matches that of the wire
+		// transaction, which only differs when there are witnesses.
+		want.Reset()
+		require.NoError(t, msgTx.Serialize(&want))
+		raw, err = tx.witnessBytes()
+		require.NoError(t, err)
+		require.Equal(t, want.Bytes(), raw)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(tx *Transaction)
+	}{{
+		name:   "input count mismatch",
+		mutate: func(tx *Transaction) { tx.TxInCount++ },
+	}, {
+		name:   "output count mismatch",
+		mutate: func(tx *Transaction) { tx.TxOutCount-- },
+	}, {
+		name:   "nil input",
+		mutate: func(tx *Transaction) { tx.TxIn[0] = nil },
+	}, {
+		name:   "nil output",
+		mutate: func(tx *Transaction) { tx.TxOut[0] = nil },
+	}, {
+		name: "short prevout hash",
+		mutate: func(tx *Transaction) {
+			tx.TxIn[1].Hash = tx.TxIn[1].Hash[:16]
+		},
+	}}
+
+	for _, test := range tests {
+		tx := v10TestTx()
+		test.mutate(tx)
+
+		_, err := tx.Bytes()
+		require.Error(t, err, test.name)
+		require.Error(t, tx.Serialize(&bytes.Buffer{}), test.name)
+	}
+}
+
+// TestTransactionValidate ensures inconsistent transactions are rejected.
+func TestTransactionValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		mutate  func(tx *Transaction)
+		wantErr bool
+	}{{
+		name:   "valid",
+		mutate: func(*Transaction) {},
+	}, {
+		name: "max value",
+		mutate: func(tx *Transaction) {
+			tx.TxOut[0].Value = MaxTxOutputValue
+		},
+	}, {
+		name:    "input count",
+		mutate:  func(tx *Transaction) { tx.TxInCount++ },
+		wantErr: true,
+	}, {
+		name:    "output count",
+		mutate:  func(tx *Transaction) { tx.TxOutCount = 0 },
+		wantErr: true,
+	}, {
+		name:    "nil input",
+		mutate:  func(tx *Transaction) { tx.TxIn[0] = nil },
+		wantErr: true,
+	}, {
+		name:    "nil output",
+		mutate:  func(tx *Transaction) { tx.TxOut[0] = nil },
+		wantErr: true,
+	}, {
+		name: "short hash",
+		mutate: func(tx *Transaction) {
+			tx.TxIn[0].Hash = make([]byte, 31)
+		},
+		wantErr: true,
+	}, {
+		name: "value too high",
+		mutate: func(tx *Transaction) {
+			tx.TxOut[0].Value = MaxTxOutputValue + 1
+		},
+		wantErr: true,
+	}, {
+		name: "reinterpreted negative value",
+		mutate: func(tx *Transaction) {
+			tx.TxOut[0].Value = uint64(1<<64 - 1)
+		},
+		wantErr: true,
+	}, {
+		name: "total too high",
+		mutate: func(tx *Transaction) {
+			tx.TxOut = append(tx.TxOut, &TxOutput{
+				Value: MaxTxOutputValue,
+			})
+			tx.TxOutCount++
+			tx.TxOut[0].Value = 1
+		},
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		tx := v10TestTx()
+		test.mutate(tx)
+
+		err := tx.Validate()
+		if test.wantErr {
+			require.Error(t, err, test.name)
+			continue
+		}
+		require.NoError(t, err, test.name)
+	}
+}
+
+// TestTransactionWeight ensures the weight and virtual size of transactions
+// with and without witness data match their known values and those of the
+// equivalent MsgTx.
+func TestTransactionWeight(t *testing.T) {
+	t.Parallel()
+
+	segnetRaw, err := hex.DecodeString(segnetWitnessTxHex)
+	require.NoError(t, err)
+	var segnetTx MsgTx
+	require.NoError(t, segnetTx.Deserialize(bytes.NewReader(segnetRaw)))
+
+	tests := []struct {
+		name       string
+		msgTx      *MsgTx
+		wantWeight int
+		wantVSize  int
+	}{
+		{
+			// 82 bytes without witness data and 108 bytes of
+			// marker, flag and witness data.
+			name:       "segnet witness",
+			msgTx:      &segnetTx,
+			wantWeight: 82*4 + 108,
+			wantVSize:  109,
+		},
+		{
+			name:       "genesis coinbase",
+			msgTx:      &genesisCoinbaseTx,
+			wantWeight: 204 * 4,
+			wantVSize:  204,
+		},
+		{name: "multiple inputs and outputs", msgTx: multiTx},
+		{name: "multiple witness inputs", msgTx: multiWitnessTx},
+	}
+
+	for _, test := range tests {
+		// The weight of the MsgTx is computed as in
+		// blockchain.GetTransactionWeight.
+		msgWeight := test.msgTx.SerializeSizeStripped()*3 +
+			test.msgTx.SerializeSize()
+		if test.wantWeight != 0 {
+			require.Equal(t, test.wantWeight, msgWeight, test.name)
+		}
+
+		tx := ConvertWireMsgTxToCommonTransaction(test.msgTx)
+		require.Equal(t, msgWeight, tx.Weight(), test.name)
+		require.Equal(t, (msgWeight+3)/4, tx.VSize(), test.name)
+		if test.wantVSize != 0 {
+			require.Equal(t, test.wantVSize, tx.VSize(), test.name)
+		}
+		if !test.msgTx.HasWitness() {
+			require.Equal(t, 4*tx.SerializeSize(), tx.Weight(),
+				test.name)
+		}
+	}
+}
+
+// TestTransactionExceedsSize ensures transactions are only reported as
+// exceeding a size when they are strictly larger than it.
+func TestTransactionExceedsSize(t *testing.T) {
+	t.Parallel()
+
+	// Pad the output script so the transaction is exactly the max standard
+	// size.  The varint for the script length grows from one to five
+	// bytes when it is padded.
+	tx := v10TestTx()
+	tx.TxOut[0].PkScript.Pkscript = nil
+	pad := MaxStandardTxSize - tx.SerializeSize()
+	tx.TxOut[0].PkScript.Pkscript = bytes.Repeat([]byte{0x51}, pad-4)
+	require.Equal(t, MaxStandardTxSize, tx.SerializeSize())
+
+	require.False(t, tx.ExceedsSize(MaxStandardTxSize))
+	require.True(t, tx.ExceedsSize(MaxStandardTxSize-1))
+
+	tx.TxOut[0].PkScript.Pkscript = append(
+		tx.TxOut[0].PkScript.Pkscript, 0x51,
+	)
+	require.True(t, tx.ExceedsSize(MaxStandardTxSize))
+	require.False(t, tx.ExceedsSize(MaxStandardTxSize+1))
+}
+
+// TestParseTransactionHex ensures hex encoded transactions are parsed along
+// with the raw bytes their standard txid is computed over.
+func TestParseTransactionHex(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		encoded []byte
+		msgTx   *MsgTx
+	}{
+		{name: "no witness", encoded: multiTxEncoded, msgTx: multiTx},
+		{
+			name:    "witness",
+			encoded: multiWitnessTxEncoded,
+			msgTx:   multiWitnessTx,
+		},
+	}
+
+	for _, test := range tests {
+		tx, raw, err := ParseTransactionHex(
+			hex.EncodeToString(test.encoded),
+		)
+		require.NoError(t, err, test.name)
+
+		want := ConvertWireMsgTxToCommonTransaction(test.msgTx)
+		require.True(t, want.Equal(tx), test.name)
+		require.Equal(t, mustBytes(t, want), raw, test.name)
+
+		hash := test.msgTx.TxHash()
+		require.Equal(t, hash[:], CalculateTxID(raw, tx), test.name)
+	}
+
+	encoded := hex.EncodeToString(multiTxEncoded)
+	for _, s := range []string{
+		"", "zz", encoded[:len(encoded)-2], encoded + "00",
+	} {
+		_, _, err := ParseTransactionHex(s)
+		require.Error(t, err)
+	}
+}
+
+// TestParseTransactions ensures concatenated transactions are parsed along
+// with raw bytes their txids can be computed from, and that missing and
+// trailing data are rejected.
+func TestParseTransactions(t *testing.T) {
+	t.Parallel()
+
+	msgTxs := []*MsgTx{multiTx, multiWitnessTx, v10TestMsgTx(t, false)}
+	var stream bytes.Buffer
+	for _, msgTx := range msgTxs {
+		require.NoError(t, msgTx.Serialize(&stream))
+	}
+	data := stream.Bytes()
+
+	txs, raws, err := ParseTransactions(bytes.NewReader(data), len(msgTxs))
+	require.NoError(t, err)
+	require.Len(t, txs, len(msgTxs))
+	require.Len(t, raws, len(msgTxs))
+	for i, msgTx := range msgTxs {
+		want := ConvertWireMsgTxToCommonTransaction(msgTx)
+		require.True(t, want.Equal(txs[i]), "transaction %d", i)
+		require.Equal(t, mustBytes(t, want), raws[i], "transaction %d",
+			i)
+
+		hash := msgTx.TxHash()
+		require.Equal(t, hash[:], CalculateTxID(raws[i], txs[i]),
+			"transaction %d", i)
+	}
+
+	// The raw bytes of one transaction can't be extended into the next.
+	require.Equal(t, len(raws[0]), cap(raws[0]))
+
+	txs, raws, err = ParseTransactions(bytes.NewReader(nil), 0)
+	require.NoError(t, err)
+	require.Empty(t, txs)
+	require.Empty(t, raws)
+
+	tests := []struct {
+		name  string
+		data  []byte
+		count int
+		want  error
+	}{{
+		name:  "missing transaction",
+		data:  data,
+		count: len(msgTxs) + 1,
+		want:  io.ErrUnexpectedEOF,
+	}, {
+		name:  "truncated transaction",
+		data:  data[:len(data)-1],
+		count: len(msgTxs),
+		want:  io.ErrUnexpectedEOF,
+	}, {
+		name:  "trailing transaction",
+		data:  data,
+		count: len(msgTxs) - 1,
+	}, {
+		name:  "trailing byte",
+		data:  append(bytes.Clone(data), 0x00),
+		count: len(msgTxs),
+	}, {
+		name:  "negative count",
+		data:  data,
+		count: -1,
+	}}
+	for _, test := range tests {
+		r := bytes.NewReader(test.data)
+		_, _, err := ParseTransactions(r, test.count)
+		require.Error(t, err, test.name)
+		if test.want != nil {
+			require.ErrorIs(t, err, test.want, test.name)
+		}
+	}
+}
+
+// TestTransactionStandardSerialize ensures known raw transactions round trip
+// through a Transaction and that the result can be used to compute their
+// txids.
+func TestTransactionStandardSerialize(t *testing.T) {
+	t.Parallel()
+
+	var genesisRaw bytes.Buffer
+	require.NoError(t, genesisCoinbaseTx.Serialize(&genesisRaw))
+
+	loc := blockOneTxLocs[0]
+	tests := []struct {
+		name string
+		raw  []byte
+	}{
+		{name: "multiple inputs and outputs", raw: multiTxEncoded},
+		{name: "genesis coinbase", raw: genesisRaw.Bytes()},
+		{
+			name: "block one coinbase",
+			raw:  blockOneBytes[loc.TxStart:][:loc.TxLen],
+		},
+	}
+
+	for _, test := range tests {
+		var msgTx MsgTx
+		err := msgTx.Deserialize(bytes.NewReader(test.raw))
+		require.NoError(t, err, test.name)
+
+		tx := ConvertWireMsgTxToCommonTransaction(&msgTx)
+		raw, err := tx.StandardSerialize()
+		require.NoError(t, err, test.name)
+		require.Equal(t, test.raw, raw, test.name)
+
+		hash := msgTx.TxHash()
+		require.Equal(t, hash[:], CalculateTxID(raw, tx), test.name)
+	}
+
+	// The genesis coinbase txid is the merkle root of the genesis block.
+	tx := ConvertWireMsgTxToCommonTransaction(&genesisCoinbaseTx)
+	raw, err := tx.StandardSerialize()
+	require.NoError(t, err)
+	require.Equal(t, mainNetGenesisMerkleRoot[:], CalculateTxID(raw, tx))
+
+	tx.TxOutCount++
+	_, err = tx.StandardSerialize()
+	require.Error(t, err)
+}
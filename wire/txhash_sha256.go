@@ -0,0 +1,92 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"crypto/sha256"
+	"hash"
+	"sync/atomic"
+)
+
+// Sha256Backend is an implementation of sha256 used for all of the hashing
+// involved in computing txids, including the double sha256 of standard
+// transactions and each of the layers of the version 10 txid.  Every
+// implementation must produce exactly the same digests as crypto/sha256.
+type Sha256Backend interface {
+	// New returns a new sha256 hash.Hash for hashing data incrementally.
+	New() hash.Hash
+
+	// Sum256 returns the sha256 digest of the data.
+	Sum256(data []byte) [sha256.Size]byte
+}
+
+// stdSha256Backend is the Sha256Backend backed by crypto/sha256.
+type stdSha256Backend struct{}
+
+// New returns a new crypto/sha256 hash.Hash.
+func (stdSha256Backend) New() hash.Hash {
+	return sha256.New()
+}
+
+// Sum256 returns the crypto/sha256 digest of the data.
+func (stdSha256Backend) Sum256(data []byte) [sha256.Size]byte {
+	return sha256.Sum256(data)
+}
+
+// StdSha256Backend is the default Sha256Backend, which uses crypto/sha256.
+// The standard library already selects the fastest implementation supported
+// by the CPU when the program starts, such as the SHA extensions or AVX2 on
+// amd64 and the SHA2 instructions on arm64, and falls back to a pure Go
+// implementation otherwise, so replacing it is only worthwhile with a backend
+// which is faster still on the target hardware, such as one hashing several
+// messages at once.
+var StdSha256Backend Sha256Backend = stdSha256Backend{}
+
+// sha256Backend holds the backend set by SetSha256Backend.  A nil pointer
+// means StdSha256Backend is used.
+var sha256Backend atomic.Pointer[Sha256Backend]
+
+// SetSha256Backend replaces the sha256 implementation used to compute txids.
+// Passing nil restores StdSha256Backend.
+//
+// This is intended to be called during initialization, before any txids are
+// computed, for instance after detecting the CPU features a faster backend
+// requires.  It is safe for concurrent use.
+func SetSha256Backend(backend Sha256Backend) {
+	if backend == nil {
+		sha256Backend.Store(nil)
+		return
+	}
+	sha256Backend.Store(&backend)
+}
+
+// newSha256 returns a new sha256 hash.Hash from the current backend.
+func newSha256() hash.Hash {
+	if backend := sha256Backend.Load(); backend != nil {
+		return (*backend).New()
+	}
+	return sha256.New()
+}
+
+// sha256Into computes the sha256 of b using the current backend and writes it
+// into the first 32 bytes of dst, which must be at least that long, returning
+// them.  It allows callers computing many hashes, such as the script hashes
+// of the version 10 txid, to write each one directly where it is needed.
+// Since the digest is computed in full before it is written, dst may overlap
+// b.
+func sha256Into(dst, b []byte) []byte {
+	dst = dst[:sha256.Size]
+	sum := sum256(b)
+	copy(dst, sum[:])
+	return dst
+}
+
+// sum256 returns the sha256 digest of the data using the current backend.
+func sum256(data []byte) [sha256.Size]byte {
+	if backend := sha256Backend.Load(); backend != nil {
+		return (*backend).Sum256(data)
+	}
+	return sha256.Sum256(data)
+}
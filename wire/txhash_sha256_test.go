@@ -0,0 +1,165 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// countingSha256Backend is a Sha256Backend which wraps StdSha256Backend and
+// counts how many times it is used.
+type countingSha256Backend struct {
+	calls atomic.Int64
+}
+
+// New returns a new hash.Hash from StdSha256Backend.
+func (b *countingSha256Backend) New() hash.Hash {
+	b.calls.Add(1)
+	return StdSha256Backend.New()
+}
+
+// Sum256 returns the digest of the data from StdSha256Backend.
+func (b *countingSha256Backend) Sum256(data []byte) [sha256.Size]byte {
+	b.calls.Add(1)
+	return StdSha256Backend.Sum256(data)
+}
+
+// recordingSha256Backend is a Sha256Backend which wraps StdSha256Backend and
+// records the data passed to Sum256, without copying it, so tests can check
+// exactly which bytes were hashed.
+type recordingSha256Backend struct {
+	mtx  sync.Mutex
+	sums [][]byte
+}
+
+// New returns a new hash.Hash from StdSha256Backend.
+func (b *recordingSha256Backend) New() hash.Hash {
+	return StdSha256Backend.New()
+}
+
+// Sum256 records the data and returns its digest from StdSha256Backend.
+func (b *recordingSha256Backend) Sum256(data []byte) [sha256.Size]byte {
+	b.mtx.Lock()
+	b.sums = append(b.sums, data)
+	b.mtx.Unlock()
+
+	return StdSha256Backend.Sum256(data)
+}
+
+// reset clears the recorded data.
+func (b *recordingSha256Backend) reset() {
+	b.mtx.Lock()
+	b.sums = nil
+	b.mtx.Unlock()
+}
+
+// TestStdSha256Backend ensures the default backend produces the same digests
+// as crypto/sha256 across the block size boundaries.
+func TestStdSha256Backend(t *testing.T) {
+	t.Parallel()
+
+	for _, n := range []int{0, 1, 55, 56, 63, 64, 65, 119, 128, 1000} {
+		data := bytes.Repeat([]byte{byte(n)}, n)
+		want := sha256.Sum256(data)
+
+		require.Equal(t, want, StdSha256Backend.Sum256(data), n)
+
+		h := StdSha256Backend.New()
+		h.Write(data)
+		require.Equal(t, want[:], h.Sum(nil), n)
+	}
+}
+
+// TestSha256Into ensures the digest is written into the start of the
+// destination, including when it overlaps the data, without allocating.  It
+// is not run in parallel since testing.AllocsPerRun requires it.
+func TestSha256Into(t *testing.T) {
+	data := []byte("script")
+	want := sha256.Sum256(data)
+
+	dst := bytes.Repeat([]byte{0xff}, sha256.Size+8)
+	got := sha256Into(dst, data)
+	require.Equal(t, want[:], got)
+	require.Equal(t, want[:], dst[:sha256.Size])
+	require.Equal(t, bytes.Repeat([]byte{0xff}, 8), dst[sha256.Size:])
+
+	// Hashing a digest in place gives the double sha256.
+	buf := append([]byte(nil), data...)
+	buf = append(buf, make([]byte, sha256.Size)...)
+	got = sha256Into(buf, buf[:len(data)])
+	require.Equal(t, DoubleSha256(data), sha256Into(got, got))
+
+	allocs := testing.AllocsPerRun(100, func() {
+		sha256Into(dst, data)
+	})
+	require.Zero(t, allocs)
+}
+
+// TestSetSha256Backend ensures every txid computation is routed through the
+// configured backend and that the txids are unchanged.
+func TestSetSha256Backend(t *testing.T) {
+	t.Cleanup(func() { SetSha256Backend(nil) })
+
+	standard := ConvertWireMsgTxToCommonTransaction(multiTx)
+	raw := mustBytes(t, standard)
+	v10 := v10TestTx()
+	v10Raw := mustBytes(t, v10)
+
+	type result struct {
+		standard, v10, fromReader, withFunc []byte
+	}
+	compute := func() result {
+		fromReader, err := CalculateTxIDFromReader(
+			bytes.NewReader(v10Raw),
+		)
+		require.NoError(t, err)
+
+		return result{
+			standard:   CalculateTxID(raw, standard),
+			v10:        CalculateTxID(nil, v10),
+			fromReader: fromReader,
+			withFunc:   CalculateTxIDWith(nil, v10, nil),
+		}
+	}
+	want := compute()
+
+	backend := new(countingSha256Backend)
+	SetSha256Backend(backend)
+	require.Equal(t, want, compute())
+	require.NotZero(t, backend.calls.Load())
+
+	// Restoring the default stops using the backend.
+	SetSha256Backend(nil)
+	calls := backend.calls.Load()
+	require.Equal(t, want, compute())
+	require.Equal(t, calls, backend.calls.Load())
+}
+
+// BenchmarkSha256Backend benchmarks the digest of data of various sizes with
+// the default backend.  Running it with GODEBUG=cpu.all=off disables the
+// hardware accelerated implementations selected by crypto/sha256, which
+// shows the speedup they provide over the pure Go fallback.
+func BenchmarkSha256Backend(b *testing.B) {
+	for _, n := range []int{64, 1024, 16384} {
+		data := bytes.Repeat([]byte{0x01}, n)
+
+		b.Run(fmt.Sprintf("%d_bytes", n), func(b *testing.B) {
+			b.SetBytes(int64(n))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				sum256(data)
+			}
+		})
+	}
+}
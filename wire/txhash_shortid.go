@@ -0,0 +1,50 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/aead/siphash"
+)
+
+// shortTxIDMask selects the low 48 bits of a siphash, which make up a short
+// txid.
+const shortTxIDMask = 1<<48 - 1
+
+// ShortTxIDKeys returns the siphash keys used to compute the short txids of
+// the transactions in a compact block as defined by BIP0152.  They are the
+// first and second little-endian 64-bit values of the single sha256 of the
+// serialized block header followed by the little-endian nonce chosen by the
+// sender of the compact block.
+func ShortTxIDKeys(header *BlockHeader, nonce uint64) (k0, k1 uint64) {
+	var buf bytes.Buffer
+	buf.Grow(MaxBlockHeaderPayload + 8)
+
+	// Writes to a bytes.Buffer can't fail.
+	_ = header.Serialize(&buf)
+	var nonceBytes [8]byte
+	binary.LittleEndian.PutUint64(nonceBytes[:], nonce)
+	buf.Write(nonceBytes[:])
+
+	hash := sum256(buf.Bytes())
+	return binary.LittleEndian.Uint64(hash[0:8]),
+		binary.LittleEndian.Uint64(hash[8:16])
+}
+
+// ShortTxID returns the 6-byte short id of a txid used by compact block relay
+// as defined by BIP0152, in the low 48 bits of the result.  It is the
+// SipHash-2-4 of the txid, in the internal byte order returned by
+// CalculateTxID, keyed with k0 and k1, which are typically derived from the
+// block header with ShortTxIDKeys.  Version 2 compact blocks use the wtxid
+// returned by CalculateWitnessTxID instead of the txid.
+func ShortTxID(txid []byte, k0, k1 uint64) uint64 {
+	var key [16]byte
+	binary.LittleEndian.PutUint64(key[0:8], k0)
+	binary.LittleEndian.PutUint64(key[8:16], k1)
+
+	return siphash.Sum64(txid, &key) & shortTxIDMask
+}
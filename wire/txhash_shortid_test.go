@@ -0,0 +1,74 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestShortTxID ensures short txids are the low 48 bits of the SipHash-2-4 of
+// the txid.  BIP0152 does not include test vectors of its own, so the vectors
+// are those of the SipHash reference implementation with the key 00..0f,
+// which are also checked by Bitcoin Core for its SipHashUint256 used to compute
+// short ids.
+func TestShortTxID(t *testing.T) {
+	t.Parallel()
+
+	const k0, k1 = 0x0706050403020100, 0x0f0e0d0c0b0a0908
+	message := make([]byte, 32)
+	for i := range message {
+		message[i] = byte(i)
+	}
+
+	tests := []struct {
+		name    string
+		txid    []byte
+		siphash uint64
+	}{
+		{
+			name:    "32 bytes",
+			txid:    message,
+			siphash: 0x7127512f72f27cce,
+		},
+		{
+			name:    "15 bytes",
+			txid:    message[:15],
+			siphash: 0xa129ca6149be45e5,
+		},
+	}
+
+	for _, test := range tests {
+		got := ShortTxID(test.txid, k0, k1)
+		require.Equal(t, test.siphash&0xffffffffffff, got, test.name)
+		require.Zero(t, got>>48, test.name)
+	}
+
+	txid := CalculateStandardTxID(multiTxEncoded)
+	require.NotEqual(t, ShortTxID(txid, k0, k1), ShortTxID(txid, k1, k0))
+}
+
+// TestShortTxIDKeys ensures the short txid keys are derived from the single
+// sha256 of the serialized header and nonce.
+func TestShortTxIDKeys(t *testing.T) {
+	t.Parallel()
+
+	const nonce = 0x0123456789abcdef
+	preimage := binary.LittleEndian.AppendUint64(
+		append([]byte(nil), blockOneBytes[:MaxBlockHeaderPayload]...),
+		nonce,
+	)
+	hash := sha256.Sum256(preimage)
+
+	k0, k1 := ShortTxIDKeys(&blockOne.Header, nonce)
+	require.Equal(t, binary.LittleEndian.Uint64(hash[:8]), k0)
+	require.Equal(t, binary.LittleEndian.Uint64(hash[8:16]), k1)
+
+	other0, other1 := ShortTxIDKeys(&blockOne.Header, nonce+1)
+	require.NotEqual(t, [2]uint64{k0, k1}, [2]uint64{other0, other1})
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// LayeredTxIDVersion is the transaction version which uses the layered txid
+// computed by CalculateV10TxID by default.
+const LayeredTxIDVersion uint32 = 10
+
+// TxIDStrategy computes the txid of a transaction, in internal byte order,
+// from its parsed form alone.  Strategies are registered for a specific
+// transaction version with RegisterTxIDStrategy.
+type TxIDStrategy func(tx *Transaction) []byte
+
+// txIDStrategyEntry is a registered txid strategy.
+type txIDStrategyEntry struct {
+	strategy TxIDStrategy
+
+	// layered is only set for the built-in version 10 strategy.  It lets
+	// code which hashes transactions without ever building a Transaction,
+	// such as CalculateTxIDFromReader, use a TxIDHasher directly while the
+	// built-in strategy has not been replaced.
+	layered bool
+}
+
+var (
+	// txIDStrategies holds the currently registered strategies keyed by
+	// transaction version.  The map is never modified once stored, so it
+	// can be read without locking on the hot txid paths.
+	txIDStrategies atomic.Pointer[map[uint32]txIDStrategyEntry]
+
+	// txIDStrategiesMtx serializes updates to txIDStrategies.
+	txIDStrategiesMtx sync.Mutex
+)
+
+func init() {
+	txIDStrategies.Store(&map[uint32]txIDStrategyEntry{
+		LayeredTxIDVersion: {
+			strategy: CalculateV10TxID,
+			layered:  true,
+		},
+	})
+}
+
+// UsesLayeredTxID returns whether the txid of the transaction is computed with
+// the built-in layered strategy described by CalculateV10TxID rather than the
+// standard double sha256 of its raw serialization or some other registered
+// strategy.  By default this is the case for LayeredTxIDVersion.  Callers
+// should use this rather than comparing the version directly so that they
+// remain correct if the registered strategies change.  False is returned for
+// a nil transaction.
+func UsesLayeredTxID(tx *Transaction) bool {
+	if tx == nil {
+		return false
+	}
+	entry, ok := lookupTxIDStrategy(tx.Version)
+	return ok && entry.layered
+}
+
+// RegisterTxIDStrategy registers the strategy used to compute the txids of
+// transactions with the given version, replacing any existing strategy for
+// that version, including the built-in version 10 layered strategy.
+// Transactions with a version that has no registered strategy use the
+// standard double sha256 of their raw serialization.
+//
+// This is intended to be called during initialization, before any txids are
+// computed.  It is safe for concurrent use.
+//
+// It panics if fn is nil, since that indicates a programming error which
+// would otherwise only surface as a panic the next time a txid is computed
+// for the version, far from the registration that caused it.  Use
+// UnregisterTxIDStrategy to remove a strategy.
+func RegisterTxIDStrategy(version uint32, fn func(tx *Transaction) []byte) {
+	if fn == nil {
+		panic(fmt.Sprintf("wire: nil txid strategy registered for "+
+			"version %d", version))
+	}
+
+	updateTxIDStrategies(func(m map[uint32]txIDStrategyEntry) {
+		m[version] = txIDStrategyEntry{strategy: fn}
+	})
+}
+
+// UnregisterTxIDStrategy removes the strategy registered for the given
+// version, so that transactions with that version revert to the standard
+// double sha256 of their raw serialization.
+func UnregisterTxIDStrategy(version uint32) {
+	updateTxIDStrategies(func(m map[uint32]txIDStrategyEntry) {
+		delete(m, version)
+	})
+}
+
+// updateTxIDStrategies replaces the registered strategies with a copy that has
+// been modified by the provided function.
+func updateTxIDStrategies(modify func(m map[uint32]txIDStrategyEntry)) {
+	txIDStrategiesMtx.Lock()
+	defer txIDStrategiesMtx.Unlock()
+
+	old := *txIDStrategies.Load()
+	m := make(map[uint32]txIDStrategyEntry, len(old)+1)
+	for version, entry := range old {
+		m[version] = entry
+	}
+	modify(m)
+
+	txIDStrategies.Store(&m)
+}
+
+// lookupTxIDStrategy returns the strategy registered for the given version, if
+// any.
+func lookupTxIDStrategy(version uint32) (txIDStrategyEntry, bool) {
+	entry, ok := (*txIDStrategies.Load())[version]
+	return entry, ok
+}
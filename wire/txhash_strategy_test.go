@@ -0,0 +1,180 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// restoreTxIDStrategies arranges for the registered txid strategies to be
+// restored once the test completes.  Tests which modify the registry must not
+// be run in parallel.
+func restoreTxIDStrategies(t *testing.T) {
+	t.Helper()
+
+	orig := txIDStrategies.Load()
+	t.Cleanup(func() {
+		txIDStrategies.Store(orig)
+	})
+}
+
+// TestTxIDStrategyDispatch ensures a registered strategy is used by each of
+// the txid entry points for transactions of its version.
+func TestTxIDStrategyDispatch(t *testing.T) {
+	restoreTxIDStrategies(t)
+
+	// The strategy is just a recognizable function of the locktime.
+	strategy := func(tx *Transaction) []byte {
+		return bytes.Repeat([]byte{byte(tx.LockTime)}, 32)
+	}
+	RegisterTxIDStrategy(7, strategy)
+
+	msgTx := multiTx.Copy()
+	msgTx.Version = 7
+	msgTx.LockTime = 0x42
+	tx := ConvertWireMsgTxToCommonTransaction(msgTx)
+	want := strategy(tx)
+
+	require.Equal(t, want, CalculateTxID(nil, tx))
+	require.Equal(t, want, tx.TxID())
+
+	hash := msgTx.TxHash()
+	require.Equal(t, want, hash[:])
+
+	// Raw bytes aren't required for a registered strategy.
+	id, err := CalculateTxIDErr(nil, tx)
+	require.NoError(t, err)
+	require.Equal(t, want, id)
+
+	ids, err := CalculateTxIDs(nil, []*Transaction{tx})
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{want}, ids)
+
+	var raw bytes.Buffer
+	require.NoError(t, msgTx.Serialize(&raw))
+	id, err = CalculateTxIDFromReader(&raw)
+	require.NoError(t, err)
+	require.Equal(t, want, id)
+	require.Zero(t, raw.Len())
+}
+
+// TestTxIDStrategyFallback ensures a version without a registered strategy
+// uses the standard txid, that registering a strategy for it changes the txid,
+// and that unregistering the strategy restores the standard txid.
+func TestTxIDStrategyFallback(t *testing.T) {
+	restoreTxIDStrategies(t)
+
+	msgTx := multiTx.Copy()
+	msgTx.Version = 7
+	tx := ConvertWireMsgTxToCommonTransaction(msgTx)
+	raw := mustBytes(t, tx)
+
+	standard := CalculateStandardTxID(raw)
+	require.Equal(t, standard, CalculateTxID(raw, tx))
+	require.Equal(t, standard, tx.TxID())
+	hash := msgTx.TxHash()
+	require.Equal(t, standard, hash[:])
+	require.False(t, UsesLayeredTxID(tx))
+
+	override := bytes.Repeat([]byte{0x07}, 32)
+	RegisterTxIDStrategy(7, func(*Transaction) []byte {
+		return override
+	})
+	tx.InvalidateTxID()
+	require.Equal(t, override, CalculateTxID(raw, tx))
+	require.Equal(t, override, tx.TxID())
+	hash = msgTx.TxHash()
+	require.Equal(t, override, hash[:])
+
+	UnregisterTxIDStrategy(7)
+	tx.InvalidateTxID()
+	require.Equal(t, standard, CalculateTxID(raw, tx))
+	require.Equal(t, standard, tx.TxID())
+	hash = msgTx.TxHash()
+	require.Equal(t, standard, hash[:])
+
+	// Unregistering a version without a strategy is a no-op.
+	UnregisterTxIDStrategy(7)
+	require.Equal(t, standard, CalculateTxID(raw, tx))
+}
+
+// TestRegisterNilTxIDStrategy ensures registering a nil strategy panics and
+// leaves the registered strategies unchanged.
+func TestRegisterNilTxIDStrategy(t *testing.T) {
+	restoreTxIDStrategies(t)
+
+	tx := v10TestTx()
+	want := CalculateTxID(nil, tx)
+	require.PanicsWithValue(t, "wire: nil txid strategy registered for "+
+		"version 10", func() {
+		RegisterTxIDStrategy(LayeredTxIDVersion, nil)
+	})
+	require.True(t, UsesLayeredTxID(tx))
+	require.Equal(t, want, CalculateTxID(nil, tx))
+
+	require.Panics(t, func() { RegisterTxIDStrategy(7, nil) })
+	_, ok := lookupTxIDStrategy(7)
+	require.False(t, ok)
+}
+
+// TestTxIDStrategyOverrideV10 ensures the built-in version 10 strategy can be
+// replaced and removed.
+func TestTxIDStrategyOverrideV10(t *testing.T) {
+	restoreTxIDStrategies(t)
+
+	tx := v10TestTx()
+	layered := CalculateV10TxID(tx)
+	require.Equal(t, layered, CalculateTxID(nil, tx))
+
+	override := bytes.Repeat([]byte{0xaa}, 32)
+	RegisterTxIDStrategy(10, func(*Transaction) []byte {
+		return override
+	})
+	require.Equal(t, override, CalculateTxID(nil, tx))
+
+	ids, err := CalculateTxIDs(nil, []*Transaction{tx})
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{override}, ids)
+
+	msgTx, err := ConvertCommonTransactionToWireMsgTx(tx)
+	require.NoError(t, err)
+	var raw bytes.Buffer
+	require.NoError(t, msgTx.Serialize(&raw))
+	id, err := CalculateTxIDFromReader(bytes.NewReader(raw.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, override, id)
+
+	// Without any strategy, version 10 uses the standard path.
+	UnregisterTxIDStrategy(10)
+	require.Equal(t, doubleSha256(raw.Bytes()),
+		CalculateTxID(raw.Bytes(), tx))
+}
+
+// TestUsesLayeredTxID ensures the predicate follows the registered strategies
+// rather than the version alone.
+func TestUsesLayeredTxID(t *testing.T) {
+	restoreTxIDStrategies(t)
+
+	tx := v10TestTx()
+	require.Equal(t, LayeredTxIDVersion, tx.Version)
+	require.True(t, UsesLayeredTxID(tx))
+
+	tx.Version = 1
+	require.False(t, UsesLayeredTxID(tx))
+
+	// Replacing the built-in strategy means the layered txid is no longer
+	// used for the version.
+	tx.Version = LayeredTxIDVersion
+	RegisterTxIDStrategy(LayeredTxIDVersion, CalculateV10TxID)
+	require.False(t, UsesLayeredTxID(tx))
+
+	UnregisterTxIDStrategy(LayeredTxIDVersion)
+	require.False(t, UsesLayeredTxID(tx))
+
+	require.False(t, UsesLayeredTxID(nil))
+}
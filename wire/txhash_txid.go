@@ -0,0 +1,127 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// TxID is a transaction id in internal byte order, which is the order
+// returned by CalculateTxID and held in TxInput.Hash.  Using it instead of a
+// byte slice makes the byte order explicit, so a txid can't be mistaken for
+// one in display order or for some other slice of bytes.
+type TxID [chainhash.HashSize]byte
+
+// String returns the txid as a hex string in display (big-endian) byte order,
+// which is the form shown by block explorers and RPC interfaces.  It is the
+// same string returned by TxIDString and chainhash.Hash.String.
+func (id TxID) String() string {
+	return TxIDString(id[:])
+}
+
+// Bytes returns a copy of the txid in internal byte order.
+func (id TxID) Bytes() []byte {
+	b := make([]byte, chainhash.HashSize)
+	copy(b, id[:])
+	return b
+}
+
+// Hash returns the txid as a chainhash.Hash, which shares the internal byte
+// order.
+func (id TxID) Hash() chainhash.Hash {
+	return chainhash.Hash(id)
+}
+
+// TxIDFromHash returns the txid held in a chainhash.Hash, such as the one
+// returned by MsgTx.TxHash.
+func TxIDFromHash(hash chainhash.Hash) TxID {
+	return TxID(hash)
+}
+
+// TxIDFromBytes returns the txid given in internal byte order, such as a txid
+// returned by CalculateTxID.  An error is returned unless it is exactly 32
+// bytes.
+func TxIDFromBytes(b []byte) (TxID, error) {
+	var id TxID
+	if len(b) != chainhash.HashSize {
+		return id, fmt.Errorf("txid has %d bytes, want %d", len(b),
+			chainhash.HashSize)
+	}
+	copy(id[:], b)
+
+	return id, nil
+}
+
+// TxIDFromDisplay is the inverse of TxID.String.  It decodes a txid given as a
+// hex string in display (big-endian) byte order.  An error is returned unless
+// the string encodes exactly 32 bytes.
+func TxIDFromDisplay(s string) (TxID, error) {
+	b, err := ParseTxInputHash(s)
+	if err != nil {
+		return TxID{}, err
+	}
+
+	return TxID(b), nil
+}
+
+// CalculateTxIDTyped computes the txid of the transaction in the same way as
+// CalculateTxID and returns it as a TxID.  A *TxHashError wrapping
+// ErrInvalidTxForHashing is returned if the transaction is nil, which also
+// wraps ErrNilTx, or if no 32 byte txid could be computed, such as when a
+// registered TxIDStrategy misbehaves or the witness data can't be stripped
+// from the raw bytes, which also wraps ErrBadTxIDLen.
+func CalculateTxIDTyped(rawTxData []byte, tx *Transaction) (TxID, error) {
+	const op = "CalculateTxIDTyped"
+	if tx == nil {
+		return TxID{}, invalidTxError(op, -1, ErrNilTx,
+			"nil transaction")
+	}
+
+	txid := CalculateTxID(rawTxData, tx)
+	id, err := TxIDFromBytes(txid)
+	if err != nil {
+		return TxID{}, invalidTxError(op, -1, ErrBadTxIDLen, "%d byte "+
+			"txid for version %d transaction", len(txid),
+			tx.Version)
+	}
+
+	return id, nil
+}
+
+// TxIDResult holds a txid as hex strings in both byte orders for services
+// such as RPC servers which return txids as strings.  Display is the form
+// which should be shown to users and returned in RPC responses, while
+// Internal matches the bytes returned by CalculateTxID and held in
+// TxInput.Hash.
+type TxIDResult struct {
+	Internal string `json:"internal"`
+	Display  string `json:"display"`
+}
+
+// NewTxIDResult returns the TxIDResult for the txid, which must be in the
+// internal byte order returned by CalculateTxID.  Unlike TxIDString, it does
+// not panic when the txid is not exactly 32 bytes, such as the nil returned by
+// CalculateTxID when no txid could be computed, and instead returns a zero
+// TxIDResult with both strings empty.
+func NewTxIDResult(id []byte) TxIDResult {
+	if len(id) != chainhash.HashSize {
+		return TxIDResult{}
+	}
+
+	return TxIDResult{
+		Internal: hex.EncodeToString(id),
+		Display:  TxIDString(id),
+	}
+}
+
+// CalculateTxIDResult computes the txid of the transaction in the same way as
+// CalculateTxID and returns it as a TxIDResult.  The result is zero when no
+// txid could be computed.
+func CalculateTxIDResult(raw []byte, tx *Transaction) TxIDResult {
+	return NewTxIDResult(CalculateTxID(raw, tx))
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTxIDType ensures a TxID converts between the internal and display byte
+// orders in the same way as chainhash.Hash.
+func TestTxIDType(t *testing.T) {
+	t.Parallel()
+
+	hash := multiTx.TxHash()
+	id := TxIDFromHash(hash)
+	require.Equal(t, hash, id.Hash())
+	require.Equal(t, hash[:], id.Bytes())
+	require.Equal(t, hash.String(), id.String())
+	require.Equal(t, TxIDString(hash[:]), id.String())
+
+	// The bytes are a copy.
+	b := id.Bytes()
+	b[0] ^= 0xff
+	require.Equal(t, hash[:], id.Bytes())
+
+	got, err := TxIDFromDisplay(id.String())
+	require.NoError(t, err)
+	require.Equal(t, id, got)
+
+	got, err = TxIDFromBytes(hash[:])
+	require.NoError(t, err)
+	require.Equal(t, id, got)
+
+	_, err = TxIDFromBytes(hash[:31])
+	require.Error(t, err)
+	_, err = TxIDFromBytes(nil)
+	require.Error(t, err)
+
+	s := id.String()
+	for _, s := range []string{"", s[:62], s + "00", "zz" + s[2:]} {
+		_, err := TxIDFromDisplay(s)
+		require.Error(t, err)
+	}
+}
+
+// TestCalculateTxIDTyped ensures the typed txid matches the one returned by
+// CalculateTxID and that failures to compute one are reported.
+func TestCalculateTxIDTyped(t *testing.T) {
+	restoreTxIDStrategies(t)
+
+	standard := ConvertWireMsgTxToCommonTransaction(multiTx)
+	raw := mustBytes(t, standard)
+
+	id, err := CalculateTxIDTyped(raw, standard)
+	require.NoError(t, err)
+	require.Equal(t, multiTx.TxHash(), id.Hash())
+
+	v10 := v10TestTx()
+	id, err = CalculateTxIDTyped(nil, v10)
+	require.NoError(t, err)
+	require.Equal(t, CalculateV10TxID(v10), id.Bytes())
+
+	_, err = CalculateTxIDTyped(raw, nil)
+	require.ErrorIs(t, err, ErrInvalidTxForHashing)
+
+	RegisterTxIDStrategy(7, func(*Transaction) []byte {
+		return make([]byte, chainhash.HashSize-1)
+	})
+	v10.Version = 7
+	_, err = CalculateTxIDTyped(nil, v10)
+	require.ErrorIs(t, err, ErrInvalidTxForHashing)
+
+	// The short txid is reported as a TxHashError by every function which
+	// checks it.
+	_, indexErr := BuildTxIDIndex([]*Transaction{v10})
+	for _, err := range []error{
+		err,
+		CalculateTxIDInto(make([]byte, chainhash.HashSize), nil, v10),
+		indexErr,
+	} {
+		var txErr *TxHashError
+		require.ErrorAs(t, err, &txErr)
+		require.ErrorIs(t, err, ErrBadTxIDLen)
+		require.ErrorIs(t, err, ErrInvalidTxForHashing)
+	}
+}
+
+// TestTxIDResult ensures a TxIDResult holds the txid in both byte orders, is
+// zero for txids which could not be computed, and has the expected JSON form.
+func TestTxIDResult(t *testing.T) {
+	t.Parallel()
+
+	hash := multiTx.TxHash()
+	result := NewTxIDResult(hash[:])
+	require.Equal(t, hash.String(), result.Display)
+	require.Equal(t, hex.EncodeToString(hash[:]), result.Internal)
+
+	standard := ConvertWireMsgTxToCommonTransaction(multiTx)
+	raw := mustBytes(t, standard)
+	require.Equal(t, result, CalculateTxIDResult(raw, standard))
+
+	tx := v10TestTx()
+	result = CalculateTxIDResult(nil, tx)
+	require.Equal(t, TxIDString(CalculateTxID(nil, tx)), result.Display)
+
+	require.Equal(t, TxIDResult{}, NewTxIDResult(nil))
+	require.Equal(t, TxIDResult{}, NewTxIDResult(hash[:31]))
+
+	b, err := json.Marshal(NewTxIDResult(hash[:]))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"internal":"`+hex.EncodeToString(hash[:])+
+		`","display":"`+hash.String()+`"}`, string(b))
+}
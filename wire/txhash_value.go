@@ -0,0 +1,143 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+)
+
+// TotalOutputValue returns the sum of the values of all outputs of the
+// transaction.  An error is returned if any output is nil or if the sum
+// overflows a uint64.
+func (tx *Transaction) TotalOutputValue() (uint64, error) {
+	return tx.sumOutputValues(func(*TxOutput) bool { return true })
+}
+
+// SpendableOutputValue returns the sum of the values of the outputs of the
+// transaction which can possibly be spent, excluding those whose public key
+// script is provably unspendable since it begins with OP_RETURN.  This
+// includes every standard nulldata script, as classified by
+// PkScript.ScriptType, along with nonstandard scripts beginning with
+// OP_RETURN.  Any value sent to such outputs is burned, so it should not be
+// counted towards balances.
+//
+// As with TotalOutputValue, an error is returned if any output is nil or if
+// the sum overflows a uint64.
+func (tx *Transaction) SpendableOutputValue() (uint64, error) {
+	return tx.sumOutputValues(func(txOut *TxOutput) bool {
+		return !txOut.PkScript.isUnspendable()
+	})
+}
+
+// sumOutputValues returns the sum of the values of the outputs for which
+// include returns true.
+func (tx *Transaction) sumOutputValues(include func(*TxOutput) bool) (uint64,
+	error) {
+
+	var total uint64
+	for i, txOut := range tx.TxOut {
+		if txOut == nil {
+			return 0, fmt.Errorf("output %d is nil", i)
+		}
+		if !include(txOut) {
+			continue
+		}
+
+		var carry uint64
+		total, carry = bits.Add64(total, txOut.Value, 0)
+		if carry != 0 {
+			return 0, fmt.Errorf("total value of outputs 0 "+
+				"through %d overflows uint64", i)
+		}
+	}
+
+	return total, nil
+}
+
+// Fee returns the fee paid by the transaction given the values of the
+// previous outputs spent by each of its inputs, in the same order as TxIn,
+// since the inputs themselves do not carry their values.  The fee is the sum
+// of the input values less the sum of the output values.
+//
+// An error is returned if the number of values does not match the number of
+// inputs, if either sum overflows a uint64, or if the outputs are worth more
+// than the inputs.
+func (tx *Transaction) Fee(prevValues []uint64) (uint64, error) {
+	if len(prevValues) != len(tx.TxIn) {
+		return 0, fmt.Errorf("%d input values provided for %d inputs",
+			len(prevValues), len(tx.TxIn))
+	}
+
+	var totalIn uint64
+	for i, value := range prevValues {
+		var carry uint64
+		totalIn, carry = bits.Add64(totalIn, value, 0)
+		if carry != 0 {
+			return 0, fmt.Errorf("total value of inputs 0 "+
+				"through %d overflows uint64", i)
+		}
+	}
+
+	totalOut, err := tx.TotalOutputValue()
+	if err != nil {
+		return 0, err
+	}
+	if totalOut > totalIn {
+		return 0, fmt.Errorf("total output value %d exceeds total "+
+			"input value %d", totalOut, totalIn)
+	}
+
+	return totalIn - totalOut, nil
+}
+
+// SortByFeeRate returns the indices of the transactions ordered by descending
+// fee rate, where fees holds the fee paid by each transaction, as returned by
+// Fee, and the fee rate is the fee divided by the virtual size reported by
+// VSize.  Transactions with equal fee rates keep their relative order, so the
+// result is deterministic.
+//
+// The fee rates are compared exactly by cross multiplying each fee with the
+// virtual size of the other transaction rather than by dividing.  A nil
+// transaction, or one with a nil input or output, has no virtual size and is
+// ordered after every other transaction.  Nil is returned if the number of
+// fees does not match the number of transactions.
+func SortByFeeRate(txs []*Transaction, fees []uint64) []int {
+	if len(fees) != len(txs) {
+		log.Warnf("Unable to sort by fee rate: %d fees provided "+
+			"for %d transactions", len(fees), len(txs))
+		return nil
+	}
+
+	// A virtual size of zero marks a transaction without a fee rate.
+	vsizes := make([]uint64, len(txs))
+	indices := make([]int, len(txs))
+	for i, tx := range txs {
+		indices[i] = i
+		if tx != nil && !tx.hasNilEntry() {
+			vsizes[i] = uint64(tx.VSize())
+		}
+	}
+
+	sort.SliceStable(indices, func(i, j int) bool {
+		a, b := indices[i], indices[j]
+		switch {
+		case vsizes[a] == 0:
+			return false
+		case vsizes[b] == 0:
+			return true
+		}
+
+		// fees[a] / vsizes[a] > fees[b] / vsizes[b] is equivalent to
+		// fees[a] * vsizes[b] > fees[b] * vsizes[a] since the sizes
+		// are positive, and the 128-bit products can't overflow.
+		hiA, loA := bits.Mul64(fees[a], vsizes[b])
+		hiB, loB := bits.Mul64(fees[b], vsizes[a])
+		return hiA > hiB || (hiA == hiB && loA > loB)
+	})
+
+	return indices
+}
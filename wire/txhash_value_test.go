@@ -0,0 +1,199 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// valueTestTx returns a transaction with the given number of inputs and
+// outputs with the given values.
+func valueTestTx(numIn int, outValues ...uint64) *Transaction {
+	tx := &Transaction{
+		TxIn:  make([]*TxInput, numIn),
+		TxOut: make([]*TxOutput, len(outValues)),
+	}
+	for i := range tx.TxIn {
+		tx.TxIn[i] = &TxInput{Hash: make([]byte, 32)}
+	}
+	for i, value := range outValues {
+		tx.TxOut[i] = &TxOutput{Value: value}
+	}
+	return tx
+}
+
+// TestTransactionTotalOutputValue ensures output values are summed and that
+// overflow is detected.
+func TestTransactionTotalOutputValue(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		values  []uint64
+		want    uint64
+		wantErr bool
+	}{
+		{name: "no outputs", values: nil, want: 0},
+		{name: "single", values: []uint64{5000}, want: 5000},
+		{name: "several", values: []uint64{1, 2, 3}, want: 6},
+		{
+			name:   "max",
+			values: []uint64{math.MaxUint64 - 1, 1},
+			want:   math.MaxUint64,
+		},
+		{
+			name:    "overflow",
+			values:  []uint64{math.MaxUint64, 1},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		got, err := valueTestTx(1, test.values...).TotalOutputValue()
+		if test.wantErr {
+			require.Error(t, err, test.name)
+			continue
+		}
+		require.NoError(t, err, test.name)
+		require.Equal(t, test.want, got, test.name)
+	}
+
+	tx := valueTestTx(1, 1, 2)
+	tx.TxOut[1] = nil
+	_, err := tx.TotalOutputValue()
+	require.Error(t, err)
+}
+
+// TestTransactionFee ensures the fee is the difference between the input and
+// output values and that inconsistent values are rejected.
+func TestTransactionFee(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		tx         *Transaction
+		prevValues []uint64
+		want       uint64
+		wantErr    bool
+	}{{
+		name:       "fee",
+		tx:         valueTestTx(2, 3000, 1000),
+		prevValues: []uint64{2500, 2000},
+		want:       500,
+	}, {
+		name:       "zero fee",
+		tx:         valueTestTx(1, 1000),
+		prevValues: []uint64{1000},
+		want:       0,
+	}, {
+		name:       "outputs exceed inputs",
+		tx:         valueTestTx(1, 1001),
+		prevValues: []uint64{1000},
+		wantErr:    true,
+	}, {
+		name:       "too few values",
+		tx:         valueTestTx(2, 1),
+		prevValues: []uint64{1000},
+		wantErr:    true,
+	}, {
+		name:       "too many values",
+		tx:         valueTestTx(1, 1),
+		prevValues: []uint64{1000, 1000},
+		wantErr:    true,
+	}, {
+		name:       "input overflow",
+		tx:         valueTestTx(2, 1),
+		prevValues: []uint64{math.MaxUint64, 1},
+		wantErr:    true,
+	}, {
+		name:       "output overflow",
+		tx:         valueTestTx(1, math.MaxUint64, 1),
+		prevValues: []uint64{math.MaxUint64},
+		wantErr:    true,
+	}}
+
+	for _, test := range tests {
+		got, err := test.tx.Fee(test.prevValues)
+		if test.wantErr {
+			require.Error(t, err, test.name)
+			continue
+		}
+		require.NoError(t, err, test.name)
+		require.Equal(t, test.want, got, test.name)
+	}
+}
+
+// TestTransactionSpendableOutputValue ensures provably unspendable outputs
+// are excluded from the spendable value.
+func TestTransactionSpendableOutputValue(t *testing.T) {
+	t.Parallel()
+
+	p2pkh := cat(
+		[]byte{opDup, opHash160, opData20}, make([]byte, 20),
+		[]byte{opEqualVerify, opCheckSig},
+	)
+	nullData := []byte{opReturn, 0x04, 0xde, 0xad, 0xbe, 0xef}
+	nonStandardReturn := cat([]byte{opReturn}, make([]byte, 100))
+
+	tx := valueTestTx(1, 1000, 2000, 4000, 8000)
+	tx.TxOut[0].PkScript.Pkscript = p2pkh
+	tx.TxOut[1].PkScript.Pkscript = nullData
+	tx.TxOut[2].PkScript.Pkscript = p2pkh
+	tx.TxOut[3].PkScript.Pkscript = nonStandardReturn
+	require.Equal(t, NullDataTy, tx.TxOut[1].PkScript.ScriptType())
+	require.Equal(t, NonStandardTy, tx.TxOut[3].PkScript.ScriptType())
+
+	spendable, err := tx.SpendableOutputValue()
+	require.NoError(t, err)
+	require.Equal(t, uint64(5000), spendable)
+
+	total, err := tx.TotalOutputValue()
+	require.NoError(t, err)
+	require.Equal(t, uint64(15000), total)
+
+	tx.TxOut[1] = nil
+	_, err = tx.SpendableOutputValue()
+	require.Error(t, err)
+
+	tx = valueTestTx(1, math.MaxUint64, 1)
+	_, err = tx.SpendableOutputValue()
+	require.Error(t, err)
+}
+
+// TestSortByFeeRate ensures transactions are ordered by descending fee rate
+// with ties kept in their original order, that transactions without a virtual
+// size are ordered last, and that mismatched fees are rejected.
+func TestSortByFeeRate(t *testing.T) {
+	t.Parallel()
+
+	small := largeV10TestTx(1, 1)
+	large := largeV10TestTx(5, 5)
+	witness := largeV10TestTx(1, 1)
+	witness.TxIn[0].Witness = [][]byte{make([]byte, 100)}
+	nilInput := largeV10TestTx(1, 1)
+	nilInput.TxIn[0] = nil
+
+	// The witness data increases the virtual size, so the witness
+	// transaction pays a lower fee rate than the small one for the same
+	// fee, while the large one pays the same rate as the second small one.
+	require.Greater(t, witness.VSize(), small.VSize())
+	const rate = 100
+
+	txs := []*Transaction{
+		small, nil, witness, large, small, nilInput, small,
+	}
+	fees := []uint64{
+		1000, 5000, 1000, rate * uint64(large.VSize()),
+		rate * uint64(small.VSize()), 5000, math.MaxUint64,
+	}
+	want := []int{6, 3, 4, 0, 2, 1, 5}
+	require.Equal(t, want, SortByFeeRate(txs, fees))
+
+	require.Empty(t, SortByFeeRate(nil, nil))
+	require.Nil(t, SortByFeeRate(txs, fees[1:]))
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2023 TuringBitChain
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// v10TxIDVector is a version 10 transaction along with its expected layered
+// txid.
+type v10TxIDVector struct {
+	name string
+	tx   *Transaction
+
+	// txid is the expected txid as hex in internal byte order, which is
+	// the order returned by CalculateTxID.  The display order shown by
+	// block explorers is the reverse.
+	txid string
+}
+
+// v10TxIDVectors are the test vectors for the layered version 10 txid
+// described by CalculateV10TxID.
+//
+// Every transaction was constructed by hand to exercise one aspect of the
+// scheme.  Each txid was derived by building the inputs, scripts, and outputs
+// serializations and the 112 byte preimage exactly as documented, hashing
+// them with sha256 from the Go standard library, which is what refV10TxID
+// does independently of the implementation under test.  The results were
+// cross-checked against a separate implementation using Python's hashlib
+// before being frozen here.
+var v10TxIDVectors = []v10TxIDVector{{
+	// A transaction with no inputs or outputs, so all three layers are
+	// the sha256 of the empty string, e3b0c442...7852b855.
+	name: "empty",
+	tx:   &Transaction{Version: 10},
+	txid: "1856bb1883f3a58b41d482ed275e53f8" +
+		"ec28bb3d42a5d3afc4a10df0c3997e44",
+}, {
+	// A single input and output whose scripts are both empty, so their
+	// script hashes are also the sha256 of the empty string.
+	name: "empty scripts",
+	tx: &Transaction{
+		Version:  10,
+		LockTime: 0,
+		TxIn: []*TxInput{{
+			Hash:     bytes.Repeat([]byte{0x01}, 32),
+			Index:    0,
+			Sequence: MaxTxInSequenceNum,
+		}},
+		TxOut: []*TxOutput{{
+			Value: 0,
+		}},
+		TxInCount:  1,
+		TxOutCount: 1,
+	},
+	txid: "e8164d4768e1cf256bc32901c4b697f8" +
+		"c36684918031a5ad3120dbb834b1e836",
+}, {
+	// Several inputs and outputs with distinct fields, exercising the
+	// ordering within each layer and the little-endian encoding of every
+	// integer, including a non-zero locktime.
+	name: "multiple inputs and outputs",
+	tx: &Transaction{
+		Version:  10,
+		LockTime: 0x00061a80,
+		TxIn: []*TxInput{{
+			Hash:            bytes.Repeat([]byte{0xaa}, 32),
+			Index:           0,
+			SignatureScript: []byte{0x00},
+			Sequence:        0xfffffffd,
+		}, {
+			Hash:            bytes.Repeat([]byte{0xbb}, 32),
+			Index:           7,
+			SignatureScript: []byte{0x51, 0x52},
+			Sequence:        0x00000001,
+		}, {
+			Hash:            bytes.Repeat([]byte{0xcc}, 32),
+			Index:           0x01020304,
+			SignatureScript: bytes.Repeat([]byte{0x6a}, 100),
+			Sequence:        0,
+		}},
+		TxOut: []*TxOutput{{
+			Value:    1,
+			PkScript: PkScript{Pkscript: []byte{0x51}},
+		}, {
+			Value: 123456789,
+			PkScript: PkScript{
+				Pkscript: bytes.Repeat([]byte{0x76}, 25),
+			},
+		}},
+		TxInCount:  3,
+		TxOutCount: 2,
+	},
+	txid: "0871a764dbbb4767dd91681af5f13d6b" +
+		"3a023a94b5c9adac6a907f3287b72331",
+}, {
+	// Outputs with the largest valid amount and the largest value the
+	// field can hold, which the layered hash commits to as is.
+	name: "max value outputs",
+	tx: &Transaction{
+		Version: 10,
+		TxIn: []*TxInput{{
+			Hash:     bytes.Repeat([]byte{0x02}, 32),
+			Sequence: MaxTxInSequenceNum,
+		}},
+		TxOut: []*TxOutput{{
+			Value:    MaxTxOutputValue,
+			PkScript: PkScript{Pkscript: []byte{0x51}},
+		}, {
+			Value:    math.MaxUint64,
+			PkScript: PkScript{Pkscript: []byte{0x51}},
+		}},
+		TxInCount:  1,
+		TxOutCount: 2,
+	},
+	txid: "7a78ff86dfe9428cdf0c59de7a084a1d" +
+		"a630aa7fa82bca74057fc6ed7b7557df",
+}, {
+	// A coinbase spending the null outpoint, which is hashed like any
+	// other input.
+	name: "coinbase",
+	tx:   v10TestCoinbase(),
+	txid: "9049e6cf05bd98641b4644686658742a" +
+		"89f89166c894b7dd360abc4bac854124",
+}}
+
+// TestV10TxIDVectors ensures CalculateTxID reproduces the frozen version 10
+// txid test vectors.
+func TestV10TxIDVectors(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range v10TxIDVectors {
+		ref := refV10TxID(test.tx)
+		require.Equal(t, test.txid, hex.EncodeToString(ref), test.name)
+
+		txid := CalculateTxID(nil, test.tx)
+		require.Equal(t, test.txid, hex.EncodeToString(txid), test.name)
+
+		// CalculateTxIDErr rejects the empty transaction and agrees on
+		// all of the others.
+		txid, err := CalculateTxIDErr(nil, test.tx)
+		if len(test.tx.TxIn) == 0 && len(test.tx.TxOut) == 0 {
+			require.Error(t, err, test.name)
+			continue
+		}
+		require.NoError(t, err, test.name)
+		require.Equal(t, test.txid, hex.EncodeToString(txid), test.name)
+	}
+}
+
+// TestCalculateTxIDEmptyV10 ensures the documented handling of a version 10
+// transaction without any inputs or outputs: the txid is the frozen empty
+// vector, while the error returning variants reject it.
+func TestCalculateTxIDEmptyV10(t *testing.T) {
+	t.Parallel()
+
+	want := v10TxIDVectors[0]
+	require.Equal(t, "empty", want.name)
+
+	tx := &Transaction{Version: 10}
+	require.Equal(t, want.txid, hex.EncodeToString(CalculateTxID(nil, tx)))
+
+	_, err := CalculateTxIDErr(nil, tx)
+	require.ErrorIs(t, err, ErrInvalidTxForHashing)
+
+	_, err = CalculateMsgTxID(NewMsgTx(10))
+	require.ErrorIs(t, err, ErrInvalidTxForHashing)
+
+	_, err = CalculateTxIDs(nil, []*Transaction{tx})
+	require.ErrorIs(t, err, ErrInvalidTxForHashing)
+}